@@ -0,0 +1,35 @@
+package asrfile
+
+import (
+	"github.com/gollmagent/subtitle"
+)
+
+// Provider 整个音频文件的语音识别, 返回带时间戳的句子
+type Provider interface {
+	Name() string
+	// Recognize 识别 audioFile, language 为 zh/en/ja 等语言代码, 空表示默认语言
+	Recognize(audioFile string, language string) ([]subtitle.Cue, error)
+}
+
+// FakeProvider 返回固定结果, 用于离线测试
+type FakeProvider struct {
+	Cues []subtitle.Cue
+	Err  error
+
+	// 最近一次调用的参数
+	LastAudioFile string
+	LastLanguage  string
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Recognize(audioFile string, language string) ([]subtitle.Cue, error) {
+	p.LastAudioFile = audioFile
+	p.LastLanguage = language
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	return append([]subtitle.Cue(nil), p.Cues...), nil
+}
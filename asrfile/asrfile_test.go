@@ -0,0 +1,50 @@
+package asrfile
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gollmagent/subtitle"
+	"github.com/tencentcloud/tencentcloud-speech-sdk-go/asr"
+)
+
+func TestFakeProvider(t *testing.T) {
+	var p Provider = &FakeProvider{Cues: []subtitle.Cue{{Start: 0, End: 1, Text: "hello"}}}
+	cues, err := p.Recognize("a.m4a", "en")
+	if err != nil || len(cues) != 1 || cues[0].Text != "hello" {
+		t.Errorf("unexpected result: %v, %v", cues, err)
+	}
+	fake := p.(*FakeProvider)
+	if fake.LastAudioFile != "a.m4a" || fake.LastLanguage != "en" {
+		t.Errorf("call arguments not recorded: %+v", fake)
+	}
+	fake.Err = fmt.Errorf("asr failed")
+	if _, err := p.Recognize("a.m4a", ""); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestCuesFromFlashResponse(t *testing.T) {
+	resp := &asr.FlashRecognitionResponse{
+		FlashResult: []*asr.FlashRecognitionResult{{
+			SentenceList: []*asr.FlashRecognitionSentence{
+				{Text: "第一句。", StartTime: 480, EndTime: 2130},
+				{Text: " ", StartTime: 2130, EndTime: 2500},
+				{Text: "第二句", StartTime: 2600, EndTime: 5010},
+			},
+		}},
+	}
+	cues := cuesFromFlashResponse(resp)
+	want := []subtitle.Cue{{Start: 0.48, End: 2.13, Text: "第一句。"}, {Start: 2.6, End: 5.01, Text: "第二句"}}
+	if len(cues) != len(want) {
+		t.Fatalf("got %v, want %v", cues, want)
+	}
+	for i := range want {
+		if cues[i] != want[i] {
+			t.Errorf("cue %d got %v, want %v", i, cues[i], want[i])
+		}
+	}
+	if len(cuesFromFlashResponse(&asr.FlashRecognitionResponse{})) != 0 {
+		t.Errorf("empty response should give no cues")
+	}
+}
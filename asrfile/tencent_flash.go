@@ -0,0 +1,93 @@
+package asrfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/subtitle"
+	"github.com/tencentcloud/tencentcloud-speech-sdk-go/asr"
+	"github.com/tencentcloud/tencentcloud-speech-sdk-go/common"
+)
+
+// 极速版录音识别单个文件最大 100MB
+const kFlashMaxFileSize = 100 * 1024 * 1024
+
+// 语言代码到 16k 引擎
+var flashEngineTypes = map[string]string{
+	"zh":  "16k_zh",
+	"en":  "16k_en",
+	"yue": "16k_yue",
+	"ja":  "16k_ja",
+	"ko":  "16k_ko",
+}
+
+// TencentFlashProvider 使用腾讯云录音文件识别极速版
+type TencentFlashProvider struct {
+	recognizer *asr.FlashRecognizer
+}
+
+func NewTencentFlashProvider(appId, secretId, secretKey string) *TencentFlashProvider {
+	credential := common.NewCredential(secretId, secretKey)
+	return &TencentFlashProvider{
+		recognizer: asr.NewFlashRecognizer(appId, credential),
+	}
+}
+
+func (p *TencentFlashProvider) Name() string {
+	return "tencent_flash"
+}
+
+func (p *TencentFlashProvider) Recognize(audioFile string, language string) ([]subtitle.Cue, error) {
+	engineType := flashEngineTypes["zh"]
+	if language != "" {
+		t, ok := flashEngineTypes[language]
+		if !ok {
+			return nil, fmt.Errorf("unsupported language for tencent flash asr: %s", language)
+		}
+		engineType = t
+	}
+	data, err := os.ReadFile(audioFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > kFlashMaxFileSize {
+		return nil, fmt.Errorf("audio file is too large for flash asr: %d bytes", len(data))
+	}
+
+	req := &asr.FlashRecognitionRequest{
+		EngineType:       engineType,
+		VoiceFormat:      strings.TrimPrefix(filepath.Ext(audioFile), "."),
+		ConvertNumMode:   1,
+		FirstChannelOnly: 1,
+	}
+	log.Infof("tencent flash asr start: %s, engine:%s, size:%d", audioFile, engineType, len(data))
+	resp, err := p.recognizer.Recognize(req, data)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("tencent flash asr done: %s, request_id:%s, audio_duration(ms):%d", audioFile, resp.RequestId, resp.AudioDuration)
+	return cuesFromFlashResponse(resp), nil
+}
+
+// cuesFromFlashResponse 把第一个声道的 sentence_list 转换为字幕, start_time/end_time 单位是毫秒
+func cuesFromFlashResponse(resp *asr.FlashRecognitionResponse) []subtitle.Cue {
+	var cues []subtitle.Cue
+	if len(resp.FlashResult) == 0 {
+		return cues
+	}
+	for _, s := range resp.FlashResult[0].SentenceList {
+		text := strings.TrimSpace(s.Text)
+		if text == "" {
+			continue
+		}
+		cues = append(cues, subtitle.Cue{
+			Start: float64(s.StartTime) / 1000,
+			End:   float64(s.EndTime) / 1000,
+			Text:  text,
+		})
+	}
+	return cues
+}
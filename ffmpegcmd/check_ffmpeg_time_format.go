@@ -2,7 +2,10 @@ package ffmpegcmd
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 func IsValidFFmpegTimeFormat(timeStr string) bool {
@@ -25,3 +28,40 @@ func GetTimeFromTimeFormat(timeStr string) (hours, minutes, seconds int, err err
 
 	return hours, minutes, seconds, nil
 }
+
+// ParseTimeToSeconds 支持秒数("90", "90.5"), "MM:SS" 和 "HH:MM:SS(.ms)" 三种写法
+func ParseTimeToSeconds(timeStr string) (float64, error) {
+	timeStr = strings.TrimSpace(timeStr)
+	if len(timeStr) == 0 {
+		return 0, fmt.Errorf("empty time string")
+	}
+	parts := strings.Split(timeStr, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time format: %s", timeStr)
+	}
+	var seconds float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		// ParseFloat 也接受 NaN/Inf, NaN 能通过下面的大小比较, 要单独排除
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("invalid time format: %s", timeStr)
+		}
+		if i < len(parts)-1 && v != float64(int(v)) {
+			return 0, fmt.Errorf("invalid time format: %s", timeStr)
+		}
+		if i > 0 && v >= 60 {
+			return 0, fmt.Errorf("invalid time format: %s", timeStr)
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds, nil
+}
+
+// FormatSeconds 把秒数格式化为 "HH:MM:SS.mmm"
+func FormatSeconds(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -1,37 +1,11 @@
 package ffmpegcmd
 
-import (
-	"fmt"
-	"os"
-	"os/exec"
-)
-
-func ImageWatermark2Video(inputVideo, watermarkImage string, x, y int, outputVideo string) error {
-	// 构建 FFmpeg 命令参数
-	args := []string{
-		"-i", inputVideo, // 输入视频文件
-		"-i", watermarkImage, // 水印图片文件
-		"-filter_complex", fmt.Sprintf("overlay=%d:%d", x, y), // 叠加水印
-		"-codec:a", "copy", // 保持音频编码不变
-		"-y",        // 覆盖输出文件
-		outputVideo, // 输出视频文件
-	}
-
-	// 执行 FFmpeg 命令
-	cmd := exec.Command("ffmpeg", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// log print ffmpeg command
-	cmdDbg := "ffmpeg"
-	for _, arg := range args {
-		cmdDbg += fmt.Sprintf(" %s", arg)
+// ImageWatermark2Video 给视频添加图片水印, videoWidth/duration 来自 ffprobe, 用于缩放和计算显示时间
+func ImageWatermark2Video(inputVideo, watermarkImage, outputVideo string, videoWidth int, duration float64, opts *WatermarkOptions) error {
+	args, err := BuildImageWatermarkArgs(inputVideo, watermarkImage, outputVideo, videoWidth, duration, opts)
+	if err != nil {
+		return err
 	}
-	fmt.Println("Executing command:", cmdDbg)
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg execution failed: %v", err)
-	}
-
-	return nil
+	_, err = runFFmpeg(args)
+	return err
 }
@@ -0,0 +1,109 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+type BackgroundMusicOptions struct {
+	Duration      float64 // 输出时长, 一般是视频时长, 音乐会循环或截断到这个长度
+	HasVoice      bool    // 输入本身是否有音频, 没有时只输出音乐
+	MusicStart    float64 // 从音乐的第几秒开始, 循环时也从这里开始
+	MusicDuration float64 // 音乐文件的时长, 用于判断是否需要循环
+	MusicVolume   float64 // 音乐音量, 默认0.3
+	VoiceVolume   float64 // 原音频音量, 默认1.0
+	FadeIn        float64 // 音乐淡入时长(秒)
+	FadeOut       float64 // 音乐淡出时长(秒)
+	Ducking       bool    // 有人声时自动压低音乐
+	DuckThreshold float64 // 触发压低的原音频电平(0-1), 默认0.05
+	DuckRatio     float64 // 压缩比, 默认8
+}
+
+func (opts *BackgroundMusicOptions) setDefaults() {
+	if opts.MusicVolume <= 0 {
+		opts.MusicVolume = 0.3
+	}
+	if opts.VoiceVolume <= 0 {
+		opts.VoiceVolume = 1.0
+	}
+	if opts.DuckThreshold <= 0 {
+		opts.DuckThreshold = 0.05
+	}
+	if opts.DuckRatio < 1 {
+		opts.DuckRatio = 8
+	}
+	// 淡入淡出加起来不能超过总时长
+	if fades := opts.FadeIn + opts.FadeOut; fades > opts.Duration && fades > 0 {
+		opts.FadeIn = opts.FadeIn * opts.Duration / fades
+		opts.FadeOut = opts.FadeOut * opts.Duration / fades
+	}
+}
+
+// 循环时先统一采样率, aloop 的 size 按这个采样率计算
+const kMusicLoopSampleRate = 48000
+
+// BuildBackgroundMusicFilter 生成混音的 filter_complex, 输入0是原视频/音频, 输入1是音乐, 输出标签 [aout].
+// 音乐从 MusicStart 开始, 不够长时在滤镜里用 aloop 循环 MusicStart 之后的部分, 不会重复前奏
+func BuildBackgroundMusicFilter(opts *BackgroundMusicOptions) string {
+	opts.setDefaults()
+	var music []string
+	if opts.MusicStart > 0 {
+		music = append(music, fmt.Sprintf("atrim=start=%.3f", opts.MusicStart), "asetpts=PTS-STARTPTS")
+	}
+	if loopLen := opts.MusicDuration - opts.MusicStart; loopLen > 0 && loopLen < opts.Duration {
+		music = append(music,
+			fmt.Sprintf("aresample=%d", kMusicLoopSampleRate),
+			fmt.Sprintf("aloop=loop=-1:size=%d", int(loopLen*kMusicLoopSampleRate+0.5)))
+	}
+	music = append(music,
+		fmt.Sprintf("atrim=duration=%.3f", opts.Duration),
+		"asetpts=PTS-STARTPTS",
+		fmt.Sprintf("volume=%g", opts.MusicVolume),
+	)
+	if opts.FadeIn > 0 {
+		music = append(music, fmt.Sprintf("afade=t=in:st=0:d=%.3f", opts.FadeIn))
+	}
+	if opts.FadeOut > 0 {
+		music = append(music, fmt.Sprintf("afade=t=out:st=%.3f:d=%.3f", opts.Duration-opts.FadeOut, opts.FadeOut))
+	}
+	filter := "[1:a]" + strings.Join(music, ",")
+	if !opts.HasVoice {
+		return filter + "[aout]"
+	}
+	filter += "[music];"
+
+	if opts.Ducking {
+		// 原音频分成两路, 一路参与混音, 一路作为 sidechaincompress 的检测信号
+		filter += fmt.Sprintf("[0:a]volume=%g,asplit=2[voice][sc];", opts.VoiceVolume)
+		filter += fmt.Sprintf("[music][sc]sidechaincompress=threshold=%g:ratio=%g:attack=20:release=400[ducked];",
+			opts.DuckThreshold, opts.DuckRatio)
+		return filter + "[voice][ducked]amix=inputs=2:duration=first:normalize=0[aout]"
+	}
+	filter += fmt.Sprintf("[0:a]volume=%g[voice];", opts.VoiceVolume)
+	return filter + "[voice][music]amix=inputs=2:duration=first:normalize=0[aout]"
+}
+
+// AddBackgroundMusic 给视频或音频混入背景音乐, 视频流直接拷贝
+func AddBackgroundMusic(inputFile string, musicFile string, outputFile string, opts *BackgroundMusicOptions) error {
+	if opts.Duration <= 0 {
+		return fmt.Errorf("invalid duration: %.3f", opts.Duration)
+	}
+	if opts.MusicDuration <= 0 {
+		return fmt.Errorf("invalid music duration: %.3f", opts.MusicDuration)
+	}
+	args := []string{"-i", inputFile}
+	args = append(args,
+		"-i", musicFile,
+		"-filter_complex", BuildBackgroundMusicFilter(opts),
+		"-map", "0:v?",
+		"-map", "[aout]",
+		"-c:v", "copy",
+		"-c:a", AudioEncoderForFile(outputFile),
+	)
+	if enc := AudioEncoderForFile(outputFile); enc != "pcm_s16le" && enc != "flac" {
+		args = append(args, "-b:a", "192k")
+	}
+	args = append(args, "-t", fmt.Sprintf("%.3f", opts.Duration), "-y", outputFile)
+	_, err := runFFmpeg(args)
+	return err
+}
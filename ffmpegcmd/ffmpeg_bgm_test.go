@@ -0,0 +1,35 @@
+package ffmpegcmd
+
+import "testing"
+
+func TestBuildBackgroundMusicFilter(t *testing.T) {
+	opts := &BackgroundMusicOptions{Duration: 30, HasVoice: true, FadeIn: 2, FadeOut: 3, Ducking: true}
+	want := "[1:a]atrim=duration=30.000,asetpts=PTS-STARTPTS,volume=0.3,afade=t=in:st=0:d=2.000,afade=t=out:st=27.000:d=3.000[music];" +
+		"[0:a]volume=1,asplit=2[voice][sc];" +
+		"[music][sc]sidechaincompress=threshold=0.05:ratio=8:attack=20:release=400[ducked];" +
+		"[voice][ducked]amix=inputs=2:duration=first:normalize=0[aout]"
+	if got := BuildBackgroundMusicFilter(opts); got != want {
+		t.Errorf("ducking filter got:\n%s\nwant:\n%s", got, want)
+	}
+
+	opts = &BackgroundMusicOptions{Duration: 10, HasVoice: true, MusicVolume: 0.5, VoiceVolume: 1.2}
+	want = "[1:a]atrim=duration=10.000,asetpts=PTS-STARTPTS,volume=0.5[music];[0:a]volume=1.2[voice];[voice][music]amix=inputs=2:duration=first:normalize=0[aout]"
+	if got := BuildBackgroundMusicFilter(opts); got != want {
+		t.Errorf("plain mix filter got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 没有原音频时只输出音乐, 淡入淡出按比例缩短到总时长以内
+	opts = &BackgroundMusicOptions{Duration: 4, FadeIn: 3, FadeOut: 5}
+	want = "[1:a]atrim=duration=4.000,asetpts=PTS-STARTPTS,volume=0.3,afade=t=in:st=0:d=1.500,afade=t=out:st=1.500:d=2.500[aout]"
+	if got := BuildBackgroundMusicFilter(opts); got != want {
+		t.Errorf("music only filter got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 音乐从第 10 秒开始, 剩下 20 秒不够 45 秒, 循环的是第 10 秒之后的部分
+	opts = &BackgroundMusicOptions{Duration: 45, MusicStart: 10, MusicDuration: 30}
+	want = "[1:a]atrim=start=10.000,asetpts=PTS-STARTPTS,aresample=48000,aloop=loop=-1:size=960000," +
+		"atrim=duration=45.000,asetpts=PTS-STARTPTS,volume=0.3[aout]"
+	if got := BuildBackgroundMusicFilter(opts); got != want {
+		t.Errorf("looped music filter got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ChapterMark struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+var ffmetadataEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+
+// BuildFFMetadata 生成 ffmpeg 的 FFMETADATA1 格式章节信息, 时间单位毫秒
+func BuildFFMetadata(chapters []ChapterMark) string {
+	var sb strings.Builder
+	sb.WriteString(";FFMETADATA1\n")
+	for _, chapter := range chapters {
+		sb.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+		sb.WriteString(fmt.Sprintf("START=%d\n", int64(chapter.Start*1000)))
+		sb.WriteString(fmt.Sprintf("END=%d\n", int64(chapter.End*1000)))
+		sb.WriteString(fmt.Sprintf("title=%s\n", ffmetadataEscaper.Replace(chapter.Title)))
+	}
+	return sb.String()
+}
+
+// WriteChapters 把章节写入输出文件(mp4/mkv), 音视频流拷贝不重新编码
+func WriteChapters(inputFile string, chapters []ChapterMark, outputFile string) error {
+	if len(chapters) == 0 {
+		return fmt.Errorf("no chapters to write")
+	}
+	metaFile, err := os.CreateTemp("", "gollmagent_chapters_*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(metaFile.Name())
+	if _, err := metaFile.WriteString(BuildFFMetadata(chapters)); err != nil {
+		metaFile.Close()
+		return err
+	}
+	metaFile.Close()
+
+	args := []string{
+		"-i", inputFile,
+		"-f", "ffmetadata", "-i", metaFile.Name(),
+		"-map", "0:v?", "-map", "0:a?",
+	}
+	if strings.ToLower(filepath.Ext(outputFile)) == ".mkv" {
+		// mkv 可以容纳任意字幕, mp4 只能容纳 mov_text, 所以只在 mkv 时保留字幕
+		args = append(args, "-map", "0:s?")
+	}
+	args = append(args,
+		"-map_metadata", "0",
+		"-map_chapters", "1",
+		"-c", "copy",
+		"-y", outputFile,
+	)
+	_, err = runFFmpeg(args)
+	return err
+}
@@ -0,0 +1,263 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gollmagent/pub"
+)
+
+var SupportedComposeLayouts = []string{"pip", "hstack", "vstack", "grid"}
+
+// 画中画小窗依次放在这些角落, 从 PipPosition 开始
+var pipCorners = []string{"top_right", "bottom_right", "bottom_left", "top_left"}
+
+type ComposeInput struct {
+	File     string
+	Width    int // 显示宽高(考虑旋转)
+	Height   int
+	Duration float64
+	HasAudio bool
+}
+
+type ComposeOptions struct {
+	Layout      string  // pip, hstack, vstack, grid
+	Height      int     // hstack/grid 每格的高度, pip 主画面的高度, 默认 720
+	Width       int     // vstack 每格的宽度, 默认 1280
+	Columns     int     // grid 的列数, 默认按输入个数取接近正方形的列数
+	PipScale    float64 // 小窗宽度占主画面宽度的比例, 默认 0.3
+	PipPosition string  // 第一个小窗的位置, 默认 top_right
+	PipMargin   int     // 小窗距离边缘的像素, 默认 20
+	Audio       string  // 音频来源: 输入序号(从 0 开始), mix 混合所有音频, none 不要音频. 默认 0
+	Duration    string  // shortest 以最短的输入为准, longest 以最长的为准(短的停在最后一帧), 默认 shortest
+}
+
+func (opts *ComposeOptions) setDefaults(n int) {
+	if opts.Height <= 0 {
+		opts.Height = 720
+	}
+	if opts.Width <= 0 {
+		opts.Width = 1280
+	}
+	if opts.Columns <= 0 {
+		opts.Columns = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	if opts.PipScale <= 0 || opts.PipScale >= 1 {
+		opts.PipScale = 0.3
+	}
+	if opts.PipPosition == "" {
+		opts.PipPosition = "top_right"
+	}
+	if opts.PipMargin < 0 {
+		opts.PipMargin = 0
+	} else if opts.PipMargin == 0 {
+		opts.PipMargin = 20
+	}
+	if opts.Audio == "" {
+		opts.Audio = "0"
+	}
+	if opts.Duration == "" {
+		opts.Duration = "shortest"
+	}
+}
+
+func even(v float64) int {
+	return int(math.Round(v/2)) * 2
+}
+
+// composeDuration 按时长策略计算输出时长
+func composeDuration(inputs []ComposeInput, policy string) (float64, error) {
+	d := inputs[0].Duration
+	for _, in := range inputs[1:] {
+		switch policy {
+		case "shortest":
+			d = math.Min(d, in.Duration)
+		case "longest":
+			d = math.Max(d, in.Duration)
+		default:
+			return 0, fmt.Errorf("invalid duration policy: %s, should be shortest or longest", policy)
+		}
+	}
+	return d, nil
+}
+
+// pipOverlayPosition 返回小窗在 corner 位置的 overlay 坐标表达式
+func pipOverlayPosition(corner string, margin int) (string, error) {
+	switch corner {
+	case "top_left":
+		return fmt.Sprintf("x=%d:y=%d", margin, margin), nil
+	case "top_right":
+		return fmt.Sprintf("x=W-w-%d:y=%d", margin, margin), nil
+	case "bottom_left":
+		return fmt.Sprintf("x=%d:y=H-h-%d", margin, margin), nil
+	case "bottom_right":
+		return fmt.Sprintf("x=W-w-%d:y=H-h-%d", margin, margin), nil
+	}
+	return "", fmt.Errorf("invalid pip position: %s", corner)
+}
+
+// buildComposeVideoFilter 生成画面部分的 filter, 输出标签 [vout]
+func buildComposeVideoFilter(inputs []ComposeInput, opts *ComposeOptions, duration float64) ([]string, error) {
+	var filters []string
+	n := len(inputs)
+
+	// 每个输入先缩放, longest 时短的输入停在最后一帧补齐时长
+	scaled := func(i int, scale string) {
+		f := fmt.Sprintf("[%d:v]%s,setsar=1", i, scale)
+		if pad := duration - inputs[i].Duration; opts.Duration == "longest" && pad > 0.01 {
+			f += fmt.Sprintf(",tpad=stop_mode=clone:stop_duration=%.3f", pad)
+		}
+		filters = append(filters, f+fmt.Sprintf("[v%d]", i))
+	}
+
+	switch opts.Layout {
+	case "hstack":
+		for i := range inputs {
+			scaled(i, fmt.Sprintf("scale=-2:%d", opts.Height))
+		}
+		var labels string
+		for i := range inputs {
+			labels += fmt.Sprintf("[v%d]", i)
+		}
+		filters = append(filters, fmt.Sprintf("%shstack=inputs=%d:shortest=1[vout]", labels, n))
+	case "vstack":
+		for i := range inputs {
+			scaled(i, fmt.Sprintf("scale=%d:-2", opts.Width))
+		}
+		var labels string
+		for i := range inputs {
+			labels += fmt.Sprintf("[v%d]", i)
+		}
+		filters = append(filters, fmt.Sprintf("%svstack=inputs=%d:shortest=1[vout]", labels, n))
+	case "grid":
+		// 每格大小相同, 不同比例的输入缩放后补黑边
+		cellH := opts.Height
+		cellW := even(float64(cellH) * 16 / 9)
+		var labels string
+		var layout []string
+		for i := range inputs {
+			scaled(i, fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2",
+				cellW, cellH, cellW, cellH))
+			labels += fmt.Sprintf("[v%d]", i)
+			layout = append(layout, fmt.Sprintf("%d_%d", i%opts.Columns*cellW, i/opts.Columns*cellH))
+		}
+		f := fmt.Sprintf("%sxstack=inputs=%d:layout=%s:shortest=1", labels, n, strings.Join(layout, "|"))
+		if n%opts.Columns != 0 {
+			f += ":fill=black"
+		}
+		filters = append(filters, f+"[vout]")
+	case "pip":
+		if n > len(pipCorners)+1 {
+			return nil, fmt.Errorf("pip supports at most %d overlay videos", len(pipCorners))
+		}
+		start := -1
+		for i, c := range pipCorners {
+			if c == opts.PipPosition {
+				start = i
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("invalid pip position: %s, should be one of %s", opts.PipPosition, strings.Join(pipCorners, ", "))
+		}
+		main := inputs[0]
+		if main.Width <= 0 || main.Height <= 0 {
+			return nil, fmt.Errorf("unknown size of the main video: %s", main.File)
+		}
+		mainW := even(float64(opts.Height) * float64(main.Width) / float64(main.Height))
+		pipW := even(float64(mainW) * opts.PipScale)
+		scaled(0, fmt.Sprintf("scale=%d:%d", mainW, opts.Height))
+		for i := 1; i < n; i++ {
+			scaled(i, fmt.Sprintf("scale=%d:-2", pipW))
+		}
+		last := "[v0]"
+		for i := 1; i < n; i++ {
+			pos, _ := pipOverlayPosition(pipCorners[(start+i-1)%len(pipCorners)], opts.PipMargin)
+			out := fmt.Sprintf("[o%d]", i)
+			if i == n-1 {
+				out = "[vout]"
+			}
+			filters = append(filters, fmt.Sprintf("%s[v%d]overlay=%s:shortest=1%s", last, i, pos, out))
+			last = out
+		}
+	default:
+		return nil, fmt.Errorf("unsupported layout: %s, should be one of %s", opts.Layout, strings.Join(SupportedComposeLayouts, ", "))
+	}
+	return filters, nil
+}
+
+// buildComposeAudioFilter 生成音频部分的 filter, 输出标签 [aout], 不要音频时返回 nil
+func buildComposeAudioFilter(inputs []ComposeInput, opts *ComposeOptions) ([]string, error) {
+	switch opts.Audio {
+	case "none":
+		return nil, nil
+	case "mix":
+		var labels string
+		count := 0
+		for i, in := range inputs {
+			if in.HasAudio {
+				labels += fmt.Sprintf("[%d:a]", i)
+				count++
+			}
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		if count == 1 {
+			return []string{labels + "apad[aout]"}, nil
+		}
+		return []string{fmt.Sprintf("%samix=inputs=%d:duration=longest,apad[aout]", labels, count)}, nil
+	}
+	var idx int
+	if _, err := fmt.Sscanf(opts.Audio, "%d", &idx); err != nil || idx < 0 || idx >= len(inputs) {
+		return nil, fmt.Errorf("invalid audio source: %s, should be an input index, mix or none", opts.Audio)
+	}
+	if !inputs[idx].HasAudio {
+		return nil, fmt.Errorf("input %d (%s) has no audio", idx, inputs[idx].File)
+	}
+	// 补静音, 最终时长由 -t 控制
+	return []string{fmt.Sprintf("[%d:a]apad[aout]", idx)}, nil
+}
+
+// BuildComposeArgs 生成多画面合成的 ffmpeg 参数, 返回输出时长
+func BuildComposeArgs(inputs []ComposeInput, outputFile string, opts *ComposeOptions) ([]string, float64, error) {
+	if len(inputs) < 2 {
+		return nil, 0, fmt.Errorf("at least two input videos are required")
+	}
+	opts.setDefaults(len(inputs))
+	duration, err := composeDuration(inputs, opts.Duration)
+	if err != nil {
+		return nil, 0, err
+	}
+	filters, err := buildComposeVideoFilter(inputs, opts, duration)
+	if err != nil {
+		return nil, 0, err
+	}
+	audioFilters, err := buildComposeAudioFilter(inputs, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	filters = append(filters, audioFilters...)
+
+	var args []string
+	for _, in := range inputs {
+		args = append(args, "-i", in.File)
+	}
+	args = append(args, "-filter_complex", strings.Join(filters, ";"), "-map", "[vout]")
+	if len(audioFilters) > 0 {
+		args = append(args, "-map", "[aout]", "-c:a", "aac", "-b:a", "128k")
+	} else {
+		args = append(args, "-an")
+	}
+	args = append(args,
+		"-c:v", "libx264", "-crf", "20", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-t", fmt.Sprintf("%.3f", duration),
+		"-movflags", "+faststart",
+		"-y", outputFile)
+	return args, duration, nil
+}
+
+// ComposeVideos 把多个视频合成到一个画面里并上报进度
+func ComposeVideos(id string, args []string, duration float64, progressObj pub.ProgressCallback) error {
+	return runFFmpegWithProgress(id, "多画面合成", args, duration, progressObj)
+}
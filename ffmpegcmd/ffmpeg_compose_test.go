@@ -0,0 +1,76 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+var composeInputs = []ComposeInput{
+	{File: "a.mp4", Width: 1920, Height: 1080, Duration: 10, HasAudio: true},
+	{File: "b.mp4", Width: 1080, Height: 1920, Duration: 6, HasAudio: true},
+	{File: "c.mp4", Width: 1280, Height: 720, Duration: 8},
+}
+
+func composeFilter(t *testing.T, args []string) string {
+	for i, a := range args {
+		if a == "-filter_complex" {
+			return args[i+1]
+		}
+	}
+	t.Fatalf("no filter_complex in %v", args)
+	return ""
+}
+
+func TestBuildComposeHstack(t *testing.T) {
+	args, duration, err := BuildComposeArgs(composeInputs[:2], "out.mp4", &ComposeOptions{Layout: "hstack", Duration: "longest", Audio: "mix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[0:v]scale=-2:720,setsar=1[v0];" +
+		"[1:v]scale=-2:720,setsar=1,tpad=stop_mode=clone:stop_duration=4.000[v1];" +
+		"[v0][v1]hstack=inputs=2:shortest=1[vout];" +
+		"[0:a][1:a]amix=inputs=2:duration=longest,apad[aout]"
+	if got := composeFilter(t, args); got != want || duration != 10 {
+		t.Errorf("filter got:\n%s\nwant:\n%s\nduration %g", got, want, duration)
+	}
+	if !strings.Contains(strings.Join(args, " "), "-map [aout] -c:a aac -b:a 128k -c:v libx264 -crf 20 -preset medium -pix_fmt yuv420p -t 10.000") {
+		t.Errorf("args got %v", args)
+	}
+}
+
+func TestBuildComposeGrid(t *testing.T) {
+	args, duration, err := BuildComposeArgs(composeInputs, "out.mp4", &ComposeOptions{Layout: "grid", Height: 360, Audio: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := composeFilter(t, args)
+	if !strings.HasSuffix(got, "[v0][v1][v2]xstack=inputs=3:layout=0_0|640_0|0_360:shortest=1:fill=black[vout]") || duration != 6 {
+		t.Errorf("grid filter got %s, duration %g", got, duration)
+	}
+	if !strings.Contains(got, "[1:v]scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1[v1]") {
+		t.Errorf("grid cell filter got %s", got)
+	}
+	if !strings.Contains(strings.Join(args, " "), "-map [vout] -an") {
+		t.Errorf("args should drop audio: %v", args)
+	}
+}
+
+func TestBuildComposePip(t *testing.T) {
+	args, _, err := BuildComposeArgs(composeInputs, "out.mp4", &ComposeOptions{Layout: "pip", PipPosition: "bottom_right", Audio: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[0:v]scale=1280:720,setsar=1[v0];[1:v]scale=384:-2,setsar=1[v1];[2:v]scale=384:-2,setsar=1[v2];" +
+		"[v0][v1]overlay=x=W-w-20:y=H-h-20:shortest=1[o1];[o1][v2]overlay=x=20:y=H-h-20:shortest=1[vout];" +
+		"[1:a]apad[aout]"
+	if got := composeFilter(t, args); got != want {
+		t.Errorf("pip filter got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, _, err := BuildComposeArgs(composeInputs, "out.mp4", &ComposeOptions{Layout: "pip", Audio: "2"}); err == nil {
+		t.Errorf("expected error for audio source without audio")
+	}
+	if _, _, err := BuildComposeArgs(composeInputs, "out.mp4", &ComposeOptions{Layout: "mosaic"}); err == nil {
+		t.Errorf("expected error for unsupported layout")
+	}
+}
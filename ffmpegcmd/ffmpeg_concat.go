@@ -2,72 +2,271 @@ package ffmpegcmd
 
 import (
 	"fmt"
+	"math"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
 	log "github.com/gollmagent/logging"
 )
 
-// ConcatVideosWithResize 合并多个MP4文件，统一缩放至目标分辨率（默认1920x1080）
-func ConcatVideosWithResize(files []string, outputFile string) error {
-	if len(files) == 0 {
-		return fmt.Errorf("no input files provided")
-	}
-
-	// 构造FFmpeg命令
-	var args []string
+// 重新编码时的帧率上限
+const kMaxConcatFrameRate = 60
 
-	// 添加输入文件
-	for _, file := range files {
-		args = append(args, "-i", file)
-	}
+// ConcatInput 拼接需要的输入参数, 由 ffprobe 结果生成
+type ConcatInput struct {
+	File       string
+	Duration   float64
+	Width      int // 显示宽高(考虑旋转)
+	Height     int
+	Rotation   int
+	FrameRate  float64
+	VideoCodec string
+	Profile    string // h264/hevc 的 profile 和 level, concat demuxer 只用第一个文件的参数集, 不一致时不能直接拷贝
+	Level      int
+	TimeBase   string
+	PixFmt     string
+	HasAudio   bool
+	AudioCodec string
+	SampleRate string
+	Channels   int
+}
 
-	// 构造filter_complex
-	filter := buildFilterComplex(files)
-	args = append(args, "-filter_complex", filter)
-	args = append(args, "-map", "[outv]", "-map", "[outa]") // 映射输出流
-	args = append(args, "-c:v", "libx264", "-crf", "23")    // 可选：设置编码参数
-	args = append(args, "-preset", "fast")                  // 可选：编码速度
-	args = append(args, outputFile)                         // 输出文件
+type ConcatOptions struct {
+	Transition         string  // xfade 转场, 空或 none 表示直接拼接
+	TransitionDuration float64 // 转场时长(秒), 默认 1
+	ForceReencode      bool    // 参数一致时也重新编码
+}
 
-	// 打印完整命令（调试用）
-	log.Info("FFmpeg command:", "ffmpeg", strings.Join(args, " "))
+// NewConcatInput 从 ffprobe 结果生成拼接输入
+func NewConcatInput(file string, pr *ffprobe.ProbeResp) (ConcatInput, error) {
+	video := pr.FirstVideo()
+	if video == nil {
+		return ConcatInput{}, fmt.Errorf("%s has no video stream", file)
+	}
+	in := ConcatInput{
+		File:       file,
+		Duration:   pr.Duration(),
+		Rotation:   video.Rotation(),
+		FrameRate:  video.FrameRate(),
+		VideoCodec: video.CodecName,
+		Profile:    video.Profile,
+		Level:      video.Level,
+		TimeBase:   string(video.TimeBase),
+		PixFmt:     video.PixFmt,
+	}
+	in.Width, in.Height = video.DisplaySize()
+	if audio := pr.FirstAudio(); audio != nil {
+		in.HasAudio = true
+		in.AudioCodec = audio.CodecName
+		in.SampleRate = audio.SampleRate
+		in.Channels = audio.Channels
+	}
+	return in, nil
+}
 
-	// 执行FFmpeg
-	cmd := exec.Command("ffmpeg", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// CanStreamCopy 判断所有输入的编码参数是否一致, 一致时可以用 concat demuxer 直接拷贝.
+// 输出只有一份 avcC/hvcC(取自第一个文件), profile/level 不同时拷贝出来的流无法正常解码
+func CanStreamCopy(inputs []ConcatInput) bool {
+	first := inputs[0]
+	for _, in := range inputs[1:] {
+		if in.VideoCodec != first.VideoCodec || in.Profile != first.Profile || in.Level != first.Level ||
+			in.TimeBase != first.TimeBase || in.PixFmt != first.PixFmt ||
+			in.Width != first.Width || in.Height != first.Height || in.Rotation != first.Rotation ||
+			math.Abs(in.FrameRate-first.FrameRate) > 0.01 {
+			return false
+		}
+		if in.HasAudio != first.HasAudio {
+			return false
+		}
+		if in.HasAudio && (in.AudioCodec != first.AudioCodec || in.SampleRate != first.SampleRate || in.Channels != first.Channels) {
+			return false
+		}
+	}
+	return true
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg execution failed: %v", err)
+// ChooseConcatTarget 重新编码时的目标参数: 取输入中面积最大的分辨率和最高的帧率(不超过 60)
+func ChooseConcatTarget(inputs []ConcatInput) (int, int, float64) {
+	var w, h int
+	var fps float64
+	for _, in := range inputs {
+		if in.Width*in.Height > w*h {
+			w, h = in.Width, in.Height
+		}
+		fps = math.Max(fps, in.FrameRate)
+	}
+	if fps <= 0 {
+		fps = 30
 	}
+	fps = math.Min(fps, kMaxConcatFrameRate)
+	return w / 2 * 2, h / 2 * 2, math.Round(fps*1000) / 1000
+}
 
+// checkTransitionDuration 每段要比转场长, 中间的片段两头都有转场, 要比两个转场长
+func checkTransitionDuration(inputs []ConcatInput, d float64) error {
+	for i, in := range inputs {
+		need := d
+		if i > 0 && i < len(inputs)-1 {
+			need = 2 * d
+		}
+		if in.Duration <= need {
+			return fmt.Errorf("%s is too short (%.2fs) for %.2fs transitions", in.File, in.Duration, d)
+		}
+	}
 	return nil
 }
 
-// buildFilterComplex 构造FFmpeg的filter_complex参数
-func buildFilterComplex(files []string) string {
+// buildConcatFilter 构造重新编码拼接的 filter_complex: 统一分辨率/帧率, 没有音频的片段补静音,
+// 可选 xfade/acrossfade 转场. 输出标签 [outv], 有音频时 [outa], 返回输出时长
+func buildConcatFilter(inputs []ConcatInput, width, height int, fps float64, opts *ConcatOptions) (string, bool, float64) {
 	var filters []string
-	targetWidth, targetHeight := 1920, 1080 // 目标分辨率（可调整）
+	withAudio := false
+	for _, in := range inputs {
+		withAudio = withAudio || in.HasAudio
+	}
+	fpsStr := strconv.FormatFloat(fps, 'f', -1, 64)
 
 	// 对每个视频进行缩放和填充
-	for i := range files {
-		scaleFilter := fmt.Sprintf(
-			"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1[v%d]",
-			i, targetWidth, targetHeight, targetWidth, targetHeight, i,
-		)
-		filters = append(filters, scaleFilter)
+	for i, in := range inputs {
+		filters = append(filters, fmt.Sprintf(
+			"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s,format=yuv420p,settb=AVTB,setpts=PTS-STARTPTS[v%d]",
+			i, width, height, width, height, fpsStr, i))
+		if !withAudio {
+			continue
+		}
+		if in.HasAudio {
+			filters = append(filters, fmt.Sprintf(
+				"[%d:a]aresample=48000,aformat=sample_fmts=fltp:channel_layouts=stereo,asetpts=PTS-STARTPTS[a%d]", i, i))
+		} else {
+			filters = append(filters, fmt.Sprintf(
+				"anullsrc=channel_layout=stereo:sample_rate=48000,atrim=duration=%.3f,aformat=sample_fmts=fltp[a%d]", in.Duration, i))
+		}
+	}
+
+	total := 0.0
+	for _, in := range inputs {
+		total += in.Duration
 	}
 
-	// 构造concat部分
-	var concatInputs []string
-	for i := range files {
-		concatInputs = append(concatInputs, fmt.Sprintf("[v%d][%d:a]", i, i))
+	if opts.Transition == "" || opts.Transition == "none" {
+		// 构造concat部分
+		var concatInputs []string
+		for i := range inputs {
+			if withAudio {
+				concatInputs = append(concatInputs, fmt.Sprintf("[v%d][a%d]", i, i))
+			} else {
+				concatInputs = append(concatInputs, fmt.Sprintf("[v%d]", i))
+			}
+		}
+		if withAudio {
+			filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=1[outv][outa]", strings.Join(concatInputs, ""), len(inputs)))
+		} else {
+			filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[outv]", strings.Join(concatInputs, ""), len(inputs)))
+		}
+		return strings.Join(filters, ";"), withAudio, total
 	}
-	concatFilter := fmt.Sprintf("%sconcat=n=%d:v=1:a=1[outv][outa]", strings.Join(concatInputs, ""), len(files))
 
-	// 合并所有filter
-	fullFilter := strings.Join(filters, "; ") + ";" + concatFilter
-	return fullFilter
+	// 转场: 每次把已拼好的部分和下一段重叠 d 秒
+	d := opts.TransitionDuration
+	length := inputs[0].Duration
+	lastV, lastA := "[v0]", "[a0]"
+	for i := 1; i < len(inputs); i++ {
+		outV, outA := fmt.Sprintf("[xv%d]", i), fmt.Sprintf("[xa%d]", i)
+		if i == len(inputs)-1 {
+			outV, outA = "[outv]", "[outa]"
+		}
+		filters = append(filters, fmt.Sprintf("%s[v%d]xfade=transition=%s:duration=%.3f:offset=%.3f%s",
+			lastV, i, opts.Transition, d, length-d, outV))
+		if withAudio {
+			filters = append(filters, fmt.Sprintf("%s[a%d]acrossfade=d=%.3f%s", lastA, i, d, outA))
+		}
+		length += inputs[i].Duration - d
+		lastV, lastA = outV, outA
+	}
+	return strings.Join(filters, ";"), withAudio, length
+}
+
+// BuildConcatArgs 生成重新编码拼接的 ffmpeg 参数
+func BuildConcatArgs(inputs []ConcatInput, outputFile string, opts *ConcatOptions) ([]string, error) {
+	if opts.Transition != "" && opts.Transition != "none" {
+		valid := false
+		for _, t := range SupportedTransitions {
+			valid = valid || t == opts.Transition
+		}
+		if !valid {
+			return nil, fmt.Errorf("unsupported transition: %s", opts.Transition)
+		}
+		if opts.TransitionDuration <= 0 {
+			opts.TransitionDuration = 1
+		}
+		if err := checkTransitionDuration(inputs, opts.TransitionDuration); err != nil {
+			return nil, err
+		}
+	}
+	w, h, fps := ChooseConcatTarget(inputs)
+	filter, withAudio, _ := buildConcatFilter(inputs, w, h, fps, opts)
+
+	var args []string
+	for _, in := range inputs {
+		args = append(args, "-i", in.File)
+	}
+	args = append(args, "-filter_complex", filter, "-map", "[outv]")
+	if withAudio {
+		args = append(args, "-map", "[outa]", "-c:a", "aac", "-b:a", "128k")
+	}
+	return append(args,
+		"-c:v", "libx264", "-crf", "23", "-preset", "fast", "-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		"-y", outputFile), nil
+}
+
+// ConcatMedia 拼接多个视频. 参数一致且不需要转场时用 concat demuxer 流拷贝, 否则重新编码. 返回使用的方式 copy 或 reencode
+func ConcatMedia(inputs []ConcatInput, outputFile string, opts *ConcatOptions) (string, error) {
+	if len(inputs) < 2 {
+		return "", fmt.Errorf("need at least two input files to concat")
+	}
+	noTransition := opts.Transition == "" || opts.Transition == "none"
+	if noTransition && !opts.ForceReencode && CanStreamCopy(inputs) {
+		tmpDir, err := os.MkdirTemp("", "gollmagent_concat_")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(tmpDir)
+		var files []string
+		for _, in := range inputs {
+			files = append(files, in.File)
+		}
+		log.Infof("concat %d files with stream copy: %v", len(files), files)
+		return "copy", concatDemuxerCopy(files, outputFile, tmpDir)
+	}
+
+	args, err := BuildConcatArgs(inputs, outputFile, opts)
+	if err != nil {
+		return "", err
+	}
+	_, err = runFFmpeg(args)
+	return "reencode", err
+}
+
+// ConcatVideosWithResize 合并多个视频文件, 参数一致时直接拷贝, 否则统一为输入中最大的分辨率重新编码
+func ConcatVideosWithResize(files []string, outputFile string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no input files provided")
+	}
+	var inputs []ConcatInput
+	for _, file := range files {
+		pr, err := ffprobe.Probe(file)
+		if err != nil {
+			return fmt.Errorf("probe %s failed: %v", file, err)
+		}
+		in, err := NewConcatInput(file, pr)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, in)
+	}
+	_, err := ConcatMedia(inputs, outputFile, &ConcatOptions{})
+	return err
 }
@@ -2,6 +2,7 @@ package ffmpegcmd
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -15,3 +16,87 @@ func TestConcat(t *testing.T) {
 	}
 	fmt.Printf("Merged file created: %s\n", outputFile)
 }
+
+func TestCanStreamCopy(t *testing.T) {
+	a := ConcatInput{File: "a.mp4", Width: 1920, Height: 1080, FrameRate: 30, VideoCodec: "h264", Profile: "High", Level: 40,
+		TimeBase: "1/15360", PixFmt: "yuv420p", HasAudio: true, AudioCodec: "aac", SampleRate: "48000", Channels: 2}
+	b := a
+	b.File = "b.mp4"
+	if !CanStreamCopy([]ConcatInput{a, b}) {
+		t.Errorf("same parameters should be stream copied")
+	}
+	c := b
+	c.Width, c.Height = 1280, 720
+	if CanStreamCopy([]ConcatInput{a, c}) {
+		t.Errorf("different sizes should not be stream copied")
+	}
+	d := b
+	d.HasAudio = false
+	if CanStreamCopy([]ConcatInput{a, d}) {
+		t.Errorf("input without audio should not be stream copied")
+	}
+	// 同样 1080p30 h264, profile 不同时参数集不兼容
+	e := b
+	e.Profile = "Main"
+	if CanStreamCopy([]ConcatInput{a, e}) {
+		t.Errorf("different profiles should not be stream copied")
+	}
+	f := b
+	f.Level, f.TimeBase = 42, "1/30000"
+	if CanStreamCopy([]ConcatInput{a, f}) {
+		t.Errorf("different level and time base should not be stream copied")
+	}
+}
+
+func TestChooseConcatTarget(t *testing.T) {
+	inputs := []ConcatInput{
+		{Width: 1280, Height: 720, FrameRate: 25},
+		{Width: 1081, Height: 1921, FrameRate: 29.97},
+		{Width: 640, Height: 360, FrameRate: 120},
+	}
+	w, h, fps := ChooseConcatTarget(inputs)
+	if w != 1080 || h != 1920 || fps != 60 {
+		t.Errorf("ChooseConcatTarget() = %d, %d, %g", w, h, fps)
+	}
+}
+
+func TestBuildConcatArgs(t *testing.T) {
+	inputs := []ConcatInput{
+		{File: "a.mp4", Width: 1280, Height: 720, FrameRate: 30, Duration: 5, HasAudio: true},
+		{File: "b.mp4", Width: 1280, Height: 720, FrameRate: 30, Duration: 4},
+		{File: "c.mp4", Width: 1280, Height: 720, FrameRate: 30, Duration: 6, HasAudio: true},
+	}
+	args, err := BuildConcatArgs(inputs, "out.mp4", &ConcatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := strings.Join(args, " ")
+	if !strings.Contains(filter, "anullsrc=channel_layout=stereo:sample_rate=48000,atrim=duration=4.000") {
+		t.Errorf("missing silent audio for input without audio: %s", filter)
+	}
+	if !strings.Contains(filter, "[v0][a0][v1][a1][v2][a2]concat=n=3:v=1:a=1[outv][outa]") {
+		t.Errorf("unexpected concat filter: %s", filter)
+	}
+
+	args, err = BuildConcatArgs(inputs, "out.mp4", &ConcatOptions{Transition: "fade", TransitionDuration: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter = strings.Join(args, " ")
+	for _, want := range []string{
+		"[v0][v1]xfade=transition=fade:duration=1.000:offset=4.000[xv1]",
+		"[xv1][v2]xfade=transition=fade:duration=1.000:offset=7.000[outv]",
+		"[xa1][a2]acrossfade=d=1.000[outa]",
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("missing %q in %s", want, filter)
+		}
+	}
+
+	if _, err := BuildConcatArgs(inputs, "out.mp4", &ConcatOptions{Transition: "fade", TransitionDuration: 2}); err == nil {
+		t.Errorf("expected error for transitions longer than half of the middle clip")
+	}
+	if _, err := BuildConcatArgs(inputs, "out.mp4", &ConcatOptions{Transition: "spin"}); err == nil {
+		t.Errorf("expected error for unsupported transition")
+	}
+}
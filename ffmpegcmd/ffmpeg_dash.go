@@ -0,0 +1,126 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/pub"
+)
+
+const (
+	kDashManifest       = "manifest.mpd"
+	kDashRepresentation = "$RepresentationID$"
+)
+
+type DashOptions struct {
+	Ladder          []Rendition
+	SegmentDuration float64 // 分片时长(秒), 默认4
+	AudioStreams    int     // 输入的音频流数量, 每路音频单独一个 adaptation set
+	AudioBitrate    int     // 音频码率 kbps, 默认128
+	DownmixStereo   bool    // 音频混缩为立体声, 默认保持源声道数(5.1/7.1 保持多声道)
+	InitSegName     string  // init 分片命名模板, 默认 init-$RepresentationID$.$ext$
+	MediaSegName    string  // media 分片命名模板, 默认 chunk-$RepresentationID$-$Number%05d$.$ext$
+	UseTimeline     bool    // 使用 SegmentTimeline
+}
+
+func (opts *DashOptions) setDefaults() {
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = 4
+	}
+	if opts.AudioBitrate <= 0 {
+		opts.AudioBitrate = 128
+	}
+	if opts.InitSegName == "" {
+		opts.InitSegName = "init-$RepresentationID$.$ext$"
+	}
+	if opts.MediaSegName == "" {
+		opts.MediaSegName = "chunk-$RepresentationID$-$Number%05d$.$ext$"
+	}
+}
+
+func (opts *DashOptions) validate() error {
+	if len(opts.Ladder) == 0 {
+		return fmt.Errorf("empty rendition ladder")
+	}
+	// 多个 representation 共用一个目录, 模板里必须带 $RepresentationID$ 否则文件会互相覆盖
+	for _, name := range []string{opts.InitSegName, opts.MediaSegName} {
+		if !strings.Contains(name, kDashRepresentation) {
+			return fmt.Errorf("segment name template %q must contain %s", name, kDashRepresentation)
+		}
+		if strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("segment name template %q must not contain path separators", name)
+		}
+	}
+	return nil
+}
+
+// dashAdaptationSets 视频档位放在同一个 adaptation set, 每路音频各自一个 adaptation set
+func dashAdaptationSets(videoCount int, audioCount int) string {
+	var videoIds []string
+	for i := 0; i < videoCount; i++ {
+		videoIds = append(videoIds, fmt.Sprintf("%d", i))
+	}
+	sets := []string{"id=0,streams=" + strings.Join(videoIds, ",")}
+	for i := 0; i < audioCount; i++ {
+		sets = append(sets, fmt.Sprintf("id=%d,streams=%d", i+1, videoCount+i))
+	}
+	return strings.Join(sets, " ")
+}
+
+// buildDashArgs 生成 DASH 打包的完整 ffmpeg 参数
+func buildDashArgs(inputFile string, outputDir string, opts *DashOptions) []string {
+	args := []string{"-i", inputFile}
+	args = append(args, buildLadderVideoArgs(opts.Ladder, opts.SegmentDuration)...)
+	for i := 0; i < opts.AudioStreams; i++ {
+		args = append(args,
+			"-map", fmt.Sprintf("0:a:%d", i),
+			fmt.Sprintf("-c:a:%d", i), "aac",
+			fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", opts.AudioBitrate),
+		)
+	}
+	if opts.AudioStreams > 0 {
+		// aac 编码器支持到 7.1, 不需要限制声道数
+		if opts.DownmixStereo {
+			args = append(args, "-ac", "2")
+		}
+		args = append(args, "-ar", "48000")
+	}
+
+	useTimeline := "0"
+	if opts.UseTimeline {
+		useTimeline = "1"
+	}
+	args = append(args,
+		"-f", "dash",
+		"-seg_duration", fmt.Sprintf("%g", opts.SegmentDuration),
+		"-use_template", "1",
+		"-use_timeline", useTimeline,
+		"-init_seg_name", opts.InitSegName,
+		"-media_seg_name", opts.MediaSegName,
+		"-adaptation_sets", dashAdaptationSets(len(opts.Ladder), opts.AudioStreams),
+		"-y", DashManifest(outputDir),
+	)
+	return args
+}
+
+// PackageDash 一次 ffmpeg 调用把输入转成多档码率的 DASH, 输出 outputDir/manifest.mpd, 进度通过 progressObj 上报
+func PackageDash(id string, inputFile string, outputDir string, opts *DashOptions, duration float64, progressObj pub.ProgressCallback) error {
+	opts.setDefaults()
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	args := buildDashArgs(inputFile, outputDir, opts)
+	return runFFmpegWithProgress(id, "DASH打包", args, duration, progressObj)
+}
+
+// CheckDashOptions 在启动异步打包前检查参数, 避免错误只能通过进度回调看到
+func CheckDashOptions(opts *DashOptions) error {
+	opts.setDefaults()
+	return opts.validate()
+}
+
+// DashManifest 返回 PackageDash 输出的 mpd 路径
+func DashManifest(outputDir string) string {
+	return filepath.Join(outputDir, kDashManifest)
+}
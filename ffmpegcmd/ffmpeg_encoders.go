@@ -0,0 +1,52 @@
+package ffmpegcmd
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	encodersOnce sync.Once
+	encodersList map[string]string
+	encodersErr  error
+)
+
+// ParseEncoderList 解析 `ffmpeg -encoders` 的输出, 返回 编码器名 -> 类型(V/A/S)
+func ParseEncoderList(output string) map[string]string {
+	encoders := map[string]string{}
+	started := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// 表头和编码器列表之间用 ------ 分隔
+		if !started {
+			started = strings.HasPrefix(line, "---")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields[0]) != 6 {
+			continue
+		}
+		encoders[fields[1]] = fields[0][:1]
+	}
+	return encoders
+}
+
+// GetEncoders 返回本地 ffmpeg 支持的编码器, 只查询一次
+func GetEncoders() (map[string]string, error) {
+	encodersOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-encoders").Output()
+		if err != nil {
+			encodersErr = err
+			return
+		}
+		encodersList = ParseEncoderList(string(out))
+	})
+	return encodersList, encodersErr
+}
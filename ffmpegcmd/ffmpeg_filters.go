@@ -0,0 +1,64 @@
+package ffmpegcmd
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	filtersOnce sync.Once
+	filtersList map[string]bool
+	filtersErr  error
+)
+
+// ParseFilterList 解析 `ffmpeg -filters` 的输出, 返回滤镜名集合
+func ParseFilterList(output string) map[string]bool {
+	filters := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		// 每行格式: " TSC libvmaf           VV->V      Calculate the VMAF ..."
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.Contains(fields[2], "->") {
+			continue
+		}
+		if strings.Trim(fields[0], "TSC.") != "" {
+			continue
+		}
+		filters[fields[1]] = true
+	}
+	return filters
+}
+
+// GetFilters 返回本地 ffmpeg 支持的滤镜, 只查询一次
+func GetFilters() (map[string]bool, error) {
+	filtersOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-filters").Output()
+		if err != nil {
+			filtersErr = err
+			return
+		}
+		filtersList = ParseFilterList(string(out))
+	})
+	return filtersList, filtersErr
+}
+
+// EscapeFilterValue 转义滤镜参数值(例如文件路径), 先按滤镜参数转义 \ ' :, 再按 filtergraph 转义
+func EscapeFilterValue(value string) string {
+	escape := func(s string, special string) string {
+		var b strings.Builder
+		for _, r := range s {
+			if strings.ContainsRune(special, r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return escape(escape(value, `\':`), `\'[],;`)
+}
@@ -7,19 +7,28 @@ import (
 	log "github.com/gollmagent/logging"
 )
 
+// frameExtractArgs 截帧的公共参数: 用 filter 选出需要的帧, -vsync vfr 不补帧, 后面再加编码参数和 image2 的输出路径.
+// 用 -vsync 而不是 -fps_mode, 兼容 5.1 之前的 ffmpeg
+func frameExtractArgs(inputVideo string, filter string) []string {
+	return []string{
+		"-i", inputVideo, // 输入视频文件
+		"-an", "-sn",
+		"-vf", filter,
+		"-vsync", "vfr", // 可变帧率
+	}
+}
+
 func GenPictureFromVideoBaseOnIframe(inputVideo string, outputDir string) error {
 	// ffmpeg cmd: gen picture from video based on I-frame
 	// ffmpeg -i input.mp4 -vf "select='eq(pict_type\,I)'" -vsync vfr -frame_pts true outputDir/out_%04d.jpg
 
 	// 构建 FFmpeg 命令参数
-	args := []string{
-		"-i", inputVideo, // 输入视频文件
-		"-vf", "select='eq(pict_type\\,I)'", // 选择 I 帧
-		"-vsync", "vfr", // 可变帧率
+	args := frameExtractArgs(inputVideo, "select='eq(pict_type\\,I)'") // 选择 I 帧
+	args = append(args,
 		"-frame_pts", "true", // 使用帧的时间戳作为文件名的一部分
 		"-y", // 覆盖输出文件
 		fmt.Sprintf("%s/out_%%04d.jpg", outputDir), // 输出图片文件路径
-	}
+	)
 	// 执行 FFmpeg 命令
 	// cmd := exec.Command("ffmpeg", "-i", inputVideo, "-vf", "select='eq(pict_type\\,I)'", "-vsync", "vfr", "-frame_pts", "true", "-y", fmt.Sprintf("%s/out_%%04d.jpg", outputDir))
 	cmd := exec.Command("ffmpeg", args...)
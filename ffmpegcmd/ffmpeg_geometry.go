@@ -0,0 +1,226 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+)
+
+var cropdetectRe = regexp.MustCompile(`crop=(-?\d+):(-?\d+):(-?\d+):(-?\d+)`)
+
+// 常用画面比例, 也可以直接传 W:H
+var AspectPresets = []string{"16:9", "9:16", "1:1", "4:3", "3:4", "4:5", "21:9"}
+
+type CropRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Filter 返回 crop 滤镜
+func (r CropRect) Filter() string {
+	return fmt.Sprintf("crop=%d:%d:%d:%d", r.Width, r.Height, r.X, r.Y)
+}
+
+// Validate 检查裁剪区域在画面内, 宽高为偶数(yuv420p 的要求)
+func (r CropRect) Validate(srcW, srcH int) error {
+	if r.Width <= 0 || r.Height <= 0 || r.X < 0 || r.Y < 0 {
+		return fmt.Errorf("invalid crop rect: %+v", r)
+	}
+	if r.X+r.Width > srcW || r.Y+r.Height > srcH {
+		return fmt.Errorf("crop rect %+v is outside of the %dx%d frame", r, srcW, srcH)
+	}
+	if r.Width%2 != 0 || r.Height%2 != 0 {
+		return fmt.Errorf("crop width and height must be even: %dx%d", r.Width, r.Height)
+	}
+	return nil
+}
+
+func parseAspect(aspect string) (float64, error) {
+	items := strings.Split(aspect, ":")
+	if len(items) != 2 {
+		return 0, fmt.Errorf("invalid aspect ratio: %s, should be W:H", aspect)
+	}
+	w, err1 := strconv.ParseFloat(items[0], 64)
+	h, err2 := strconv.ParseFloat(items[1], 64)
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio: %s, should be W:H", aspect)
+	}
+	return w / h, nil
+}
+
+// AspectCropRect 计算从画面中心裁出指定比例的最大区域
+func AspectCropRect(srcW, srcH int, aspect string) (CropRect, error) {
+	ratio, err := parseAspect(aspect)
+	if err != nil {
+		return CropRect{}, err
+	}
+	w, h := srcW, srcH
+	if float64(srcW)/float64(srcH) > ratio {
+		w = int(float64(srcH) * ratio)
+	} else {
+		h = int(float64(srcW) / ratio)
+	}
+	w, h = w/2*2, h/2*2
+	// 偏移也取偶数, 避免色度采样错位
+	return CropRect{X: (srcW - w) / 4 * 2, Y: (srcH - h) / 4 * 2, Width: w, Height: h}, nil
+}
+
+// ParseCropdetectOutput 取出 cropdetect 输出的最后一个有效裁剪区域, 全黑画面会输出负的宽高, 忽略
+func ParseCropdetectOutput(stderr string) (CropRect, bool) {
+	var rect CropRect
+	found := false
+	for _, m := range cropdetectRe.FindAllStringSubmatch(stderr, -1) {
+		w, _ := strconv.Atoi(m[1])
+		h, _ := strconv.Atoi(m[2])
+		x, _ := strconv.Atoi(m[3])
+		y, _ := strconv.Atoi(m[4])
+		if w <= 0 || h <= 0 || x < 0 || y < 0 {
+			continue
+		}
+		rect = CropRect{X: x, Y: y, Width: w, Height: h}
+		found = true
+	}
+	return rect, found
+}
+
+// MergeCropRects 合并多个采样点的检测结果, 取能包含所有结果的最小区域, 避免暗场景把画面裁掉
+func MergeCropRects(rects []CropRect) CropRect {
+	if len(rects) == 0 {
+		return CropRect{}
+	}
+	x1, y1 := rects[0].X, rects[0].Y
+	x2, y2 := rects[0].X+rects[0].Width, rects[0].Y+rects[0].Height
+	for _, r := range rects[1:] {
+		x1, y1 = min(x1, r.X), min(y1, r.Y)
+		x2, y2 = max(x2, r.X+r.Width), max(y2, r.Y+r.Height)
+	}
+	return CropRect{X: x1, Y: y1, Width: (x2 - x1) / 2 * 2, Height: (y2 - y1) / 2 * 2}
+}
+
+// DetectCrop 在文件中均匀取 samples 个点(跳过片头片尾), 每个点用 cropdetect 分析 2 秒画面, 合并结果
+func DetectCrop(inputFile string, duration float64, samples int) (CropRect, error) {
+	if samples <= 0 {
+		samples = 6
+	}
+	var rects []CropRect
+	for i := 0; i < samples; i++ {
+		t := duration * (0.05 + 0.9*float64(i)/float64(max(samples-1, 1)))
+		args := []string{
+			"-ss", fmt.Sprintf("%.3f", t),
+			"-i", inputFile,
+			"-t", "2",
+			"-an", "-sn",
+			"-vf", "cropdetect=limit=24:round=2:reset=0",
+			"-f", "null", "-",
+		}
+		stderr, err := runFFmpeg(args)
+		if err != nil {
+			return CropRect{}, err
+		}
+		if rect, ok := ParseCropdetectOutput(stderr); ok {
+			rects = append(rects, rect)
+		}
+	}
+	if len(rects) == 0 {
+		return CropRect{}, fmt.Errorf("cropdetect found nothing, the video may be completely black")
+	}
+	rect := MergeCropRects(rects)
+	log.Infof("detected crop %+v in %s from %d samples: %+v", rect, inputFile, len(rects), rects)
+	return rect, nil
+}
+
+// RotateFilter 返回顺时针旋转的滤镜
+func RotateFilter(degrees int) (string, error) {
+	switch degrees {
+	case 90:
+		return "transpose=clock", nil
+	case 180:
+		return "hflip,vflip", nil
+	case 270:
+		return "transpose=cclock", nil
+	}
+	return "", fmt.Errorf("invalid rotation: %d, should be 90, 180 or 270", degrees)
+}
+
+// FlipFilter 返回翻转滤镜, direction 为 horizontal, vertical 或 both
+func FlipFilter(direction string) (string, error) {
+	switch direction {
+	case "horizontal":
+		return "hflip", nil
+	case "vertical":
+		return "vflip", nil
+	case "both":
+		return "hflip,vflip", nil
+	}
+	return "", fmt.Errorf("invalid flip direction: %s, should be horizontal, vertical or both", direction)
+}
+
+// TransformVideo 对画面应用滤镜后重新编码, 音频直接拷贝
+func TransformVideo(inputFile string, outputFile string, filter string) error {
+	args := []string{
+		"-i", inputFile,
+		"-vf", filter,
+		"-c:v", "libx264", "-crf", "20", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		"-movflags", "+faststart",
+		"-y", outputFile,
+	}
+	_, err := runFFmpeg(args)
+	return err
+}
+
+// 主版本号, 例如 "6.1.1", "n7.0-3-g08a81b090b", "4.4.2-0ubuntu0.22.04.1"
+var ffmpegMajorMinorRe = regexp.MustCompile(`^n?(\d+)\.(\d+)`)
+
+// SupportsDisplayRotation 判断 ffmpeg 是否支持 -display_rotation(6.1 加入). 解析不出版本号的(例如 git 快照 N-12345-g...)按新版本处理
+func SupportsDisplayRotation(version string) bool {
+	m := ffmpegMajorMinorRe.FindStringSubmatch(version)
+	if m == nil {
+		return true
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return major > 6 || (major == 6 && minor >= 1)
+}
+
+// BuildDisplayRotationArgs 只修改旋转元数据, 不重新编码. rotation 是最终的顺时针角度.
+// 新版本用 -display_rotation(逆时针), 旧版本用 mp4/mov 的 rotate 元数据(顺时针)
+func BuildDisplayRotationArgs(inputFile string, outputFile string, rotation int, ffmpegVersion string) []string {
+	if !SupportsDisplayRotation(ffmpegVersion) {
+		return []string{
+			"-i", inputFile,
+			"-map", "0",
+			"-c", "copy",
+			"-metadata:s:v:0", "rotate=" + strconv.Itoa(rotation),
+			"-y", outputFile,
+		}
+	}
+	return []string{
+		"-display_rotation:v:0", strconv.Itoa((360 - rotation) % 360),
+		"-i", inputFile,
+		"-map", "0",
+		"-c", "copy",
+		"-y", outputFile,
+	}
+}
+
+// SetDisplayRotation 把视频的显示旋转设置为 rotation(顺时针 0/90/180/270), 播放器按元数据旋转, 画面数据不变
+func SetDisplayRotation(inputFile string, outputFile string, rotation int) error {
+	if rotation%90 != 0 || rotation < 0 || rotation >= 360 {
+		return fmt.Errorf("invalid rotation: %d", rotation)
+	}
+	version := GetFFmpegVersion()
+	if version == "" {
+		return fmt.Errorf("can not get ffmpeg version, is ffmpeg installed?")
+	}
+	if !SupportsDisplayRotation(version) {
+		log.Infof("ffmpeg %s has no -display_rotation, fall back to rotate metadata", version)
+	}
+	_, err := runFFmpeg(BuildDisplayRotationArgs(inputFile, outputFile, rotation, version))
+	return err
+}
@@ -0,0 +1,89 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAspectCropRect(t *testing.T) {
+	cases := []struct {
+		w, h   int
+		aspect string
+		want   CropRect
+	}{
+		{1920, 1080, "9:16", CropRect{X: 656, Y: 0, Width: 606, Height: 1080}},
+		{1920, 1080, "1:1", CropRect{X: 420, Y: 0, Width: 1080, Height: 1080}},
+		{1080, 1920, "4:5", CropRect{X: 0, Y: 284, Width: 1080, Height: 1350}},
+		{1920, 1080, "16:9", CropRect{X: 0, Y: 0, Width: 1920, Height: 1080}},
+	}
+	for _, c := range cases {
+		got, err := AspectCropRect(c.w, c.h, c.aspect)
+		if err != nil || got != c.want {
+			t.Errorf("AspectCropRect(%dx%d, %s) got %+v, %v, want %+v", c.w, c.h, c.aspect, got, err, c.want)
+		}
+		if err := got.Validate(c.w, c.h); err != nil {
+			t.Errorf("rect should be valid: %v", err)
+		}
+	}
+	if _, err := AspectCropRect(1920, 1080, "wide"); err == nil {
+		t.Errorf("expected error for invalid aspect")
+	}
+	if err := (CropRect{X: 100, Y: 0, Width: 1900, Height: 1080}).Validate(1920, 1080); err == nil {
+		t.Errorf("expected error for rect outside of frame")
+	}
+}
+
+func TestParseCropdetectOutput(t *testing.T) {
+	stderr := "[Parsed_cropdetect_0 @ 0x1] x1:0 x2:1919 y1:138 y2:941 w:1920 h:800 x:0 y:140 pts:1 t:0.04 crop=1920:800:0:140\n" +
+		"[Parsed_cropdetect_0 @ 0x1] x1:0 x2:1919 y1:136 y2:943 w:1920 h:804 x:0 y:138 pts:2 t:0.08 crop=1920:804:0:138\n" +
+		"[Parsed_cropdetect_0 @ 0x1] x1:1919 x2:0 y1:1079 y2:0 w:-1920 h:-1080 x:1924 y:1084 pts:3 t:0.12 crop=-1920:-1080:1924:1084\n"
+	rect, ok := ParseCropdetectOutput(stderr)
+	if !ok || rect != (CropRect{X: 0, Y: 138, Width: 1920, Height: 804}) {
+		t.Errorf("ParseCropdetectOutput got %+v, %v", rect, ok)
+	}
+	if _, ok := ParseCropdetectOutput("no crop here"); ok {
+		t.Errorf("expected nothing found")
+	}
+}
+
+func TestMergeCropRects(t *testing.T) {
+	// 暗场景检测出的区域偏小, 合并后取并集
+	rects := []CropRect{
+		{X: 0, Y: 140, Width: 1920, Height: 800},
+		{X: 200, Y: 300, Width: 1000, Height: 400},
+		{X: 0, Y: 138, Width: 1920, Height: 803},
+	}
+	if got := MergeCropRects(rects); got != (CropRect{X: 0, Y: 138, Width: 1920, Height: 802}) {
+		t.Errorf("MergeCropRects got %+v", got)
+	}
+}
+
+func TestRotateAndFlipFilters(t *testing.T) {
+	for degrees, want := range map[int]string{90: "transpose=clock", 180: "hflip,vflip", 270: "transpose=cclock"} {
+		if got, err := RotateFilter(degrees); err != nil || got != want {
+			t.Errorf("RotateFilter(%d) got %s, %v", degrees, got, err)
+		}
+	}
+	if _, err := RotateFilter(45); err == nil {
+		t.Errorf("expected error for 45 degrees")
+	}
+	if got, _ := FlipFilter("horizontal"); got != "hflip" {
+		t.Errorf("FlipFilter got %s", got)
+	}
+	if _, err := FlipFilter("diagonal"); err == nil {
+		t.Errorf("expected error for invalid direction")
+	}
+	args := strings.Join(BuildDisplayRotationArgs("in.mp4", "out.mp4", 90, "7.1"), " ")
+	if args != "-display_rotation:v:0 270 -i in.mp4 -map 0 -c copy -y out.mp4" {
+		t.Errorf("display rotation args got %s", args)
+	}
+	args = strings.Join(BuildDisplayRotationArgs("in.mp4", "out.mp4", 90, "4.4.2-0ubuntu0.22.04.1"), " ")
+	if args != "-i in.mp4 -map 0 -c copy -metadata:s:v:0 rotate=90 -y out.mp4" {
+		t.Errorf("rotate metadata args got %s", args)
+	}
+	for version, want := range map[string]bool{"6.1.1": true, "n7.0-3-g08a81b090b": true, "N-112233-gabc": true, "6.0": false, "5.1.4": false} {
+		if got := SupportsDisplayRotation(version); got != want {
+			t.Errorf("SupportsDisplayRotation(%s) = %v, want %v", version, got, want)
+		}
+	}
+}
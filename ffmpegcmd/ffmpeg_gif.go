@@ -0,0 +1,185 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/gollmagent/logging"
+)
+
+// 控制文件大小时最多尝试的次数, 以及帧率和宽度的下限
+const (
+	kGifMaxAttempts = 8
+	kGifMinFps      = 6
+	kGifMinWidth    = 120
+)
+
+var SupportedGifDithers = []string{"sierra2_4a", "floyd_steinberg", "sierra2", "bayer", "heckbert", "none"}
+
+type GifOptions struct {
+	Start    float64 // 开始时间(秒)
+	Duration float64 // 时长(秒), 0 表示到结尾
+	Fps      int     // 默认12
+	Width    int     // 输出宽度, 高度按比例, 默认480
+	Loop     int     // 播放次数, 0 表示无限循环
+	Dither   string  // 调色板抖动算法, 只对 gif 有效, 默认 sierra2_4a
+	Format   string  // gif 或 webp
+	MaxBytes int64   // 目标文件大小上限, 0 表示不限制
+}
+
+func (opts *GifOptions) setDefaults() {
+	if opts.Fps <= 0 {
+		opts.Fps = 12
+	}
+	if opts.Width <= 0 {
+		opts.Width = 480
+	}
+	if opts.Dither == "" {
+		opts.Dither = "sierra2_4a"
+	}
+	if opts.Format == "" {
+		opts.Format = "gif"
+	}
+}
+
+func (opts *GifOptions) inputArgs(inputFile string) []string {
+	var args []string
+	if opts.Start > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", opts.Start))
+	}
+	if opts.Duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", opts.Duration))
+	}
+	return append(args, "-i", inputFile)
+}
+
+func (opts *GifOptions) scaleFilter() string {
+	return fmt.Sprintf("fps=%d,scale=%d:-1:flags=lanczos", opts.Fps, opts.Width)
+}
+
+// gifLoopValue gif 的 -loop: 0 无限循环, -1 只播放一次, n 表示额外重复 n 次
+func gifLoopValue(playCount int) int {
+	if playCount <= 0 {
+		return 0
+	}
+	if playCount == 1 {
+		return -1
+	}
+	return playCount - 1
+}
+
+// buildPaletteUse 返回 paletteuse 滤镜参数, bayer 抖动额外指定 bayer_scale
+func buildPaletteUse(dither string) string {
+	if dither == "bayer" {
+		return "paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle"
+	}
+	return fmt.Sprintf("paletteuse=dither=%s:diff_mode=rectangle", dither)
+}
+
+// VideoToAnimatedImage 把视频片段转换为 gif(两遍: palettegen + paletteuse) 或动态 webp
+func VideoToAnimatedImage(inputFile string, outputFile string, opts *GifOptions) error {
+	opts.setDefaults()
+	switch opts.Format {
+	case "gif":
+		valid := false
+		for _, d := range SupportedGifDithers {
+			if d == opts.Dither {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unsupported dither: %s, supported: %v", opts.Dither, SupportedGifDithers)
+		}
+		return videoToGif(inputFile, outputFile, opts)
+	case "webp":
+		return videoToWebp(inputFile, outputFile, opts)
+	}
+	return fmt.Errorf("unsupported animated image format: %s", opts.Format)
+}
+
+func videoToGif(inputFile string, outputFile string, opts *GifOptions) error {
+	palette, err := os.CreateTemp("", "gollmagent_palette_*.png")
+	if err != nil {
+		return err
+	}
+	palette.Close()
+	defer os.Remove(palette.Name())
+
+	// 第一遍: 统计片段的颜色生成 256 色调色板
+	args := opts.inputArgs(inputFile)
+	args = append(args,
+		"-vf", opts.scaleFilter()+",palettegen=stats_mode=diff",
+		"-update", "1",
+		"-y", palette.Name(),
+	)
+	if _, err := runFFmpeg(args); err != nil {
+		return err
+	}
+
+	// 第二遍: 用调色板和抖动算法生成 gif
+	args = opts.inputArgs(inputFile)
+	args = append(args,
+		"-i", palette.Name(),
+		"-lavfi", fmt.Sprintf("%s[x];[x][1:v]%s", opts.scaleFilter(), buildPaletteUse(opts.Dither)),
+		"-loop", fmt.Sprintf("%d", gifLoopValue(opts.Loop)),
+		"-y", outputFile,
+	)
+	_, err = runFFmpeg(args)
+	return err
+}
+
+func videoToWebp(inputFile string, outputFile string, opts *GifOptions) error {
+	args := opts.inputArgs(inputFile)
+	args = append(args,
+		"-vf", opts.scaleFilter(),
+		"-an",
+		"-c:v", "libwebp",
+		"-lossless", "0",
+		"-q:v", "70",
+		"-compression_level", "6",
+		"-loop", fmt.Sprintf("%d", opts.Loop),
+		"-y", outputFile,
+	)
+	_, err := runFFmpeg(args)
+	return err
+}
+
+// shrinkGifOptions 文件过大时先降低帧率, 帧率到下限后再缩小宽度, 都到下限时返回 false
+func shrinkGifOptions(opts *GifOptions) bool {
+	if opts.Fps > kGifMinFps {
+		opts.Fps = max(kGifMinFps, opts.Fps*3/4)
+		return true
+	}
+	if opts.Width > kGifMinWidth {
+		opts.Width = max(kGifMinWidth, opts.Width*4/5/2*2)
+		return true
+	}
+	return false
+}
+
+// VideoToAnimatedImageWithLimit 生成后检查文件大小, 超过 MaxBytes 就逐步降低帧率和宽度重新生成.
+// 返回最终使用的参数和文件大小
+func VideoToAnimatedImageWithLimit(inputFile string, outputFile string, opts *GifOptions) (*GifOptions, int64, error) {
+	opts.setDefaults()
+	for attempt := 1; ; attempt++ {
+		if err := VideoToAnimatedImage(inputFile, outputFile, opts); err != nil {
+			return opts, 0, err
+		}
+		fi, err := os.Stat(outputFile)
+		if err != nil {
+			return opts, 0, err
+		}
+		size := fi.Size()
+		if opts.MaxBytes <= 0 || size <= opts.MaxBytes {
+			return opts, size, nil
+		}
+		log.Infof("%s is %d bytes, over the limit %d, attempt:%d, fps:%d, width:%d",
+			filepath.Base(outputFile), size, opts.MaxBytes, attempt, opts.Fps, opts.Width)
+		if attempt >= kGifMaxAttempts || !shrinkGifOptions(opts) {
+			return opts, size, fmt.Errorf("cannot fit into %d bytes, smallest result is %d bytes with fps %d and width %d",
+				opts.MaxBytes, size, opts.Fps, opts.Width)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package ffmpegcmd
+
+import "testing"
+
+func TestShrinkGifOptions(t *testing.T) {
+	opts := &GifOptions{Fps: 15, Width: 480}
+	var steps [][2]int
+	for shrinkGifOptions(opts) {
+		steps = append(steps, [2]int{opts.Fps, opts.Width})
+	}
+	if len(steps) == 0 || opts.Fps != kGifMinFps || opts.Width != kGifMinWidth {
+		t.Fatalf("unexpected final options: %+v, steps: %v", opts, steps)
+	}
+	// 先降帧率再缩小宽度
+	if steps[0] != [2]int{11, 480} {
+		t.Errorf("first step should reduce fps, got %v", steps[0])
+	}
+	for _, step := range steps {
+		if step[1]%2 != 0 {
+			t.Errorf("width should stay even: %v", step)
+		}
+	}
+}
+
+func TestGifFilters(t *testing.T) {
+	opts := &GifOptions{}
+	opts.setDefaults()
+	if opts.scaleFilter() != "fps=12,scale=480:-1:flags=lanczos" {
+		t.Errorf("scaleFilter got %s", opts.scaleFilter())
+	}
+	if buildPaletteUse("bayer") != "paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle" {
+		t.Errorf("bayer paletteuse got %s", buildPaletteUse("bayer"))
+	}
+	if gifLoopValue(0) != 0 || gifLoopValue(1) != -1 || gifLoopValue(3) != 2 {
+		t.Errorf("unexpected gif loop mapping")
+	}
+}
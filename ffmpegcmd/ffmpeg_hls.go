@@ -0,0 +1,149 @@
+package ffmpegcmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+const (
+	kHLSMasterPlaylist = "master.m3u8"
+	kHLSKeyFile        = "enc.key"
+	kHLSKeyInfoFile    = "enc.keyinfo"
+)
+
+type HLSOptions struct {
+	Ladder          []Rendition
+	SegmentDuration float64 // 分片时长(秒), 默认6
+	SegmentType     string  // fmp4 或 ts, 默认ts
+	HasAudio        bool
+	Encrypt         bool   // AES-128 加密
+	KeyURL          string // 播放列表里写入的 key 地址, 默认与播放列表同目录的 enc.key
+}
+
+func (opts *HLSOptions) setDefaults() {
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = 6
+	}
+	if opts.SegmentType == "" {
+		opts.SegmentType = "ts"
+	}
+	if opts.KeyURL == "" {
+		opts.KeyURL = kHLSKeyFile
+	}
+}
+
+// varStreamMap 生成 -var_stream_map, 每档视频和一路音频组成一个 variant, 用档位名命名
+func varStreamMap(ladder []Rendition, hasAudio bool) string {
+	var items []string
+	for i, r := range ladder {
+		if hasAudio {
+			items = append(items, fmt.Sprintf("v:%d,a:%d,name:%s", i, i, r.Name))
+		} else {
+			items = append(items, fmt.Sprintf("v:%d,name:%s", i, r.Name))
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+// buildHLSArgs 生成 HLS 打包的完整 ffmpeg 参数, keyInfoFile 为空表示不加密
+func buildHLSArgs(inputFile string, outputDir string, opts *HLSOptions, keyInfoFile string) []string {
+	args := []string{"-i", inputFile}
+	args = append(args, buildLadderVideoArgs(opts.Ladder, opts.SegmentDuration)...)
+	if opts.HasAudio {
+		// HLS 的每个 variant 都带一路音频, 码率随档位变化
+		for i, r := range opts.Ladder {
+			args = append(args,
+				"-map", "0:a:0",
+				fmt.Sprintf("-c:a:%d", i), "aac",
+				fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", r.AudioBitrate),
+			)
+		}
+		args = append(args, "-ac", "2", "-ar", "48000")
+	}
+
+	segExt := "ts"
+	args = append(args, "-f", "hls",
+		"-hls_time", fmt.Sprintf("%g", opts.SegmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+	)
+	if opts.SegmentType == "fmp4" {
+		segExt = "m4s"
+		args = append(args, "-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init.mp4")
+	} else {
+		args = append(args, "-hls_segment_type", "mpegts")
+	}
+	if keyInfoFile != "" {
+		args = append(args, "-hls_key_info_file", keyInfoFile)
+	}
+	args = append(args,
+		"-hls_segment_filename", filepath.Join(outputDir, "%v_%05d."+segExt),
+		"-master_pl_name", kHLSMasterPlaylist,
+		"-var_stream_map", varStreamMap(opts.Ladder, opts.HasAudio),
+		"-y", filepath.Join(outputDir, "%v.m3u8"),
+	)
+	return args
+}
+
+// writeHLSKey 生成随机的 AES-128 key 和 IV, 写入 key 文件和 ffmpeg 需要的 keyinfo 文件
+func writeHLSKey(outputDir string, keyURL string) (string, error) {
+	key := make([]byte, 16)
+	iv := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	keyFile := filepath.Join(outputDir, kHLSKeyFile)
+	if err := os.WriteFile(keyFile, key, 0600); err != nil {
+		return "", err
+	}
+	// keyinfo 格式: key URI, 本地 key 文件路径, IV(十六进制)
+	keyInfo := fmt.Sprintf("%s\n%s\n%s\n", keyURL, keyFile, hex.EncodeToString(iv))
+	keyInfoFile := filepath.Join(outputDir, kHLSKeyInfoFile)
+	if err := os.WriteFile(keyInfoFile, []byte(keyInfo), 0600); err != nil {
+		return "", err
+	}
+	return keyInfoFile, nil
+}
+
+// PackageHLS 一次 ffmpeg 调用把输入转成多档码率的 HLS, 输出 outputDir/master.m3u8, 进度通过 progressObj 上报
+func PackageHLS(id string, inputFile string, outputDir string, opts *HLSOptions, duration float64, progressObj pub.ProgressCallback) error {
+	opts.setDefaults()
+	if len(opts.Ladder) == 0 {
+		return fmt.Errorf("empty rendition ladder")
+	}
+
+	keyInfoFile := ""
+	if opts.Encrypt {
+		var err error
+		keyInfoFile, err = writeHLSKey(outputDir, opts.KeyURL)
+		if err != nil {
+			log.Errorf("error writing hls key: %v", err)
+			progressObj.OnProgress(&pub.ProgressInfo{
+				Progress: 0,
+				Message:  fmt.Sprintf("生成加密 key 失败: %v", err),
+				Done:     true,
+			}, id)
+			return err
+		}
+		// keyinfo 里有本地路径, 打包完就不需要了
+		defer os.Remove(keyInfoFile)
+	}
+
+	args := buildHLSArgs(inputFile, outputDir, opts, keyInfoFile)
+	return runFFmpegWithProgress(id, "HLS打包", args, duration, progressObj)
+}
+
+// HLSMasterPlaylist 返回 PackageHLS 输出的 master playlist 路径
+func HLSMasterPlaylist(outputDir string) string {
+	return filepath.Join(outputDir, kHLSMasterPlaylist)
+}
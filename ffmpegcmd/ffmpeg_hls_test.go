@@ -0,0 +1,118 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildRenditionLadder(t *testing.T) {
+	// 720p 源不会放大到 1080p
+	ladder, err := BuildRenditionLadder(nil, 1280, 720)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range ladder {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, ",") != "720p,480p,360p" {
+		t.Errorf("unexpected ladder: %v", names)
+	}
+	if ladder[0].Width != 1280 || ladder[0].Height != 720 || ladder[0].VideoBitrate != 2800 {
+		t.Errorf("unexpected top rendition: %+v", ladder[0])
+	}
+
+	// 竖屏按短边计算
+	ladder, err = BuildRenditionLadder([]string{"480p"}, 1080, 1920)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ladder) != 1 || ladder[0].Width != 480 || ladder[0].Height != 854 {
+		t.Errorf("unexpected portrait ladder: %+v", ladder)
+	}
+
+	// 源比所有档位都小时输出源分辨率
+	ladder, err = BuildRenditionLadder(nil, 320, 241)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ladder) != 1 || ladder[0].Name != "241p" || ladder[0].Height != 242 {
+		t.Errorf("unexpected small ladder: %+v", ladder)
+	}
+
+	if _, err := BuildRenditionLadder([]string{"4k"}, 1920, 1080); err == nil {
+		t.Errorf("expected error for unsupported resolution")
+	}
+}
+
+func TestBuildHLSArgs(t *testing.T) {
+	opts := &HLSOptions{
+		Ladder: []Rendition{
+			{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+			{Name: "360p", Width: 640, Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+		},
+		SegmentType: "fmp4",
+		HasAudio:    true,
+	}
+	opts.setDefaults()
+	args := strings.Join(buildHLSArgs("in.mp4", "out", opts, "out/enc.keyinfo"), " ")
+	for _, want := range []string{
+		"[0:v]split=2[v0][v1];[v0]scale=1280:720[v0out];[v1]scale=640:360[v1out]",
+		"-b:v:1 800k",
+		"-map 0:a:0 -c:a:1 aac -b:a:1 96k",
+		"-force_key_frames expr:gte(t,n_forced*6)",
+		"-hls_segment_type fmp4",
+		"-hls_key_info_file out/enc.keyinfo",
+		"-hls_segment_filename out/%v_%05d.m4s",
+		"-var_stream_map v:0,a:0,name:720p v:1,a:1,name:360p",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+
+	if got := varStreamMap(opts.Ladder, false); got != "v:0,name:720p v:1,name:360p" {
+		t.Errorf("varStreamMap without audio got %s", got)
+	}
+}
+
+func TestBuildDashArgs(t *testing.T) {
+	opts := &DashOptions{
+		Ladder: []Rendition{
+			{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+			{Name: "360p", Width: 640, Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+		},
+		AudioStreams: 2,
+		UseTimeline:  true,
+	}
+	if err := CheckDashOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(buildDashArgs("in.mp4", "out", opts), " ")
+	for _, want := range []string{
+		"-map 0:a:1 -c:a:1 aac -b:a:1 128k",
+		"-seg_duration 4",
+		"-use_timeline 1",
+		"-init_seg_name init-$RepresentationID$.$ext$",
+		"-adaptation_sets id=0,streams=0,1 id=1,streams=2 id=2,streams=3",
+		"-y out/manifest.mpd",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+
+	// 默认保持源声道数, 5.1/7.1 不会被混缩成立体声
+	if strings.Contains(args, "-ac ") {
+		t.Errorf("dash should keep source channels by default: %s", args)
+	}
+	opts.DownmixStereo = true
+	if args = strings.Join(buildDashArgs("in.mp4", "out", opts), " "); !strings.Contains(args, "-ac 2 -ar 48000") {
+		t.Errorf("expected stereo downmix: %s", args)
+	}
+
+	opts.MediaSegName = "chunk-$Number$.m4s"
+	if err := CheckDashOptions(opts); err == nil {
+		t.Errorf("expected error for template without $RepresentationID$")
+	}
+}
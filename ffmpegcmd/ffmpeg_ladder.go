@@ -0,0 +1,114 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rendition 自适应码率的一档输出, 码率单位 kbps
+type Rendition struct {
+	Name         string `json:"name"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	VideoBitrate int    `json:"video_bitrate_kbps"`
+	AudioBitrate int    `json:"audio_bitrate_kbps"`
+}
+
+// 各分辨率默认的视频/音频码率(kbps), 与 VideoResolutions 的 key 对应
+var renditionBitrates = map[string][2]int{
+	"360p":  {800, 96},
+	"480p":  {1400, 128},
+	"720p":  {2800, 128},
+	"1080p": {5000, 192},
+}
+
+// RenditionFor 返回单个分辨率档位的输出尺寸和默认码率, 不跳过高于源分辨率的档位, 转码也用它决定输出尺寸
+func RenditionFor(res string, inputW int, inputH int) (Rendition, error) {
+	w, h, err := GetVideoResolution(res, inputW, inputH)
+	if err != nil {
+		return Rendition{}, err
+	}
+	bitrates := renditionBitrates[res]
+	return Rendition{Name: res, Width: w, Height: h, VideoBitrate: bitrates[0], AudioBitrate: bitrates[1]}, nil
+}
+
+// BuildRenditionLadder 按 VideoResolutions 生成码率阶梯, 高于源分辨率的档位会被跳过,
+// resolutions 为空时使用全部档位, 结果按分辨率从高到低排序
+func BuildRenditionLadder(resolutions []string, inputW int, inputH int) ([]Rendition, error) {
+	if inputW <= 0 || inputH <= 0 {
+		return nil, fmt.Errorf("invalid input dimensions: %dx%d", inputW, inputH)
+	}
+	if len(resolutions) == 0 {
+		for res := range VideoResolutions {
+			resolutions = append(resolutions, res)
+		}
+	}
+	srcShort := min(inputW, inputH)
+
+	var ladder []Rendition
+	seen := map[string]bool{}
+	for _, res := range resolutions {
+		if seen[res] {
+			continue
+		}
+		seen[res] = true
+		value, ok := VideoResolutions[res]
+		if !ok {
+			return nil, fmt.Errorf("unsupported resolution: %s", res)
+		}
+		if value > srcShort {
+			continue
+		}
+		r, err := RenditionFor(res, inputW, inputH)
+		if err != nil {
+			return nil, err
+		}
+		ladder = append(ladder, r)
+	}
+	if len(ladder) == 0 {
+		// 源分辨率比所有档位都低, 只输出一档源分辨率
+		ladder = append(ladder, Rendition{
+			Name:         fmt.Sprintf("%dp", srcShort),
+			Width:        (inputW + 1) / 2 * 2,
+			Height:       (inputH + 1) / 2 * 2,
+			VideoBitrate: renditionBitrates["360p"][0],
+			AudioBitrate: renditionBitrates["360p"][1],
+		})
+	}
+	sort.Slice(ladder, func(i, j int) bool {
+		return ladder[i].Width*ladder[i].Height > ladder[j].Width*ladder[j].Height
+	})
+	return ladder, nil
+}
+
+// buildLadderVideoArgs 生成一次 ffmpeg 调用输出全部视频档位所需的 filter_complex 和编码参数,
+// 输出流序号 0..len(ladder)-1 依次对应各档位. 关键帧按 segmentDuration 强制对齐, 保证各档位的分片边界一致
+func buildLadderVideoArgs(ladder []Rendition, segmentDuration float64) []string {
+	var filter strings.Builder
+	fmt.Fprintf(&filter, "[0:v]split=%d", len(ladder))
+	for i := range ladder {
+		fmt.Fprintf(&filter, "[v%d]", i)
+	}
+	for i, r := range ladder {
+		fmt.Fprintf(&filter, ";[v%d]scale=%d:%d[v%dout]", i, r.Width, r.Height, i)
+	}
+
+	args := []string{"-filter_complex", filter.String()}
+	for i, r := range ladder {
+		args = append(args,
+			"-map", fmt.Sprintf("[v%dout]", i),
+			fmt.Sprintf("-c:v:%d", i), "libx264",
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate*107/100),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", r.VideoBitrate*3/2),
+		)
+	}
+	args = append(args,
+		"-preset", "veryfast",
+		"-pix_fmt", "yuv420p",
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%g)", segmentDuration),
+	)
+	return args
+}
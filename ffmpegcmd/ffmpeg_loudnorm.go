@@ -0,0 +1,147 @@
+package ffmpegcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+)
+
+type LoudnessTarget struct {
+	I   float64 `json:"integrated_lufs"` // 目标综合响度 LUFS
+	TP  float64 `json:"true_peak_dbtp"`  // 真峰值上限 dBTP
+	LRA float64 `json:"loudness_range"`  // 响度范围 LU
+}
+
+// 各平台和广播标准的响度预设
+var LoudnessPresets = map[string]LoudnessTarget{
+	"streaming": {I: -14, TP: -1, LRA: 11},
+	"youtube":   {I: -14, TP: -1, LRA: 11},
+	"spotify":   {I: -14, TP: -1, LRA: 11},
+	"podcast":   {I: -16, TP: -1.5, LRA: 11},
+	"apple":     {I: -16, TP: -1, LRA: 11},
+	"ebu_r128":  {I: -23, TP: -1, LRA: 7},
+	"atsc":      {I: -24, TP: -2, LRA: 7},
+}
+
+// LoudnessPresetNames 返回排序后的预设名, 用于工具描述
+func LoudnessPresetNames() []string {
+	var names []string
+	for name := range LoudnessPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t LoudnessTarget) Validate() error {
+	if t.I < -70 || t.I > -5 {
+		return fmt.Errorf("integrated loudness %.1f out of range [-70, -5]", t.I)
+	}
+	if t.TP < -9 || t.TP > 0 {
+		return fmt.Errorf("true peak %.1f out of range [-9, 0]", t.TP)
+	}
+	if t.LRA < 1 || t.LRA > 50 {
+		return fmt.Errorf("loudness range %.1f out of range [1, 50]", t.LRA)
+	}
+	return nil
+}
+
+// LoudnormMeasurement loudnorm 第一遍 print_format=json 输出的测量值, ffmpeg 输出的都是字符串
+type LoudnormMeasurement struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// ParseLoudnormOutput 从 stderr 中取出 loudnorm 打印的最后一个 json 块
+func ParseLoudnormOutput(stderr string) (*LoudnormMeasurement, error) {
+	end := strings.LastIndex(stderr, "}")
+	if end < 0 {
+		return nil, fmt.Errorf("loudnorm json not found in ffmpeg output")
+	}
+	start := strings.LastIndex(stderr[:end], "{")
+	if start < 0 {
+		return nil, fmt.Errorf("loudnorm json not found in ffmpeg output")
+	}
+	var m LoudnormMeasurement
+	if err := json.Unmarshal([]byte(stderr[start:end+1]), &m); err != nil {
+		return nil, fmt.Errorf("error parsing loudnorm json: %v", err)
+	}
+	// 静音输入测出来是 -inf, 第二遍无法使用
+	if m.InputI == "" || strings.Contains(m.InputI, "inf") {
+		return nil, fmt.Errorf("invalid loudness measurement: %+v", m)
+	}
+	return &m, nil
+}
+
+func buildLoudnormMeasureFilter(t LoudnessTarget) string {
+	return fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:print_format=json", t.I, t.TP, t.LRA)
+}
+
+func buildLoudnormApplyFilter(t LoudnessTarget, m *LoudnormMeasurement) string {
+	return fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true:print_format=summary",
+		t.I, t.TP, t.LRA, m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset)
+}
+
+// MeasureLoudness loudnorm 第一遍: 只分析第一路音频的响度
+func MeasureLoudness(inputFile string, target LoudnessTarget) (*LoudnormMeasurement, error) {
+	args := []string{
+		"-i", inputFile,
+		"-map", "0:a:0",
+		"-af", buildLoudnormMeasureFilter(target),
+		"-f", "null", "-",
+	}
+	stderr, err := runFFmpeg(args)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLoudnormOutput(stderr)
+}
+
+// libopus 只支持这些采样率
+var opusSampleRates = map[int]bool{48000: true, 24000: true, 16000: true, 12000: true, 8000: true}
+
+// buildLoudnormApplyArgs 第二遍的参数: 只处理第一路音频, 其他音轨丢掉, 视频、字幕和章节直接拷贝
+func buildLoudnormApplyArgs(inputFile string, outputFile string, target LoudnessTarget, m *LoudnormMeasurement, sampleRate int) []string {
+	enc := AudioEncoderForFile(outputFile)
+	if sampleRate <= 0 || (enc == "libopus" && !opusSampleRates[sampleRate]) {
+		sampleRate = 48000
+	}
+	args := []string{
+		"-i", inputFile,
+		"-map", "0",
+		"-map", "-0:a",
+		"-map", "0:a:0",
+		"-c", "copy",
+		"-af", buildLoudnormApplyFilter(target, m),
+		"-ar", fmt.Sprintf("%d", sampleRate),
+		"-c:a", enc,
+	}
+	if enc != "pcm_s16le" && enc != "flac" {
+		args = append(args, "-b:a", "192k")
+	}
+	return append(args, "-y", outputFile)
+}
+
+// NormalizeLoudness 两遍 loudnorm: 先测量再用测量值线性调整到目标响度, 视频、字幕直接拷贝, 只保留第一路音频.
+// sampleRate 为输出采样率(loudnorm 内部会升到 192k), 0 表示 48000, libopus 不支持时也用 48000. 返回第一遍的测量值
+func NormalizeLoudness(inputFile string, outputFile string, target LoudnessTarget, sampleRate int) (*LoudnormMeasurement, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	m, err := MeasureLoudness(inputFile, target)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("loudness measured: %s, %+v", inputFile, m)
+
+	if _, err := runFFmpeg(buildLoudnormApplyArgs(inputFile, outputFile, target, m, sampleRate)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const testLoudnormStderr = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':
+  Duration: 00:00:10.00, start: 0.000000, bitrate: 1200 kb/s
+[Parsed_loudnorm_0 @ 0x7f8c1c004a00]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+size=N/A time=00:00:10.00 bitrate=N/A speed= 400x
+`
+
+func TestParseLoudnormOutput(t *testing.T) {
+	m, err := ParseLoudnormOutput(testLoudnormStderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.InputI != "-27.61" || m.InputThresh != "-39.20" || m.TargetOffset != "0.58" {
+		t.Errorf("unexpected measurement: %+v", m)
+	}
+	want := "loudnorm=I=-16:TP=-1.5:LRA=11:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.20:offset=0.58:linear=true:print_format=summary"
+	if got := buildLoudnormApplyFilter(LoudnessPresets["podcast"], m); got != want {
+		t.Errorf("apply filter got %s", got)
+	}
+
+	if _, err := ParseLoudnormOutput(`{"input_i" : "-inf", "input_tp" : "-inf"}`); err == nil {
+		t.Errorf("expected error for silent input")
+	}
+	if _, err := ParseLoudnormOutput("no json here"); err == nil {
+		t.Errorf("expected error without json")
+	}
+}
+
+func TestLoudnessTargetValidate(t *testing.T) {
+	for name, preset := range LoudnessPresets {
+		if err := preset.Validate(); err != nil {
+			t.Errorf("preset %s invalid: %v", name, err)
+		}
+	}
+	if err := (LoudnessTarget{I: -3, TP: -1, LRA: 11}).Validate(); err == nil {
+		t.Errorf("expected error for too loud target")
+	}
+}
+
+func TestBuildLoudnormApplyArgs(t *testing.T) {
+	m := &LoudnormMeasurement{InputI: "-20", InputTP: "-3", InputLRA: "5", InputThresh: "-30", TargetOffset: "0.5"}
+	target := LoudnessTarget{I: -14, TP: -1, LRA: 11}
+
+	// 44.1k 的 ogg 用 libopus 编码, 采样率要改成 48k
+	args := strings.Join(buildLoudnormApplyArgs("a.ogg", "b.ogg", target, m, 44100), " ")
+	if !strings.Contains(args, "-ar 48000 -c:a libopus") {
+		t.Errorf("libopus should use 48000: %s", args)
+	}
+	if !strings.HasPrefix(args, "-i a.ogg -map 0 -map -0:a -map 0:a:0 -c copy ") {
+		t.Errorf("other streams should be copied: %s", args)
+	}
+
+	args = strings.Join(buildLoudnormApplyArgs("a.mp4", "b.mp4", target, m, 44100), " ")
+	if !strings.Contains(args, "-ar 44100 -c:a aac -b:a 192k") {
+		t.Errorf("aac should keep the source sample rate: %s", args)
+	}
+}
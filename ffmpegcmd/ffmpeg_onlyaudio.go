@@ -42,3 +42,19 @@ func GetM4aFromMediaFile(inputFile string) (string, error) {
 	log.Infof("ffmpeg command succeeded, output file: %s", outputFile)
 	return outputFile, nil
 }
+
+// ExtractAudioForASR 提取 16k 单声道音频给语音识别用, 体积小且满足识别引擎的采样率要求
+func ExtractAudioForASR(inputFile string, outputFile string) error {
+	args := []string{
+		"-i", inputFile,
+		"-vn", "-sn", "-dn",
+		"-map", "0:a:0",
+		"-ac", "1",
+		"-ar", "16000",
+		"-c:a", "aac",
+		"-b:a", "32k",
+		"-y", outputFile,
+	}
+	_, err := runFFmpeg(args)
+	return err
+}
@@ -0,0 +1,290 @@
+package ffmpegcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PSNR 完全相同时是 inf, 统计时按这个值截断
+const kMaxPSNR = 100.0
+
+const (
+	QualityMethodAuto     = "auto"
+	QualityMethodVMAF     = "vmaf"
+	QualityMethodPSNRSSIM = "psnr_ssim"
+)
+
+type QualityOptions struct {
+	Method          string  // auto, vmaf, psnr_ssim
+	RefWidth        int     // 参考视频的显示宽高, 两路视频都会缩放到这个尺寸
+	RefHeight       int     //
+	RefFps          float64 // 参考视频帧率, 用于对齐帧和计算时间点
+	SegmentDuration float64 // 分段统计的时长(秒), 默认10
+	WorstCount      int     // 返回最差时刻的个数, 默认5
+}
+
+type QualitySample struct {
+	Time  float64 `json:"time"`
+	Score float64 `json:"score"`
+}
+
+type QualitySegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Mean  float64 `json:"mean"`
+	Min   float64 `json:"min"`
+}
+
+type MetricSummary struct {
+	Mean     float64          `json:"mean"`
+	Min      float64          `json:"min"`
+	Max      float64          `json:"max"`
+	Segments []QualitySegment `json:"segments"`
+	Worst    []QualitySample  `json:"worst"`
+}
+
+type QualityReport struct {
+	Method  string                    `json:"method"`
+	Frames  int                       `json:"frames"`
+	Metrics map[string]*MetricSummary `json:"metrics"`
+}
+
+func (opts *QualityOptions) setDefaults() {
+	if opts.Method == "" {
+		opts.Method = QualityMethodAuto
+	}
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = 10
+	}
+	if opts.WorstCount <= 0 {
+		opts.WorstCount = 5
+	}
+	if opts.RefFps <= 0 {
+		opts.RefFps = 25
+	}
+}
+
+// SelectQualityMethod 根据本地 ffmpeg 的滤镜决定使用 vmaf 还是 psnr/ssim
+func SelectQualityMethod(method string, filters map[string]bool) (string, error) {
+	switch method {
+	case QualityMethodAuto, "":
+		if filters["libvmaf"] {
+			return QualityMethodVMAF, nil
+		}
+		if filters["psnr"] && filters["ssim"] {
+			return QualityMethodPSNRSSIM, nil
+		}
+		return "", fmt.Errorf("local ffmpeg has neither libvmaf nor psnr/ssim filters")
+	case QualityMethodVMAF:
+		if !filters["libvmaf"] {
+			return "", fmt.Errorf("local ffmpeg is not built with libvmaf")
+		}
+		return method, nil
+	case QualityMethodPSNRSSIM:
+		if !filters["psnr"] || !filters["ssim"] {
+			return "", fmt.Errorf("local ffmpeg has no psnr/ssim filters")
+		}
+		return method, nil
+	}
+	return "", fmt.Errorf("unsupported quality method: %s", method)
+}
+
+// alignFilters 两路都缩放到参考视频的显示尺寸并设置 sar=1, 对齐帧率, 从 0 开始计时.
+// 参考视频是非方形像素(例如 1440x1080, sar 4:3)时也要缩放, 否则两路尺寸不一致, libvmaf/psnr/ssim 会失败
+func alignFilters(opts *QualityOptions) string {
+	align := fmt.Sprintf("scale=%d:%d:flags=bicubic,setsar=1,fps=%g,setpts=PTS-STARTPTS", opts.RefWidth, opts.RefHeight, opts.RefFps)
+	return fmt.Sprintf("[0:v]%s[dist];[1:v]%s[ref]", align, align)
+}
+
+func buildVMAFFilter(opts *QualityOptions, logPath string) string {
+	return alignFilters(opts) + fmt.Sprintf(";[dist][ref]libvmaf=log_fmt=json:log_path=%s:n_threads=4", EscapeFilterValue(logPath))
+}
+
+func buildPSNRSSIMFilter(opts *QualityOptions, psnrPath string, ssimPath string) string {
+	return alignFilters(opts) + fmt.Sprintf(";[dist]split[d1][d2];[ref]split[r1][r2];[d1][r1]psnr=stats_file=%s;[d2][r2]ssim=stats_file=%s",
+		EscapeFilterValue(psnrPath), EscapeFilterValue(ssimPath))
+}
+
+// ParseVMAFLog 解析 libvmaf 的 json 日志, 返回每帧的 vmaf 分数(按帧序)
+func ParseVMAFLog(data []byte) ([]float64, error) {
+	var vmafLog struct {
+		Frames []struct {
+			FrameNum int                `json:"frameNum"`
+			Metrics  map[string]float64 `json:"metrics"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &vmafLog); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(vmafLog.Frames))
+	for i, frame := range vmafLog.Frames {
+		scores[i] = frame.Metrics["vmaf"]
+	}
+	return scores, nil
+}
+
+// ParseStatsFile 解析 psnr/ssim 滤镜的 stats_file, 每行形如 "n:1 ... key:value ...", 返回每帧 key 对应的值
+func ParseStatsFile(data string, key string) []float64 {
+	var values []float64
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			k, v, ok := strings.Cut(field, ":")
+			if !ok || k != key {
+				continue
+			}
+			if v == "inf" {
+				values = append(values, kMaxPSNR)
+				break
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err == nil {
+				values = append(values, math.Min(f, kMaxPSNR))
+			}
+			break
+		}
+	}
+	return values
+}
+
+// SummarizeMetric 汇总每帧分数: 整体均值/最值, 按 segmentDuration 分段, 以及相隔至少 1 秒的最差时刻
+func SummarizeMetric(scores []float64, fps float64, segmentDuration float64, worstCount int) *MetricSummary {
+	summary := &MetricSummary{}
+	if len(scores) == 0 {
+		return summary
+	}
+	summary.Min, summary.Max = math.Inf(1), math.Inf(-1)
+	var seg *QualitySegment
+	var segSum float64
+	var segCount int
+	flush := func() {
+		if seg != nil {
+			seg.Mean = segSum / float64(segCount)
+			summary.Segments = append(summary.Segments, *seg)
+		}
+	}
+	var sum float64
+	for i, score := range scores {
+		t := float64(i) / fps
+		sum += score
+		summary.Min = math.Min(summary.Min, score)
+		summary.Max = math.Max(summary.Max, score)
+
+		segIndex := int(t / segmentDuration)
+		if seg == nil || t >= seg.End {
+			flush()
+			seg = &QualitySegment{Start: float64(segIndex) * segmentDuration, End: float64(segIndex+1) * segmentDuration, Min: score}
+			segSum, segCount = 0, 0
+		}
+		segSum += score
+		segCount++
+		seg.Min = math.Min(seg.Min, score)
+	}
+	// 最后一段的结束时间用实际时长
+	seg.End = float64(len(scores)) / fps
+	flush()
+	summary.Mean = sum / float64(len(scores))
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+	for _, idx := range order {
+		if len(summary.Worst) >= worstCount {
+			break
+		}
+		t := float64(idx) / fps
+		tooClose := false
+		for _, w := range summary.Worst {
+			if math.Abs(w.Time-t) < 1 {
+				tooClose = true
+				break
+			}
+		}
+		if !tooClose {
+			summary.Worst = append(summary.Worst, QualitySample{Time: math.Round(t*1000) / 1000, Score: scores[idx]})
+		}
+	}
+	return summary
+}
+
+// CompareQuality 以 reference 为参考计算 distorted 的客观质量, 优先使用 libvmaf, 没有时使用 psnr/ssim
+func CompareQuality(distorted string, reference string, opts *QualityOptions) (*QualityReport, error) {
+	opts.setDefaults()
+	if opts.RefWidth <= 0 || opts.RefHeight <= 0 {
+		return nil, fmt.Errorf("invalid reference dimensions: %dx%d", opts.RefWidth, opts.RefHeight)
+	}
+	filters, err := GetFilters()
+	if err != nil {
+		return nil, fmt.Errorf("error getting ffmpeg filters: %v", err)
+	}
+	method, err := SelectQualityMethod(opts.Method, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Join(os.TempDir(), fmt.Sprintf("gollmagent_quality_%d", time.Now().UnixNano()))
+	report := &QualityReport{Method: method, Metrics: map[string]*MetricSummary{}}
+	metrics := map[string][]float64{}
+	switch method {
+	case QualityMethodVMAF:
+		logPath := prefix + "_vmaf.json"
+		defer os.Remove(logPath)
+		if err := runQualityFilter(distorted, reference, buildVMAFFilter(opts, logPath)); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(logPath)
+		if err != nil {
+			return nil, err
+		}
+		if metrics["vmaf"], err = ParseVMAFLog(data); err != nil {
+			return nil, fmt.Errorf("error parsing vmaf log: %v", err)
+		}
+	case QualityMethodPSNRSSIM:
+		psnrPath, ssimPath := prefix+"_psnr.log", prefix+"_ssim.log"
+		defer os.Remove(psnrPath)
+		defer os.Remove(ssimPath)
+		if err := runQualityFilter(distorted, reference, buildPSNRSSIMFilter(opts, psnrPath, ssimPath)); err != nil {
+			return nil, err
+		}
+		psnrData, err := os.ReadFile(psnrPath)
+		if err != nil {
+			return nil, err
+		}
+		ssimData, err := os.ReadFile(ssimPath)
+		if err != nil {
+			return nil, err
+		}
+		metrics["psnr"] = ParseStatsFile(string(psnrData), "psnr_avg")
+		metrics["ssim"] = ParseStatsFile(string(ssimData), "All")
+	}
+
+	for name, scores := range metrics {
+		report.Frames = max(report.Frames, len(scores))
+		report.Metrics[name] = SummarizeMetric(scores, opts.RefFps, opts.SegmentDuration, opts.WorstCount)
+	}
+	if report.Frames == 0 {
+		return nil, fmt.Errorf("no frames compared, check that both inputs contain video")
+	}
+	return report, nil
+}
+
+func runQualityFilter(distorted string, reference string, filter string) error {
+	args := []string{
+		"-i", distorted,
+		"-i", reference,
+		"-lavfi", filter,
+		"-an", "-f", "null", "-",
+	}
+	_, err := runFFmpeg(args)
+	return err
+}
@@ -0,0 +1,97 @@
+package ffmpegcmd
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+)
+
+const testFiltersOutput = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  V = Video input/output
+  N = Dynamic number and/or type of input/output
+  | = Source or sink filter
+ ... abench            A->A       Benchmark part of a filtergraph.
+ TS. psnr              VV->V      Calculate the PSNR between two video streams.
+ TS. ssim              VV->V      Calculate the SSIM between two video streams.
+`
+
+func TestParseFilterListAndSelectMethod(t *testing.T) {
+	filters := ParseFilterList(testFiltersOutput)
+	if len(filters) != 3 || !filters["psnr"] || !filters["abench"] {
+		t.Fatalf("unexpected filters: %v", filters)
+	}
+	if method, err := SelectQualityMethod("auto", filters); err != nil || method != QualityMethodPSNRSSIM {
+		t.Errorf("auto without libvmaf got %s, %v", method, err)
+	}
+	if _, err := SelectQualityMethod("vmaf", filters); err == nil {
+		t.Errorf("expected error for vmaf without libvmaf")
+	}
+	filters["libvmaf"] = true
+	if method, _ := SelectQualityMethod("auto", filters); method != QualityMethodVMAF {
+		t.Errorf("auto with libvmaf got %s", method)
+	}
+}
+
+func TestEscapeFilterValue(t *testing.T) {
+	cases := map[string]string{
+		"/tmp/vmaf.json":   "/tmp/vmaf.json",
+		`C:\logs\a.json`:   `C\\:\\\\logs\\\\a.json`,
+		"/tmp/it's[1].log": `/tmp/it\\\'s\[1\].log`,
+	}
+	for in, want := range cases {
+		if got := EscapeFilterValue(in); got != want {
+			t.Errorf("EscapeFilterValue(%q) got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseQualityLogs(t *testing.T) {
+	vmaf, err := ParseVMAFLog([]byte(`{"frames":[{"frameNum":0,"metrics":{"vmaf":95.5}},{"frameNum":1,"metrics":{"vmaf":80.25}}],"pooled_metrics":{}}`))
+	if err != nil || len(vmaf) != 2 || vmaf[1] != 80.25 {
+		t.Errorf("ParseVMAFLog got %v, %v", vmaf, err)
+	}
+
+	psnr := ParseStatsFile("n:1 mse_avg:0.00 mse_y:0.00 psnr_avg:inf psnr_y:inf\nn:2 mse_avg:3.51 psnr_avg:42.68 psnr_y:41.20\n", "psnr_avg")
+	if len(psnr) != 2 || psnr[0] != kMaxPSNR || psnr[1] != 42.68 {
+		t.Errorf("psnr stats got %v", psnr)
+	}
+	ssim := ParseStatsFile("n:1 Y:0.990 U:0.995 V:0.996 All:0.992 (21.0)\n", "All")
+	if len(ssim) != 1 || ssim[0] != 0.992 {
+		t.Errorf("ssim stats got %v", ssim)
+	}
+}
+
+func TestSummarizeMetric(t *testing.T) {
+	// 2fps, 共 10 帧(5 秒), 分段 2 秒
+	scores := []float64{90, 90, 50, 90, 90, 90, 40, 45, 90, 80}
+	s := SummarizeMetric(scores, 2, 2, 2)
+	if s.Min != 40 || s.Max != 90 || math.Abs(s.Mean-75.5) > 1e-9 {
+		t.Errorf("unexpected aggregate: %+v", s)
+	}
+	if len(s.Segments) != 3 || s.Segments[2].End != 5 || s.Segments[1].Min != 40 {
+		t.Errorf("unexpected segments: %+v", s.Segments)
+	}
+	// 40(3.0s) 和 45(3.5s) 太近, 第二差的时刻是 50(1.0s)
+	if len(s.Worst) != 2 || s.Worst[0].Time != 3 || s.Worst[1].Time != 1 {
+		t.Errorf("unexpected worst moments: %+v", s.Worst)
+	}
+}
+
+func TestAlignFiltersAnamorphicReference(t *testing.T) {
+	// 1440x1080, sar 4:3 的参考视频显示为 1920x1080, 两路都要缩放到这个尺寸
+	ref := &ffprobe.Stream{CodecType: "video", Width: 1440, Height: 1080, SampleAspectRatio: "4:3"}
+	w, h := ref.DisplaySize()
+	opts := &QualityOptions{RefWidth: w, RefHeight: h, RefFps: 25}
+	filter := buildVMAFFilter(opts, "/tmp/vmaf.json")
+	for _, label := range []string{"[0:v]", "[1:v]"} {
+		if !strings.Contains(filter, label+"scale=1920:1080:flags=bicubic,setsar=1,fps=25,") {
+			t.Errorf("%s should be scaled to 1920x1080 with sar 1: %s", label, filter)
+		}
+	}
+}
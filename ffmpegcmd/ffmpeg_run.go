@@ -0,0 +1,48 @@
+package ffmpegcmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+)
+
+// ffmpeg 出错时只保留 stderr 最后几行, 够定位问题又不会刷屏
+const kStderrTailLines = 8
+
+func cmdDebugString(name string, args []string) string {
+	cmdDbg := name
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " ;[]'\"") {
+			cmdDbg += fmt.Sprintf(" %q", arg)
+		} else {
+			cmdDbg += " " + arg
+		}
+	}
+	return cmdDbg
+}
+
+func stderrTail(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if len(lines) > kStderrTailLines {
+		lines = lines[len(lines)-kStderrTailLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runFFmpeg 执行 ffmpeg 并返回 stderr 的全部内容, 分析类滤镜(silencedetect, cropdetect 等)的结果就在 stderr 里
+func runFFmpeg(args []string) (string, error) {
+	args = append([]string{"-hide_banner", "-nostdin"}, args...)
+	log.Infof("Executing command: %s", cmdDebugString("ffmpeg", args))
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.Errorf("ffmpeg execution failed: %v, stderr: %s", err, stderrTail(stderr.String()))
+		return stderr.String(), fmt.Errorf("ffmpeg execution failed: %v, %s", err, stderrTail(stderr.String()))
+	}
+	return stderr.String(), nil
+}
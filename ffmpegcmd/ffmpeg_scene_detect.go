@@ -0,0 +1,134 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+)
+
+var (
+	ptsTimeRe    = regexp.MustCompile(`pts_time:([\d.]+)`)
+	sceneScoreRe = regexp.MustCompile(`lavfi\.scene_score=([\d.]+)`)
+	scdetRe      = regexp.MustCompile(`lavfi\.scd\.score:\s*([\d.]+),\s*lavfi\.scd\.time:\s*([\d.]+)`)
+)
+
+var SupportedSceneMethods = []string{"select", "scdet"}
+
+type SceneChange struct {
+	Time      float64 `json:"time"`  // 秒
+	Score     float64 `json:"score"` // 0-1, 越大画面变化越大
+	Thumbnail string  `json:"thumbnail,omitempty"`
+}
+
+type SceneDetectOptions struct {
+	Threshold      float64 // 0-1, 默认 0.3
+	Method         string  // select: select=gt(scene,x); scdet: scdet 滤镜
+	MinSceneLength float64 // 两次场景切换的最小间隔(秒), 过滤闪光等造成的连续误判
+}
+
+// DetectScenes 用 ffmpeg 的场景分数检测画面内容的变化, 不同于 I 帧, 这里反映的是内容切换
+func DetectScenes(inputFile string, opts *SceneDetectOptions) ([]SceneChange, error) {
+	threshold := opts.Threshold
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.3
+	}
+
+	// 先缩小画面再计算场景分数, 结果基本一致但快很多
+	var filter string
+	switch opts.Method {
+	case "", "select":
+		filter = fmt.Sprintf("scale=320:-2,select='gt(scene,%.3f)',metadata=print", threshold)
+	case "scdet":
+		filter = fmt.Sprintf("scale=320:-2,scdet=threshold=%.1f", threshold*100)
+	default:
+		return nil, fmt.Errorf("unsupported scene detect method: %s", opts.Method)
+	}
+	args := []string{
+		"-i", inputFile,
+		"-an", "-sn",
+		"-vf", filter,
+		"-f", "null", "-",
+	}
+	stderr, err := runFFmpeg(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenes []SceneChange
+	if opts.Method == "scdet" {
+		scenes = ParseScdetOutput(stderr)
+	} else {
+		scenes = ParseSceneSelectOutput(stderr)
+	}
+	scenes = filterShortScenes(scenes, opts.MinSceneLength)
+	log.Infof("detected %d scene changes in %s, threshold:%.3f", len(scenes), inputFile, threshold)
+	return scenes, nil
+}
+
+// ParseSceneSelectOutput 解析 metadata=print 的输出, 每个被选中的帧先打印 pts_time, 然后是 lavfi.scene_score
+func ParseSceneSelectOutput(stderr string) []SceneChange {
+	var scenes []SceneChange
+	ptsTime := -1.0
+	for _, line := range strings.Split(stderr, "\n") {
+		if m := ptsTimeRe.FindStringSubmatch(line); len(m) > 1 {
+			ptsTime, _ = strconv.ParseFloat(m[1], 64)
+			continue
+		}
+		if m := sceneScoreRe.FindStringSubmatch(line); len(m) > 1 && ptsTime >= 0 {
+			score, _ := strconv.ParseFloat(m[1], 64)
+			scenes = append(scenes, SceneChange{Time: ptsTime, Score: score})
+			ptsTime = -1
+		}
+	}
+	return scenes
+}
+
+// ParseScdetOutput 解析 scdet 的输出, 例如 "lavfi.scd.score: 24.118, lavfi.scd.time: 8.3", 分数转换为 0-1
+func ParseScdetOutput(stderr string) []SceneChange {
+	var scenes []SceneChange
+	for _, m := range scdetRe.FindAllStringSubmatch(stderr, -1) {
+		score, _ := strconv.ParseFloat(m[1], 64)
+		t, _ := strconv.ParseFloat(m[2], 64)
+		scenes = append(scenes, SceneChange{Time: t, Score: score / 100})
+	}
+	return scenes
+}
+
+func filterShortScenes(scenes []SceneChange, minLength float64) []SceneChange {
+	if minLength <= 0 {
+		return scenes
+	}
+	var ret []SceneChange
+	last := 0.0
+	for _, scene := range scenes {
+		if scene.Time-last < minLength {
+			continue
+		}
+		ret = append(ret, scene)
+		last = scene.Time
+	}
+	return ret
+}
+
+// ChaptersFromScenes 以每个场景切换点作为章节的开始, 生成覆盖整个视频的章节列表
+func ChaptersFromScenes(scenes []SceneChange, duration float64) []ChapterMark {
+	var chapters []ChapterMark
+	start := 0.0
+	for _, scene := range scenes {
+		if scene.Time <= start || scene.Time >= duration {
+			continue
+		}
+		chapters = append(chapters, ChapterMark{Start: start, End: scene.Time})
+		start = scene.Time
+	}
+	if duration > start {
+		chapters = append(chapters, ChapterMark{Start: start, End: duration})
+	}
+	for i := range chapters {
+		chapters[i].Title = fmt.Sprintf("Scene %d", i+1)
+	}
+	return chapters
+}
@@ -0,0 +1,46 @@
+package ffmpegcmd
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSceneOutput(t *testing.T) {
+	selectOutput := `[Parsed_metadata_2 @ 0x5581] frame:0    pts:180     pts_time:7.2
+[Parsed_metadata_2 @ 0x5581] lavfi.scene_score=0.563201
+[Parsed_metadata_2 @ 0x5581] frame:1    pts:375     pts_time:15.015
+[Parsed_metadata_2 @ 0x5581] lavfi.scene_score=0.412000
+frame=  450 fps=0.0 q=-0.0 Lsize=N/A time=00:00:18.00 bitrate=N/A speed=  60x`
+	scenes := ParseSceneSelectOutput(selectOutput)
+	want := []SceneChange{{Time: 7.2, Score: 0.563201}, {Time: 15.015, Score: 0.412}}
+	if !reflect.DeepEqual(scenes, want) {
+		t.Errorf("ParseSceneSelectOutput got %+v, want %+v", scenes, want)
+	}
+
+	scdetOutput := `[scdet @ 0x55] lavfi.scd.score: 24.118, lavfi.scd.time: 8.3
+[scdet @ 0x55] lavfi.scd.score: 51.000, lavfi.scd.time: 8.8`
+	scenes = filterShortScenes(ParseScdetOutput(scdetOutput), 1)
+	if len(scenes) != 1 || scenes[0].Time != 8.3 || math.Abs(scenes[0].Score-0.24118) > 1e-9 {
+		t.Errorf("ParseScdetOutput got %+v", scenes)
+	}
+}
+
+func TestChaptersFromScenes(t *testing.T) {
+	chapters := ChaptersFromScenes([]SceneChange{{Time: 7.2}, {Time: 15}}, 20)
+	want := []ChapterMark{
+		{Start: 0, End: 7.2, Title: "Scene 1"},
+		{Start: 7.2, End: 15, Title: "Scene 2"},
+		{Start: 15, End: 20, Title: "Scene 3"},
+	}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Errorf("ChaptersFromScenes got %+v, want %+v", chapters, want)
+	}
+
+	meta := BuildFFMetadata([]ChapterMark{{Start: 0, End: 7.2, Title: "a=b;c"}})
+	if !strings.HasPrefix(meta, ";FFMETADATA1\n") ||
+		!strings.Contains(meta, "START=0\nEND=7200\ntitle=a\\=b\\;c\n") {
+		t.Errorf("BuildFFMetadata got %q", meta)
+	}
+}
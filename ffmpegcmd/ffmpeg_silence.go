@@ -0,0 +1,97 @@
+package ffmpegcmd
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	silenceStartRe = regexp.MustCompile(`silence_start:\s*(-?[\d.]+)`)
+	silenceEndRe   = regexp.MustCompile(`silence_end:\s*(-?[\d.]+)`)
+)
+
+type SilenceOptions struct {
+	NoiseDB    float64 // 低于这个音量(dB)算静音, 默认 -30
+	MinSilence float64 // 静音持续超过这个时长(秒)才算, 默认 0.5
+}
+
+func (opts *SilenceOptions) setDefaults() {
+	if opts.NoiseDB == 0 {
+		opts.NoiseDB = -30
+	}
+	if opts.MinSilence <= 0 {
+		opts.MinSilence = 0.5
+	}
+}
+
+// ParseSilenceOutput 解析 silencedetect 打印的 silence_start/silence_end, 文件末尾没有结束的静音截止到 duration
+func ParseSilenceOutput(stderr string, duration float64) []TimeRange {
+	var ranges []TimeRange
+	start := -1.0
+	scanner := bufio.NewScanner(strings.NewReader(stderr))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := silenceStartRe.FindStringSubmatch(line); m != nil {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+				start = max(v, 0)
+			}
+			continue
+		}
+		if m := silenceEndRe.FindStringSubmatch(line); m != nil && start >= 0 {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil && v > start {
+				ranges = append(ranges, TimeRange{Start: start, End: v})
+			}
+			start = -1
+		}
+	}
+	if start >= 0 && duration > start {
+		ranges = append(ranges, TimeRange{Start: start, End: duration})
+	}
+	return ranges
+}
+
+// DetectSilence 用 silencedetect 分析第一路音频, 返回静音时间段
+func DetectSilence(inputFile string, opts *SilenceOptions, duration float64) ([]TimeRange, error) {
+	opts.setDefaults()
+	args := []string{
+		"-i", inputFile,
+		"-map", "0:a:0",
+		"-af", fmt.Sprintf("silencedetect=noise=%gdB:d=%g", opts.NoiseDB, opts.MinSilence),
+		"-f", "null", "-",
+	}
+	stderr, err := runFFmpeg(args)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSilenceOutput(stderr, duration), nil
+}
+
+// BuildSilenceKeepRanges 根据静音时间段生成需要保留的时间段. 只删除长度不小于 minGap 的静音,
+// 静音两端各保留 padding 秒, 避免把语音的开头和结尾切掉. 返回保留的时间段和被删除的总时长
+func BuildSilenceKeepRanges(silences []TimeRange, duration float64, padding float64, minGap float64) ([]TimeRange, float64) {
+	var removed []TimeRange
+	for _, s := range silences {
+		if s.Duration() < minGap {
+			continue
+		}
+		// 文件开头和结尾的静音不需要留边
+		start, end := s.Start+padding, s.End-padding
+		if s.Start <= 0 {
+			start = 0
+		}
+		if duration > 0 && s.End >= duration {
+			end = duration
+		}
+		if end-start >= kMinRangeDuration {
+			removed = append(removed, TimeRange{Start: start, End: end})
+		}
+	}
+	if len(removed) == 0 {
+		return []TimeRange{{Start: 0, End: duration}}, 0
+	}
+	keep := InvertRanges(removed, duration)
+	return keep, duration - TotalDuration(keep)
+}
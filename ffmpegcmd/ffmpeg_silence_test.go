@@ -0,0 +1,52 @@
+package ffmpegcmd
+
+import (
+	"math"
+	"testing"
+)
+
+const testSilenceStderr = `[silencedetect @ 0x600003a7c000] silence_start: 0
+[silencedetect @ 0x600003a7c000] silence_end: 1.2 | silence_duration: 1.2
+size=N/A time=00:00:05.00 bitrate=N/A speed= 900x
+[silencedetect @ 0x600003a7c000] silence_start: 4.5
+[silencedetect @ 0x600003a7c000] silence_end: 4.8 | silence_duration: 0.3
+[silencedetect @ 0x600003a7c000] silence_start: 6.25
+[silencedetect @ 0x600003a7c000] silence_end: 9.75 | silence_duration: 3.5
+[silencedetect @ 0x600003a7c000] silence_start: 18.4
+`
+
+func TestParseSilenceOutput(t *testing.T) {
+	silences := ParseSilenceOutput(testSilenceStderr, 20)
+	want := []TimeRange{{0, 1.2}, {4.5, 4.8}, {6.25, 9.75}, {18.4, 20}}
+	if len(silences) != len(want) {
+		t.Fatalf("got %v, want %v", silences, want)
+	}
+	for i := range want {
+		if silences[i] != want[i] {
+			t.Errorf("silence %d got %v, want %v", i, silences[i], want[i])
+		}
+	}
+}
+
+func TestBuildSilenceKeepRanges(t *testing.T) {
+	silences := ParseSilenceOutput(testSilenceStderr, 20)
+	// 0.3 秒的静音小于 minGap 保留; 中间的静音两端各留 0.25 秒
+	keep, removed := BuildSilenceKeepRanges(silences, 20, 0.25, 0.5)
+	want := []TimeRange{{0.95, 6.5}, {9.5, 18.65}}
+	if len(keep) != len(want) {
+		t.Fatalf("got %v, want %v", keep, want)
+	}
+	for i := range want {
+		if math.Abs(keep[i].Start-want[i].Start) > 1e-9 || math.Abs(keep[i].End-want[i].End) > 1e-9 {
+			t.Errorf("keep %d got %v, want %v", i, keep[i], want[i])
+		}
+	}
+	if math.Abs(removed-5.3) > 1e-9 {
+		t.Errorf("removed got %.3f, want 5.3", removed)
+	}
+
+	keep, removed = BuildSilenceKeepRanges(nil, 20, 0.25, 0.5)
+	if len(keep) != 1 || keep[0].End != 20 || removed != 0 {
+		t.Errorf("no silence should keep everything: %v, %.3f", keep, removed)
+	}
+}
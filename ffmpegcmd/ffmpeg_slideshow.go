@@ -0,0 +1,207 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gollmagent/pub"
+)
+
+// 一次最多处理的图片数, 每张图片都是一路输入
+const kMaxSlideshowImages = 200
+
+var ImageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".bmp": true,
+}
+
+// 常用的 xfade 转场, none 表示直接切换
+var SupportedTransitions = []string{
+	"none", "fade", "fadeblack", "fadewhite", "dissolve", "wipeleft", "wiperight", "wipeup", "wipedown",
+	"slideleft", "slideright", "slideup", "slidedown", "smoothleft", "smoothright",
+	"circleopen", "circleclose", "radial", "pixelize", "zoomin",
+}
+
+var SupportedSlideshowResolutions = map[string]int{"480p": 480, "720p": 720, "1080p": 1080}
+
+type SlideshowOptions struct {
+	ImageDuration      float64 // 每张图片显示的时长(秒), 默认 3
+	Transition         string  // xfade 转场, 默认 fade
+	TransitionDuration float64 // 转场时长(秒), 默认 1, 必须小于 ImageDuration
+	KenBurns           bool    // 缩放平移的动态效果, 否则静止显示完整图片
+	Width              int
+	Height             int
+	FrameRate          int     // 默认 30
+	MusicFile          string  // 背景音乐, 循环或截断到视频时长
+	MusicVolume        float64 // 默认 1.0
+}
+
+func (opts *SlideshowOptions) setDefaults() {
+	if opts.ImageDuration <= 0 {
+		opts.ImageDuration = 3
+	}
+	if opts.Transition == "" {
+		opts.Transition = "fade"
+	}
+	if opts.TransitionDuration <= 0 {
+		opts.TransitionDuration = 1
+	}
+	if opts.FrameRate <= 0 {
+		opts.FrameRate = 30
+	}
+	if opts.MusicVolume <= 0 {
+		opts.MusicVolume = 1.0
+	}
+}
+
+// SlideshowSize 根据分辨率(短边)和画面比例计算输出宽高
+func SlideshowSize(resolution string, aspect string) (int, int, error) {
+	short, ok := SupportedSlideshowResolutions[resolution]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported resolution: %s, should be 480p, 720p or 1080p", resolution)
+	}
+	ratio, err := parseAspect(aspect)
+	if err != nil {
+		return 0, 0, err
+	}
+	if ratio >= 1 {
+		return even(float64(short) * ratio), short, nil
+	}
+	return short, even(float64(short) / ratio), nil
+}
+
+// ListImages 返回目录下的图片文件, 按文件名排序
+func ListImages(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, e := range entries {
+		if !e.IsDir() && ImageExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			images = append(images, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// SlideshowDuration 返回总时长, 每次转场两张图片重叠 TransitionDuration 秒
+func SlideshowDuration(count int, opts *SlideshowOptions) float64 {
+	if opts.Transition == "none" || count < 2 {
+		return float64(count) * opts.ImageDuration
+	}
+	return float64(count)*opts.ImageDuration - float64(count-1)*opts.TransitionDuration
+}
+
+// kenBurnsFilter 返回第 i 张图片的 zoompan 滤镜, 依次使用放大、缩小、向右平移、向左平移
+func kenBurnsFilter(i int, opts *SlideshowOptions) string {
+	frames := int(opts.ImageDuration * float64(opts.FrameRate))
+	center := "x='iw/2-(iw/zoom/2)':y='ih/2-(ih/zoom/2)'"
+	var motion string
+	switch i % 4 {
+	case 0:
+		motion = fmt.Sprintf("z='1+0.2*on/%d':%s", frames, center)
+	case 1:
+		motion = fmt.Sprintf("z='1.2-0.2*on/%d':%s", frames, center)
+	case 2:
+		motion = fmt.Sprintf("z=1.2:x='(iw-iw/zoom)*on/%d':y='ih/2-(ih/zoom/2)'", frames)
+	case 3:
+		motion = fmt.Sprintf("z=1.2:x='(iw-iw/zoom)*(1-on/%d)':y='ih/2-(ih/zoom/2)'", frames)
+	}
+	return fmt.Sprintf("zoompan=%s:d=1:s=%dx%d:fps=%d", motion, opts.Width, opts.Height, opts.FrameRate)
+}
+
+// BuildSlideshowArgs 生成图片轮播的 ffmpeg 参数, 返回视频时长
+func BuildSlideshowArgs(images []string, outputFile string, opts *SlideshowOptions) ([]string, float64, error) {
+	if len(images) == 0 {
+		return nil, 0, fmt.Errorf("no image provided")
+	}
+	if len(images) > kMaxSlideshowImages {
+		return nil, 0, fmt.Errorf("too many images: %d, max %d", len(images), kMaxSlideshowImages)
+	}
+	opts.setDefaults()
+	if opts.Width <= 0 || opts.Height <= 0 || opts.Width%2 != 0 || opts.Height%2 != 0 {
+		return nil, 0, fmt.Errorf("invalid output size: %dx%d", opts.Width, opts.Height)
+	}
+	valid := false
+	for _, t := range SupportedTransitions {
+		valid = valid || t == opts.Transition
+	}
+	if !valid {
+		return nil, 0, fmt.Errorf("unsupported transition: %s", opts.Transition)
+	}
+	if opts.Transition != "none" && opts.TransitionDuration >= opts.ImageDuration {
+		return nil, 0, fmt.Errorf("transition duration %gs must be shorter than image duration %gs", opts.TransitionDuration, opts.ImageDuration)
+	}
+	duration := SlideshowDuration(len(images), opts)
+
+	var args []string
+	for _, img := range images {
+		args = append(args, "-loop", "1", "-framerate", fmt.Sprint(opts.FrameRate),
+			"-t", fmt.Sprintf("%.3f", opts.ImageDuration), "-i", img)
+	}
+
+	var filters []string
+	for i := range images {
+		var f string
+		if opts.KenBurns {
+			// 先放大到两倍再 zoompan, 减少缩放时的抖动
+			f = fmt.Sprintf("[%d:v]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,%s",
+				i, opts.Width*2, opts.Height*2, opts.Width*2, opts.Height*2, kenBurnsFilter(i, opts))
+		} else {
+			f = fmt.Sprintf("[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,fps=%d",
+				i, opts.Width, opts.Height, opts.Width, opts.Height, opts.FrameRate)
+		}
+		filters = append(filters, f+fmt.Sprintf(",setsar=1,format=yuv420p,trim=duration=%.3f,setpts=PTS-STARTPTS[v%d]", opts.ImageDuration, i))
+	}
+
+	switch {
+	case len(images) == 1:
+		filters = append(filters, "[v0]null[vout]")
+	case opts.Transition == "none":
+		var labels string
+		for i := range images {
+			labels += fmt.Sprintf("[v%d]", i)
+		}
+		filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=0[vout]", labels, len(images)))
+	default:
+		last := "[v0]"
+		for i := 1; i < len(images); i++ {
+			out := fmt.Sprintf("[x%d]", i)
+			if i == len(images)-1 {
+				out = "[vout]"
+			}
+			offset := float64(i) * (opts.ImageDuration - opts.TransitionDuration)
+			filters = append(filters, fmt.Sprintf("%s[v%d]xfade=transition=%s:duration=%.3f:offset=%.3f%s",
+				last, i, opts.Transition, opts.TransitionDuration, offset, out))
+			last = out
+		}
+	}
+
+	if opts.MusicFile != "" {
+		args = append(args, "-stream_loop", "-1", "-i", opts.MusicFile)
+		fade := min(2, duration/2)
+		filters = append(filters, fmt.Sprintf("[%d:a]atrim=duration=%.3f,asetpts=PTS-STARTPTS,volume=%g,afade=t=out:st=%.3f:d=%.3f[aout]",
+			len(images), duration, opts.MusicVolume, duration-fade, fade))
+	}
+
+	args = append(args, "-filter_complex", strings.Join(filters, ";"), "-map", "[vout]")
+	if opts.MusicFile != "" {
+		args = append(args, "-map", "[aout]", "-c:a", "aac", "-b:a", "128k")
+	}
+	args = append(args,
+		"-c:v", "libx264", "-crf", "20", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-r", fmt.Sprint(opts.FrameRate),
+		"-t", fmt.Sprintf("%.3f", duration),
+		"-movflags", "+faststart",
+		"-y", outputFile)
+	return args, duration, nil
+}
+
+// ImagesToVideo 把图片合成轮播视频并上报进度
+func ImagesToVideo(id string, args []string, duration float64, progressObj pub.ProgressCallback) error {
+	return runFFmpegWithProgress(id, "图片合成视频", args, duration, progressObj)
+}
@@ -0,0 +1,84 @@
+package ffmpegcmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSlideshowSize(t *testing.T) {
+	cases := []struct {
+		resolution, aspect string
+		w, h               int
+	}{
+		{"1080p", "16:9", 1920, 1080},
+		{"720p", "9:16", 720, 1280},
+		{"720p", "1:1", 720, 720},
+		{"480p", "4:3", 640, 480},
+	}
+	for _, c := range cases {
+		w, h, err := SlideshowSize(c.resolution, c.aspect)
+		if err != nil || w != c.w || h != c.h {
+			t.Errorf("SlideshowSize(%s, %s) got %dx%d, %v, want %dx%d", c.resolution, c.aspect, w, h, err, c.w, c.h)
+		}
+	}
+	if _, _, err := SlideshowSize("8k", "16:9"); err == nil {
+		t.Errorf("expected error for unsupported resolution")
+	}
+}
+
+func TestListImages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.PNG", "a.jpg", "notes.txt", "c.webp"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	os.Mkdir(filepath.Join(dir, "sub.jpg"), 0755)
+	images, err := ListImages(dir)
+	want := []string{filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.PNG"), filepath.Join(dir, "c.webp")}
+	if err != nil || !reflect.DeepEqual(images, want) {
+		t.Errorf("ListImages got %v, %v", images, err)
+	}
+}
+
+func TestBuildSlideshowArgs(t *testing.T) {
+	opts := &SlideshowOptions{ImageDuration: 4, TransitionDuration: 1, KenBurns: true, Width: 1280, Height: 720, MusicFile: "bgm.mp3"}
+	args, duration, err := BuildSlideshowArgs([]string{"1.jpg", "2.jpg", "3.jpg"}, "out.mp4", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duration != 10 {
+		t.Errorf("duration got %g, want 10", duration)
+	}
+	cmd := strings.Join(args, " ")
+	wants := []string{
+		"-loop 1 -framerate 30 -t 4.000 -i 1.jpg",
+		"-stream_loop -1 -i bgm.mp3",
+		"[0:v]scale=2560:1440:force_original_aspect_ratio=increase,crop=2560:1440,zoompan=z='1+0.2*on/120':x='iw/2-(iw/zoom/2)':y='ih/2-(ih/zoom/2)':d=1:s=1280x720:fps=30,setsar=1,format=yuv420p,trim=duration=4.000,setpts=PTS-STARTPTS[v0]",
+		"[v0][v1]xfade=transition=fade:duration=1.000:offset=3.000[x1]",
+		"[x1][v2]xfade=transition=fade:duration=1.000:offset=6.000[vout]",
+		"[3:a]atrim=duration=10.000,asetpts=PTS-STARTPTS,volume=1,afade=t=out:st=8.000:d=2.000[aout]",
+		"-map [vout] -map [aout]",
+		"-t 10.000",
+	}
+	for _, w := range wants {
+		if !strings.Contains(cmd, w) {
+			t.Errorf("args missing %q:\n%s", w, cmd)
+		}
+	}
+
+	opts = &SlideshowOptions{Transition: "none", Width: 640, Height: 480}
+	args, duration, _ = BuildSlideshowArgs([]string{"1.jpg", "2.jpg"}, "out.mp4", opts)
+	cmd = strings.Join(args, " ")
+	if duration != 6 || !strings.Contains(cmd, "[v0][v1]concat=n=2:v=1:a=0[vout]") || strings.Contains(cmd, "[aout]") {
+		t.Errorf("no transition args got %s, duration %g", cmd, duration)
+	}
+
+	if _, _, err := BuildSlideshowArgs([]string{"1.jpg", "2.jpg"}, "out.mp4", &SlideshowOptions{ImageDuration: 1, TransitionDuration: 1, Width: 640, Height: 480}); err == nil {
+		t.Errorf("expected error for transition longer than image")
+	}
+	if _, _, err := BuildSlideshowArgs([]string{"1.jpg"}, "out.mp4", &SlideshowOptions{Transition: "spin", Width: 640, Height: 480}); err == nil {
+		t.Errorf("expected error for unsupported transition")
+	}
+}
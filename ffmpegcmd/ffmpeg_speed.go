@@ -0,0 +1,153 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gollmagent/pub"
+)
+
+// 倍速范围, atempo 单个滤镜只支持 0.5~2.0, 超出时串联多个
+const (
+	kMinSpeedFactor = 0.1
+	kMaxSpeedFactor = 10.0
+)
+
+// reverse 滤镜需要把整段画面缓存在内存里, 限制输入时长
+const kMaxReverseDuration = 60
+
+type SpeedOptions struct {
+	Factor      float64 // 倍速, 大于 1 加速, 小于 1 慢放
+	DropAudio   bool    // 去掉音频, 否则用 atempo 变速不变调
+	Interpolate bool    // 慢放时用 minterpolate 运动补偿插帧, 画面更流畅, 但编码很慢
+	FrameRate   float64 // 插帧的目标帧率, 0 表示使用源帧率
+}
+
+func formatFactor(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// BuildAtempoChain 把倍速拆成多个 0.5~2.0 之间的 atempo 串联
+func BuildAtempoChain(factor float64) string {
+	var items []string
+	for factor > 2.0 {
+		items = append(items, "atempo=2.0")
+		factor /= 2.0
+	}
+	for factor < 0.5 {
+		items = append(items, "atempo=0.5")
+		factor /= 0.5
+	}
+	items = append(items, "atempo="+formatFactor(math.Round(factor*1e6)/1e6))
+	return strings.Join(items, ",")
+}
+
+// BuildSpeedFilters 生成变速的视频和音频滤镜, audioFilter 为空表示不输出音频
+func BuildSpeedFilters(opts *SpeedOptions, hasAudio bool, srcFps float64) (videoFilter string, audioFilter string, err error) {
+	if opts.Factor < kMinSpeedFactor || opts.Factor > kMaxSpeedFactor {
+		return "", "", fmt.Errorf("invalid speed factor: %g, should be between %g and %g", opts.Factor, kMinSpeedFactor, kMaxSpeedFactor)
+	}
+	videoFilter = fmt.Sprintf("setpts=PTS/%s", formatFactor(opts.Factor))
+	if opts.Interpolate && opts.Factor < 1 {
+		fps := opts.FrameRate
+		if fps <= 0 {
+			fps = srcFps
+		}
+		if fps <= 0 {
+			return "", "", fmt.Errorf("frame rate is required for interpolation")
+		}
+		videoFilter += fmt.Sprintf(",minterpolate=fps=%s:mi_mode=mci:mc_mode=aobmc:me_mode=bidir:vsbmc=1", formatFactor(fps))
+	}
+	if hasAudio && !opts.DropAudio {
+		audioFilter = BuildAtempoChain(opts.Factor)
+	}
+	return videoFilter, audioFilter, nil
+}
+
+// BuildChangeSpeedArgs 生成变速的 ffmpeg 参数
+func BuildChangeSpeedArgs(inputFile string, outputFile string, videoFilter string, audioFilter string) []string {
+	args := []string{
+		"-i", inputFile,
+		"-filter:v", videoFilter,
+	}
+	if audioFilter != "" {
+		args = append(args, "-filter:a", audioFilter, "-c:a", "aac", "-b:a", "128k")
+	} else {
+		args = append(args, "-an")
+	}
+	return append(args,
+		"-c:v", "libx264", "-crf", "20", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		"-y", outputFile)
+}
+
+// ChangeSpeed 变速并上报进度, duration 为源文件时长
+func ChangeSpeed(id string, inputFile string, outputFile string, opts *SpeedOptions, hasAudio bool, srcFps float64, duration float64, progressObj pub.ProgressCallback) error {
+	videoFilter, audioFilter, err := BuildSpeedFilters(opts, hasAudio, srcFps)
+	if err != nil {
+		progressObj.OnProgress(&pub.ProgressInfo{
+			Progress: 0,
+			Message:  fmt.Sprintf("变速失败: %v", err),
+			Done:     true,
+		}, id)
+		return err
+	}
+	args := BuildChangeSpeedArgs(inputFile, outputFile, videoFilter, audioFilter)
+	return runFFmpegWithProgress(id, "变速", args, duration/opts.Factor, progressObj)
+}
+
+// BuildReverseArgs 生成倒放的参数, boomerang 为 true 时正放后接倒放
+func BuildReverseArgs(inputFile string, outputFile string, boomerang bool, withAudio bool) []string {
+	args := []string{"-i", inputFile}
+	if boomerang {
+		filter := "[0:v]split[fwd][tmp];[tmp]reverse[rev];[fwd][rev]concat=n=2:v=1:a=0[v]"
+		if withAudio {
+			filter = "[0:v]split[fwd][tmp];[tmp]reverse[rev];[0:a]asplit[afwd][atmp];[atmp]areverse[arev];" +
+				"[fwd][afwd][rev][arev]concat=n=2:v=1:a=1[v][a]"
+		}
+		args = append(args, "-filter_complex", filter, "-map", "[v]")
+		if withAudio {
+			args = append(args, "-map", "[a]")
+		}
+	} else {
+		args = append(args, "-vf", "reverse")
+		if withAudio {
+			args = append(args, "-af", "areverse")
+		}
+	}
+	if withAudio {
+		args = append(args, "-c:a", "aac", "-b:a", "128k")
+	} else {
+		args = append(args, "-an")
+	}
+	return append(args,
+		"-c:v", "libx264", "-crf", "20", "-preset", "medium", "-pix_fmt", "yuv420p",
+		"-y", outputFile)
+}
+
+// ReverseVideo 倒放或回旋(正放+倒放), 输入时长不能超过 kMaxReverseDuration 秒
+func ReverseVideo(inputFile string, outputFile string, boomerang bool, withAudio bool, duration float64) error {
+	if duration > kMaxReverseDuration {
+		return fmt.Errorf("input is too long to reverse: %.1fs, max %ds, please trim it first", duration, kMaxReverseDuration)
+	}
+	_, err := runFFmpeg(BuildReverseArgs(inputFile, outputFile, boomerang, withAudio))
+	return err
+}
+
+// LoopMedia 把文件循环 count 次, 直接拷贝流不重新编码
+func LoopMedia(inputFile string, outputFile string, count int) error {
+	if count < 2 {
+		return fmt.Errorf("invalid loop count: %d, should be at least 2", count)
+	}
+	args := []string{
+		"-stream_loop", strconv.Itoa(count - 1),
+		"-i", inputFile,
+		"-map", "0",
+		"-c", "copy",
+		"-y", outputFile,
+	}
+	_, err := runFFmpeg(args)
+	return err
+}
@@ -0,0 +1,56 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildAtempoChain(t *testing.T) {
+	cases := map[float64]string{
+		1.5:  "atempo=1.5",
+		4:    "atempo=2.0,atempo=2",
+		5:    "atempo=2.0,atempo=2.0,atempo=1.25",
+		0.25: "atempo=0.5,atempo=0.5",
+		0.3:  "atempo=0.5,atempo=0.6",
+	}
+	for factor, want := range cases {
+		if got := BuildAtempoChain(factor); got != want {
+			t.Errorf("BuildAtempoChain(%g) got %s, want %s", factor, got, want)
+		}
+	}
+}
+
+func TestBuildSpeedFilters(t *testing.T) {
+	v, a, err := BuildSpeedFilters(&SpeedOptions{Factor: 0.5, Interpolate: true}, true, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "setpts=PTS/0.5,minterpolate=fps=30:mi_mode=mci:mc_mode=aobmc:me_mode=bidir:vsbmc=1" || a != "atempo=0.5" {
+		t.Errorf("slow motion filters got %s, %s", v, a)
+	}
+
+	// 加速时不插帧, 去掉音频
+	v, a, _ = BuildSpeedFilters(&SpeedOptions{Factor: 3, Interpolate: true, DropAudio: true}, true, 30)
+	if v != "setpts=PTS/3" || a != "" {
+		t.Errorf("fast filters got %s, %s", v, a)
+	}
+	args := strings.Join(BuildChangeSpeedArgs("in.mp4", "out.mp4", v, a), " ")
+	if !strings.Contains(args, "-filter:v setpts=PTS/3 -an") {
+		t.Errorf("args got %s", args)
+	}
+	if _, _, err := BuildSpeedFilters(&SpeedOptions{Factor: 20}, true, 30); err == nil {
+		t.Errorf("expected error for invalid factor")
+	}
+}
+
+func TestBuildReverseArgs(t *testing.T) {
+	args := strings.Join(BuildReverseArgs("in.mp4", "out.mp4", false, true), " ")
+	if !strings.Contains(args, "-vf reverse -af areverse -c:a aac") {
+		t.Errorf("reverse args got %s", args)
+	}
+	args = strings.Join(BuildReverseArgs("in.mp4", "out.mp4", true, false), " ")
+	want := "-filter_complex [0:v]split[fwd][tmp];[tmp]reverse[rev];[fwd][rev]concat=n=2:v=1:a=0[v] -map [v] -an"
+	if !strings.Contains(args, want) {
+		t.Errorf("boomerang args got %s", args)
+	}
+}
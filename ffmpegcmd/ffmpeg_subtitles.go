@@ -0,0 +1,248 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 字幕颜色名, 值为 RRGGBB
+var subtitleColorNames = map[string]string{
+	"white":   "FFFFFF",
+	"black":   "000000",
+	"red":     "FF0000",
+	"green":   "00FF00",
+	"blue":    "0000FF",
+	"yellow":  "FFFF00",
+	"cyan":    "00FFFF",
+	"magenta": "FF00FF",
+	"gray":    "808080",
+}
+
+// ASS 对齐方式按小键盘布局, 这里只用水平居中的三种
+var subtitleAlignments = map[string]int{
+	"bottom": 2,
+	"middle": 5,
+	"top":    8,
+}
+
+type SubtitleStyle struct {
+	FontName     string
+	FontSize     int
+	Color        string  // 文字颜色, 颜色名或 #RRGGBB
+	OutlineColor string  // 描边颜色
+	Outline      float64 // 描边宽度, 0 表示使用字幕文件或 libass 的默认值
+	MarginV      int     // 距离上下边缘的距离(像素)
+	Position     string  // bottom, middle, top
+	Bold         bool
+	FontsDir     string // 额外的字体目录
+}
+
+// ASSColor 把颜色名或 #RRGGBB 转换为 ASS 的 &HAABBGGRR 格式(AA=00 表示不透明)
+func ASSColor(color string) (string, error) {
+	c := strings.ToLower(strings.TrimSpace(color))
+	if hex, ok := subtitleColorNames[c]; ok {
+		c = hex
+	}
+	c = strings.TrimPrefix(strings.TrimPrefix(c, "#"), "0x")
+	if len(c) != 6 {
+		return "", fmt.Errorf("invalid color: %s", color)
+	}
+	if _, err := strconv.ParseUint(c, 16, 32); err != nil {
+		return "", fmt.Errorf("invalid color: %s", color)
+	}
+	return strings.ToUpper("&H00" + c[4:6] + c[2:4] + c[0:2]), nil
+}
+
+// BuildForceStyle 生成 subtitles 滤镜的 force_style, 只包含设置了的字段
+func BuildForceStyle(style *SubtitleStyle) (string, error) {
+	var items []string
+	if style.FontName != "" {
+		// 样式值用单引号包起来, 字体名里不能再有引号和逗号
+		items = append(items, "FontName="+strings.NewReplacer("'", "", ",", "").Replace(style.FontName))
+	}
+	if style.FontSize > 0 {
+		items = append(items, fmt.Sprintf("FontSize=%d", style.FontSize))
+	}
+	if style.Color != "" {
+		c, err := ASSColor(style.Color)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, "PrimaryColour="+c)
+	}
+	if style.OutlineColor != "" {
+		c, err := ASSColor(style.OutlineColor)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, "OutlineColour="+c)
+	}
+	if style.Outline > 0 {
+		items = append(items, "BorderStyle=1", fmt.Sprintf("Outline=%g", style.Outline))
+	}
+	if style.MarginV > 0 {
+		items = append(items, fmt.Sprintf("MarginV=%d", style.MarginV))
+	}
+	if style.Position != "" {
+		alignment, ok := subtitleAlignments[style.Position]
+		if !ok {
+			return "", fmt.Errorf("invalid subtitle position: %s, should be bottom, middle or top", style.Position)
+		}
+		items = append(items, fmt.Sprintf("Alignment=%d", alignment))
+	}
+	if style.Bold {
+		items = append(items, "Bold=1")
+	}
+	return strings.Join(items, ","), nil
+}
+
+// BuildBurnSubtitlesFilter 生成烧录字幕的滤镜. ass/ssa 字幕没有样式覆盖时用 ass 滤镜保留原样式
+func BuildBurnSubtitlesFilter(subtitleFile string, style *SubtitleStyle) (string, error) {
+	forceStyle, err := BuildForceStyle(style)
+	if err != nil {
+		return "", err
+	}
+	ext := strings.ToLower(filepath.Ext(subtitleFile))
+	var filter string
+	if forceStyle == "" && (ext == ".ass" || ext == ".ssa") {
+		filter = "ass=filename=" + EscapeFilterValue(subtitleFile)
+	} else {
+		filter = "subtitles=filename=" + EscapeFilterValue(subtitleFile)
+		if forceStyle != "" {
+			filter += ":force_style='" + forceStyle + "'"
+		}
+	}
+	if style.FontsDir != "" {
+		filter += ":fontsdir=" + EscapeFilterValue(style.FontsDir)
+	}
+	return filter, nil
+}
+
+// BurnSubtitles 把字幕烧录到画面里, 视频需要重新编码, 音频直接拷贝
+func BurnSubtitles(inputFile string, subtitleFile string, outputFile string, style *SubtitleStyle) error {
+	filter, err := BuildBurnSubtitlesFilter(subtitleFile, style)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"-i", inputFile,
+		"-vf", filter,
+		"-c:v", "libx264", "-crf", "20", "-preset", "medium",
+		"-c:a", "copy",
+		"-y", outputFile,
+	}
+	_, err = runFFmpeg(args)
+	return err
+}
+
+type SubtitleTrack struct {
+	File     string `json:"file"`
+	Language string `json:"language"` // ISO 639-2, 例如 chi, eng, jpn
+	Title    string `json:"title"`
+	Default  bool   `json:"default"`
+	Forced   bool   `json:"forced"`
+}
+
+// 可以转换为 mov_text 放进 mp4/mov 的字幕格式, 其他格式(ass 样式, 图形字幕)只能放进 mkv
+var movTextCompatible = map[string]bool{".srt": true, ".vtt": true}
+
+// ChooseSubtitleContainer 选择软字幕输出的容器: container 为 auto 时, mp4/mov 输入且字幕都能转 mov_text 就保持原容器, 否则用 mkv
+func ChooseSubtitleContainer(inputFile string, tracks []SubtitleTrack, container string) (string, error) {
+	compatible := true
+	for _, t := range tracks {
+		if !movTextCompatible[strings.ToLower(filepath.Ext(t.File))] {
+			compatible = false
+			break
+		}
+	}
+	inputExt := strings.TrimPrefix(strings.ToLower(filepath.Ext(inputFile)), ".")
+	switch container {
+	case "", "auto":
+		if compatible && (inputExt == "mp4" || inputExt == "mov") {
+			return inputExt, nil
+		}
+		return "mkv", nil
+	case "mp4", "mov":
+		if !compatible {
+			return "", fmt.Errorf("%s can only carry srt/vtt subtitles as mov_text, use mkv for other formats", container)
+		}
+		return container, nil
+	case "mkv":
+		return container, nil
+	}
+	return "", fmt.Errorf("unsupported container for subtitles: %s", container)
+}
+
+func trackDisposition(t SubtitleTrack) string {
+	var flags []string
+	if t.Default {
+		flags = append(flags, "default")
+	}
+	if t.Forced {
+		flags = append(flags, "forced")
+	}
+	if len(flags) == 0 {
+		return "0"
+	}
+	return strings.Join(flags, "+")
+}
+
+// mkv 不能直接放的字幕编码, 转成 srt
+var mkvIncompatibleSubtitles = map[string]bool{"mov_text": true}
+
+// BuildSubtitleTracksArgs 生成添加软字幕的参数. existingSubtitles 是输入文件已有字幕流的编码名, 新字幕的序号排在它们后面.
+// 数据流(例如 tmcd)在 mkv 里放不下, 统一丢掉
+func BuildSubtitleTracksArgs(inputFile string, tracks []SubtitleTrack, outputFile string, container string, existingSubtitles []string) []string {
+	args := []string{"-i", inputFile}
+	for _, t := range tracks {
+		args = append(args, "-i", t.File)
+	}
+	args = append(args, "-map", "0", "-map", "-0:d")
+	for i := range tracks {
+		args = append(args, "-map", fmt.Sprintf("%d:s:0", i+1))
+	}
+	args = append(args, "-c", "copy")
+	if container == "mkv" {
+		args = append(args, "-c:s", "copy")
+		for i, codec := range existingSubtitles {
+			if mkvIncompatibleSubtitles[codec] {
+				args = append(args, fmt.Sprintf("-c:s:%d", i), "srt")
+			}
+		}
+	} else {
+		args = append(args, "-c:s", "mov_text")
+	}
+
+	// 新字幕设为默认时, 去掉已有字幕的默认标记, 避免出现两条默认字幕
+	for _, t := range tracks {
+		if t.Default {
+			for i := range existingSubtitles {
+				args = append(args, fmt.Sprintf("-disposition:s:%d", i), "0")
+			}
+			break
+		}
+	}
+	for i, t := range tracks {
+		idx := len(existingSubtitles) + i
+		if t.Language != "" {
+			args = append(args, fmt.Sprintf("-metadata:s:s:%d", idx), "language="+t.Language)
+		}
+		if t.Title != "" {
+			args = append(args, fmt.Sprintf("-metadata:s:s:%d", idx), "title="+t.Title)
+		}
+		args = append(args, fmt.Sprintf("-disposition:s:%d", idx), trackDisposition(t))
+	}
+	format := map[string]string{"mkv": "matroska", "mp4": "mp4", "mov": "mov"}[container]
+	return append(args, "-f", format, "-y", outputFile)
+}
+
+// AddSubtitleTracks 把多个字幕文件作为软字幕轨道封装进视频
+func AddSubtitleTracks(inputFile string, tracks []SubtitleTrack, outputFile string, container string, existingSubtitles []string) error {
+	if len(tracks) == 0 {
+		return fmt.Errorf("no subtitle track provided")
+	}
+	_, err := runFFmpeg(BuildSubtitleTracksArgs(inputFile, tracks, outputFile, container, existingSubtitles))
+	return err
+}
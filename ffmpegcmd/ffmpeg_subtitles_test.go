@@ -0,0 +1,90 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestASSColor(t *testing.T) {
+	cases := map[string]string{
+		"white":    "&H00FFFFFF",
+		"#FF8000":  "&H000080FF",
+		"0x00ff00": "&H0000FF00",
+	}
+	for in, want := range cases {
+		if got, err := ASSColor(in); err != nil || got != want {
+			t.Errorf("ASSColor(%s) got %s, %v, want %s", in, got, err, want)
+		}
+	}
+	if _, err := ASSColor("#12345"); err == nil {
+		t.Errorf("expected error for invalid color")
+	}
+}
+
+func TestBuildBurnSubtitlesFilter(t *testing.T) {
+	style := &SubtitleStyle{FontName: "Noto Sans CJK SC", FontSize: 28, Color: "yellow", Outline: 2, MarginV: 40, Position: "bottom"}
+	filter, err := BuildBurnSubtitlesFilter("/tmp/a.srt", style)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "subtitles=filename=/tmp/a.srt:force_style='FontName=Noto Sans CJK SC,FontSize=28,PrimaryColour=&H0000FFFF,BorderStyle=1,Outline=2,MarginV=40,Alignment=2'"
+	if filter != want {
+		t.Errorf("filter got:\n%s\nwant:\n%s", filter, want)
+	}
+
+	// 没有样式覆盖的 ass 字幕保留原样式
+	filter, _ = BuildBurnSubtitlesFilter("/tmp/a.ass", &SubtitleStyle{FontsDir: "/fonts"})
+	if filter != "ass=filename=/tmp/a.ass:fontsdir=/fonts" {
+		t.Errorf("ass filter got %s", filter)
+	}
+	if _, err := BuildBurnSubtitlesFilter("/tmp/a.srt", &SubtitleStyle{Position: "left"}); err == nil {
+		t.Errorf("expected error for invalid position")
+	}
+}
+
+func TestChooseSubtitleContainer(t *testing.T) {
+	srt := []SubtitleTrack{{File: "zh.srt"}, {File: "en.vtt"}}
+	ass := []SubtitleTrack{{File: "zh.srt"}, {File: "en.ass"}}
+	cases := []struct {
+		input     string
+		tracks    []SubtitleTrack
+		container string
+		want      string
+	}{
+		{"a.mp4", srt, "auto", "mp4"},
+		{"a.mp4", ass, "auto", "mkv"},
+		{"a.mkv", srt, "", "mkv"},
+		{"a.mov", srt, "mkv", "mkv"},
+		{"a.mp4", ass, "mp4", ""},
+	}
+	for _, c := range cases {
+		got, err := ChooseSubtitleContainer(c.input, c.tracks, c.container)
+		if got != c.want || (c.want == "") != (err != nil) {
+			t.Errorf("ChooseSubtitleContainer(%s, %s) got %s, %v, want %s", c.input, c.container, got, err, c.want)
+		}
+	}
+}
+
+func TestBuildSubtitleTracksArgs(t *testing.T) {
+	tracks := []SubtitleTrack{
+		{File: "zh.srt", Language: "chi", Title: "中文", Default: true},
+		{File: "en.ass", Language: "eng", Forced: true},
+	}
+	args := strings.Join(BuildSubtitleTracksArgs("a.mkv", tracks, "out.mkv", "mkv", []string{"subrip"}), " ")
+	want := "-i a.mkv -i zh.srt -i en.ass -map 0 -map -0:d -map 1:s:0 -map 2:s:0 -c copy -c:s copy " +
+		"-disposition:s:0 0 " +
+		"-metadata:s:s:1 language=chi -metadata:s:s:1 title=中文 -disposition:s:1 default " +
+		"-metadata:s:s:2 language=eng -disposition:s:2 forced -f matroska -y out.mkv"
+	if args != want {
+		t.Errorf("args got:\n%s\nwant:\n%s", args, want)
+	}
+
+	// mp4 里已有的 mov_text 字幕放进 mkv 时转成 srt, 新字幕不是默认时保留原有标记
+	tracks = []SubtitleTrack{{File: "en.ass", Language: "eng"}}
+	args = strings.Join(BuildSubtitleTracksArgs("a.mp4", tracks, "out.mkv", "mkv", []string{"mov_text", "mov_text"}), " ")
+	want = "-i a.mp4 -i en.ass -map 0 -map -0:d -map 1:s:0 -c copy -c:s copy -c:s:0 srt -c:s:1 srt " +
+		"-metadata:s:s:2 language=eng -disposition:s:2 0 -f matroska -y out.mkv"
+	if args != want {
+		t.Errorf("args got:\n%s\nwant:\n%s", args, want)
+	}
+}
@@ -0,0 +1,189 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+// 容器封装的开销按 3% 预留
+const kContainerOverhead = 0.03
+
+// 两遍编码的实际码率会有几个百分点的波动, 再预留 5% 保证不超过目标大小
+const kBitrateMargin = 0.05
+
+// BytesPerMB 目标大小按十进制 MB 计算, 和上传限制的口径一致, 也比 MiB 小, 更不容易超限
+const BytesPerMB = 1000 * 1000
+
+// 各分辨率(短边)可接受的最低视频码率 kbps, 低于这个码率就降分辨率
+var minVideoBitrates = []struct {
+	ShortSide int
+	Kbps      int
+}{
+	{1080, 1200},
+	{720, 700},
+	{480, 400},
+	{360, 250},
+	{240, 150},
+}
+
+type TargetSizePlan struct {
+	TargetBytes int64 `json:"target_bytes"`
+	VideoKbps   int   `json:"video_kbps"`
+	AudioKbps   int   `json:"audio_kbps"`
+	Width       int   `json:"width"`
+	Height      int   `json:"height"`
+	Downscaled  bool  `json:"downscaled"`
+}
+
+// ComputeTargetBitrate 根据目标大小、时长和音频码率计算视频码率(kbps), 扣除封装开销和码率波动的余量
+func ComputeTargetBitrate(targetBytes int64, duration float64, audioKbps int) (int, error) {
+	if targetBytes <= 0 || duration <= 0 {
+		return 0, fmt.Errorf("invalid target size %d or duration %.2f", targetBytes, duration)
+	}
+	totalKbps := float64(targetBytes) * 8 / 1000 / duration * (1 - kContainerOverhead - kBitrateMargin)
+	videoKbps := int(totalKbps) - audioKbps
+	if videoKbps <= 0 {
+		return 0, fmt.Errorf("target size too small: total bitrate %.0fkbps is not enough for %dkbps audio", totalKbps, audioKbps)
+	}
+	return videoKbps, nil
+}
+
+func minBitrateForShortSide(shortSide int) int {
+	for _, item := range minVideoBitrates {
+		if shortSide >= item.ShortSide {
+			return item.Kbps
+		}
+	}
+	return minVideoBitrates[len(minVideoBitrates)-1].Kbps
+}
+
+// scaleToShortSide 按短边缩放, 保持宽高比, 宽高取偶数
+func scaleToShortSide(w int, h int, shortSide int) (int, int) {
+	if w >= h {
+		return (w*shortSide/h + 1) / 2 * 2, shortSide
+	}
+	return shortSide, (h*shortSide/w + 1) / 2 * 2
+}
+
+// PlanTargetSize 计算达到目标大小的码率, 码率低于当前分辨率的下限时逐档降低分辨率
+func PlanTargetSize(targetBytes int64, duration float64, audioKbps int, w int, h int) (*TargetSizePlan, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid input dimensions: %dx%d", w, h)
+	}
+	videoKbps, err := ComputeTargetBitrate(targetBytes, duration, audioKbps)
+	if err != nil {
+		return nil, err
+	}
+	plan := &TargetSizePlan{
+		TargetBytes: targetBytes,
+		VideoKbps:   videoKbps,
+		AudioKbps:   audioKbps,
+		Width:       (w + 1) / 2 * 2,
+		Height:      (h + 1) / 2 * 2,
+	}
+	shortSide := min(w, h)
+	if videoKbps >= minBitrateForShortSide(shortSide) {
+		return plan, nil
+	}
+	for _, item := range minVideoBitrates {
+		if item.ShortSide >= shortSide {
+			continue
+		}
+		plan.Width, plan.Height = scaleToShortSide(w, h, item.ShortSide)
+		plan.Downscaled = true
+		if videoKbps >= item.Kbps {
+			return plan, nil
+		}
+	}
+	return nil, fmt.Errorf("target size too small: video bitrate %dkbps is below %dkbps even at %dp",
+		videoKbps, minVideoBitrates[len(minVideoBitrates)-1].Kbps, minVideoBitrates[len(minVideoBitrates)-1].ShortSide)
+}
+
+// passProgress 把单遍编码的进度映射到整体进度的一段, 结束状态由调用方统一上报
+type passProgress struct {
+	cb     pub.ProgressCallback
+	pass   int
+	offset float32
+	scale  float32
+}
+
+func (p *passProgress) OnProgress(info *pub.ProgressInfo, id string) {
+	p.cb.OnProgress(&pub.ProgressInfo{
+		Progress: p.offset + info.Progress*p.scale,
+		Message:  fmt.Sprintf("第%d遍: %s", p.pass, info.Message),
+		Done:     false,
+	}, id)
+}
+
+func (p *passProgress) CheckProgress(id string) *pub.ProgressInfo {
+	return p.cb.CheckProgress(id)
+}
+
+func buildTwoPassArgs(in string, out string, plan *TargetSizePlan, passLogFile string, pass int) []string {
+	args := []string{
+		"-i", in,
+		"-c:v", "libx264",
+		"-preset", "medium",
+		"-b:v", fmt.Sprintf("%dk", plan.VideoKbps),
+		"-pass", fmt.Sprintf("%d", pass),
+		"-passlogfile", passLogFile,
+	}
+	if plan.Downscaled {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", plan.Width, plan.Height))
+	}
+	if pass == 1 {
+		// 第一遍只做码率分析, 不需要音频和输出文件
+		return append(args, "-an", "-f", "null", "-y", os.DevNull)
+	}
+	if plan.AudioKbps > 0 {
+		args = append(args, "-c:a", "aac", "-b:a", fmt.Sprintf("%dk", plan.AudioKbps), "-ac", "2")
+	} else {
+		args = append(args, "-an")
+	}
+	return append(args, "-movflags", "+faststart", "-f", "mp4", "-y", out)
+}
+
+// TranscodeToTargetSize 两遍编码输出接近目标大小的 mp4, 第一遍占进度的前 50%, 返回实际文件大小
+func TranscodeToTargetSize(id string, in string, out string, plan *TargetSizePlan, duration float64, progressObj pub.ProgressCallback) (int64, error) {
+	passLogFile := filepath.Join(os.TempDir(), fmt.Sprintf("gollmagent_2pass_%d", time.Now().UnixNano()))
+	defer func() {
+		matches, _ := filepath.Glob(passLogFile + "*")
+		for _, f := range matches {
+			os.Remove(f)
+		}
+	}()
+
+	fail := func(err error) (int64, error) {
+		progressObj.OnProgress(&pub.ProgressInfo{
+			Progress: 0,
+			Message:  fmt.Sprintf("两遍编码失败: %v", err),
+			Done:     true,
+		}, id)
+		return 0, err
+	}
+
+	for pass := 1; pass <= 2; pass++ {
+		cb := &passProgress{cb: progressObj, pass: pass, offset: float32(pass-1) * 0.5, scale: 0.5}
+		if err := runFFmpegWithProgress(id, "编码", buildTwoPassArgs(in, out, plan, passLogFile, pass), duration, cb); err != nil {
+			return fail(err)
+		}
+	}
+
+	fi, err := os.Stat(out)
+	if err != nil {
+		return fail(err)
+	}
+	log.Infof("two-pass encode finished: %s, size:%d, target:%d", out, fi.Size(), plan.TargetBytes)
+	progressObj.OnProgress(&pub.ProgressInfo{
+		Progress: 1.0,
+		Message: fmt.Sprintf("编码完成, 输出文件: %s, 大小: %.2fMB, 目标: %.2fMB",
+			out, float64(fi.Size())/BytesPerMB, float64(plan.TargetBytes)/BytesPerMB),
+		Done: true,
+	}, id)
+	return fi.Size(), nil
+}
@@ -0,0 +1,65 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputeTargetBitrate(t *testing.T) {
+	// 25MB, 100 秒, 128k 音频: 25*1000*1000*8/1000/100*0.92 = 1840kbps, 视频 1712kbps
+	kbps, err := ComputeTargetBitrate(25*BytesPerMB, 100, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kbps != 1712 {
+		t.Errorf("ComputeTargetBitrate got %d, want 1712", kbps)
+	}
+	if _, err := ComputeTargetBitrate(100*1000, 100, 128); err == nil {
+		t.Errorf("expected error when audio alone exceeds target")
+	}
+}
+
+func TestPlanTargetSize(t *testing.T) {
+	// 码率足够时保持原分辨率
+	plan, err := PlanTargetSize(25*BytesPerMB, 100, 128, 1920, 1080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Downscaled || plan.Width != 1920 || plan.Height != 1080 {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+
+	// 10 分钟压到 25MB, 视频约 178kbps, 需要降到 240p
+	plan, err = PlanTargetSize(25*BytesPerMB, 600, 128, 1920, 1080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Downscaled || plan.Height != 240 || plan.Width != 426 {
+		t.Errorf("unexpected downscaled plan: %+v", plan)
+	}
+
+	// 竖屏按短边降
+	plan, err = PlanTargetSize(25*BytesPerMB, 200, 128, 1080, 1920)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Width != 720 || plan.Height != 1280 {
+		t.Errorf("unexpected portrait plan: %+v", plan)
+	}
+
+	if _, err := PlanTargetSize(5*BytesPerMB, 600, 64, 1920, 1080); err == nil {
+		t.Errorf("expected error when even the lowest resolution does not fit")
+	}
+}
+
+func TestBuildTwoPassArgs(t *testing.T) {
+	plan := &TargetSizePlan{VideoKbps: 900, AudioKbps: 96, Width: 854, Height: 480, Downscaled: true}
+	pass1 := strings.Join(buildTwoPassArgs("in.mp4", "out.mp4", plan, "/tmp/log", 1), " ")
+	if !strings.Contains(pass1, "-b:v 900k -pass 1 -passlogfile /tmp/log -vf scale=854:480 -an -f null") {
+		t.Errorf("unexpected pass1 args: %s", pass1)
+	}
+	pass2 := strings.Join(buildTwoPassArgs("in.mp4", "out.mp4", plan, "/tmp/log", 2), " ")
+	if !strings.Contains(pass2, "-pass 2") || !strings.Contains(pass2, "-c:a aac -b:a 96k") || !strings.HasSuffix(pass2, "-y out.mp4") {
+		t.Errorf("unexpected pass2 args: %s", pass2)
+	}
+}
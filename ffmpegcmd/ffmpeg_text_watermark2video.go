@@ -3,57 +3,193 @@ package ffmpegcmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	log "github.com/gollmagent/logging"
 )
 
+// 文字颜色名, 值为 RRGGBB
+var textColorNames = map[string]string{
+	"black": "000000", "white": "FFFFFF", "red": "FF0000", "green": "00FF00", "blue": "0000FF",
+	"yellow": "FFFF00", "cyan": "00FFFF", "magenta": "FF00FF", "gray": "808080", "grey": "808080",
+	"darkred": "8B0000", "darkgreen": "006400", "darkblue": "00008B", "darkyellow": "808000",
+	"darkcyan": "008B8B", "darkmagenta": "8B008B", "lightgray": "D3D3D3", "lightgrey": "D3D3D3",
+}
+
+// 常见系统上的中日韩字体, 文字里有 CJK 字符且没有指定字体时按顺序查找
+var cjkFontCandidates = []string{
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-zenhei.ttc",
+	"/usr/share/fonts/wenquanyi/wqy-microhei/wqy-microhei.ttc",
+	"/System/Library/Fonts/PingFang.ttc",
+	"/System/Library/Fonts/STHeiti Medium.ttc",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"C:/Windows/Fonts/msyh.ttc",
+	"C:/Windows/Fonts/simhei.ttf",
+}
+
+// SupportedTextColor 支持的颜色名, 按字母排序
 func SupportedTextColor() []string {
-	return []string{
-		"black", "white", "red", "green", "blue", "yellow", "cyan", "magenta",
-		"gray", "grey", "darkred", "darkgreen", "darkblue", "darkyellow",
-		"darkcyan", "darkmagenta", "lightgray", "lightgrey",
+	names := make([]string, 0, len(textColorNames))
+	for name := range textColorNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTextColor 把颜色转换为 drawtext 的 0xRRGGBB@alpha 格式.
+// 支持颜色名(可带 @alpha, 例如 white@0.5), #RGB, #RRGGBB, #RRGGBBAA, 0xRRGGBB, rgb(r,g,b), rgba(r,g,b,a)
+func ParseTextColor(color string) (string, error) {
+	c := strings.ToLower(strings.ReplaceAll(color, " ", ""))
+	invalid := fmt.Errorf("invalid color: %s, should be a color name, #RRGGBB, #RRGGBBAA or rgba(r,g,b,a)", color)
+	alpha := 1.0
+
+	if strings.HasPrefix(c, "rgb(") || strings.HasPrefix(c, "rgba(") {
+		body, ok := strings.CutSuffix(c[strings.Index(c, "(")+1:], ")")
+		parts := strings.Split(body, ",")
+		if !ok || len(parts) < 3 || len(parts) > 4 {
+			return "", invalid
+		}
+		var rgb [3]int
+		for i := 0; i < 3; i++ {
+			v, err := strconv.Atoi(parts[i])
+			if err != nil || v < 0 || v > 255 {
+				return "", invalid
+			}
+			rgb[i] = v
+		}
+		if len(parts) == 4 {
+			a, err := strconv.ParseFloat(parts[3], 64)
+			if err != nil || a < 0 || a > 1 {
+				return "", invalid
+			}
+			alpha = a
+		}
+		return fmt.Sprintf("0x%02X%02X%02X@%g", rgb[0], rgb[1], rgb[2], alpha), nil
+	}
+
+	if name, a, ok := strings.Cut(c, "@"); ok {
+		v, err := strconv.ParseFloat(a, 64)
+		if err != nil || v < 0 || v > 1 {
+			return "", invalid
+		}
+		c, alpha = name, v
+	}
+	if hex, ok := textColorNames[c]; ok {
+		c = hex
+	} else {
+		c = strings.TrimPrefix(strings.TrimPrefix(c, "#"), "0x")
+	}
+	if len(c) == 3 {
+		c = string([]byte{c[0], c[0], c[1], c[1], c[2], c[2]})
+	}
+	if len(c) != 6 && len(c) != 8 {
+		return "", invalid
+	}
+	if _, err := strconv.ParseUint(c, 16, 32); err != nil {
+		return "", invalid
 	}
+	if len(c) == 8 {
+		a, _ := strconv.ParseUint(c[6:], 16, 8)
+		alpha = float64(a) / 255
+		c = c[:6]
+	}
+	return fmt.Sprintf("0x%s@%g", strings.ToUpper(c), float64(int(alpha*1000+0.5))/1000), nil
 }
 
-func TextWatermark2Video(inputVideo, watermarkText string, x, y int, colorString string, outputVideo string) error {
+// ContainsCJK 判断文字里是否有中日韩字符, 这些字符需要 CJK 字体才能显示
+func ContainsCJK(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
 
-	colors := SupportedTextColor()
-	supported := false
-	for _, c := range colors {
-		if c == colorString {
-			supported = true
-			break
+// FindCJKFont 返回系统上找到的第一个 CJK 字体文件, 找不到时返回空
+func FindCJKFont() string {
+	for _, f := range cjkFontCandidates {
+		if _, err := os.Stat(f); err == nil {
+			return f
 		}
 	}
-	if !supported {
-		log.Errorf("Unsupported color: %s. Supported colors are: %v", colorString, colors)
-		return fmt.Errorf("unsupported color: %s", colorString)
+	return ""
+}
+
+// buildDrawtextStyle 生成 drawtext 的字体、颜色、描边、阴影和背景框参数
+func buildDrawtextStyle(opts *WatermarkOptions) ([]string, error) {
+	var items []string
+	if opts.FontFile != "" {
+		items = append(items, "fontfile="+EscapeFilterValue(opts.FontFile))
+	} else if opts.Font != "" {
+		items = append(items, "font="+EscapeFilterValue(opts.Font))
 	}
-	// 构建 FFmpeg 命令参数
-	args := []string{
-		"-i", inputVideo, // 输入视频文件
-		"-vf", fmt.Sprintf("drawtext=text='%s':fontcolor=%s:fontsize=24:x=%d:y=%d", watermarkText, colorString, x, y), // 叠加文本水印
-		"-codec:a", "copy", // 保持音频编码不变
-		"-y",        // 覆盖输出文件
-		outputVideo, // 输出视频文件
+
+	color, err := ParseTextColor(opts.Color)
+	if err != nil {
+		return nil, err
 	}
+	items = append(items, "fontcolor="+color, fmt.Sprintf("fontsize=%d", opts.FontSize))
 
-	// 执行 FFmpeg 命令
-	cmd := exec.Command("ffmpeg", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if opts.BorderWidth > 0 {
+		c, err := ParseTextColor(opts.BorderColor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, fmt.Sprintf("borderw=%d", opts.BorderWidth), "bordercolor="+c)
+	}
+	if opts.ShadowX != 0 || opts.ShadowY != 0 {
+		c, err := ParseTextColor(opts.ShadowColor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, fmt.Sprintf("shadowx=%d", opts.ShadowX), fmt.Sprintf("shadowy=%d", opts.ShadowY), "shadowcolor="+c)
+	}
+	if opts.Box {
+		c, err := ParseTextColor(opts.BoxColor)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, "box=1", "boxcolor="+c, fmt.Sprintf("boxborderw=%d", opts.BoxPadding))
+	}
+	return items, nil
+}
 
-	// log print ffmpeg command
-	cmdDbg := "ffmpeg"
-	for _, arg := range args {
-		cmdDbg += fmt.Sprintf(" %s", arg)
+// TextWatermark2Video 给视频添加文字水印. 文字写到临时文件里用 textfile 读取, 不需要转义, 也不会被当成滤镜参数
+func TextWatermark2Video(inputVideo, watermarkText, outputVideo string, duration float64, opts *WatermarkOptions) error {
+	if strings.TrimSpace(watermarkText) == "" {
+		return fmt.Errorf("watermark text is empty")
+	}
+	if opts.FontFile == "" && opts.Font == "" && ContainsCJK(watermarkText) {
+		opts.FontFile = FindCJKFont()
+		if opts.FontFile == "" {
+			log.Warningf("no CJK font found, text may not display correctly, please set font_file")
+		}
 	}
-	fmt.Println("Executing command:", cmdDbg)
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg execution failed: %v", err)
+	textFile, err := os.CreateTemp("", "gollmagent_drawtext_*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(textFile.Name())
+	_, err = textFile.WriteString(watermarkText)
+	textFile.Close()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	args, err := BuildTextWatermarkArgs(inputVideo, textFile.Name(), outputVideo, duration, opts)
+	if err != nil {
+		return err
+	}
+	_, err = runFFmpeg(args)
+	return err
 }
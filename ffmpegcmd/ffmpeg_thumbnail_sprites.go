@@ -0,0 +1,117 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+)
+
+type SpriteOptions struct {
+	Interval   float64 // 采样间隔(秒), 默认5
+	Columns    int     // 每张雪碧图的列数, 默认5
+	Rows       int     // 每张雪碧图的行数, 默认5
+	ThumbWidth int     // 缩略图宽度, 高度按视频比例计算, 默认160
+	Format     string  // jpg 或 webp, 默认 jpg
+}
+
+type SpriteResult struct {
+	Sheets      []string `json:"sheets"`
+	VttFile     string   `json:"vtt_file"`
+	ThumbWidth  int      `json:"thumb_width"`
+	ThumbHeight int      `json:"thumb_height"`
+	Count       int      `json:"count"`
+}
+
+func (opts *SpriteOptions) setDefaults() {
+	if opts.Interval <= 0 {
+		opts.Interval = 5
+	}
+	if opts.Columns <= 0 {
+		opts.Columns = 5
+	}
+	if opts.Rows <= 0 {
+		opts.Rows = 5
+	}
+	if opts.ThumbWidth <= 0 {
+		opts.ThumbWidth = 160
+	}
+	opts.ThumbWidth = (opts.ThumbWidth + 1) / 2 * 2
+	if opts.Format == "" || opts.Format == "jpeg" {
+		opts.Format = "jpg"
+	}
+}
+
+// GenThumbnailSprites 按固定间隔截帧, 用 tile 滤镜拼成雪碧图, 并生成播放器拖动预览用的 thumbnails.vtt.
+// videoW, videoH 是显示宽高(考虑旋转)
+func GenThumbnailSprites(inputFile string, outputDir string, duration float64, videoW int, videoH int, opts *SpriteOptions) (*SpriteResult, error) {
+	opts.setDefaults()
+	if opts.Format != "jpg" && opts.Format != "webp" {
+		return nil, fmt.Errorf("unsupported sprite format: %s", opts.Format)
+	}
+	if duration <= 0 || videoW <= 0 || videoH <= 0 {
+		return nil, fmt.Errorf("invalid video info, duration:%.2f, size:%dx%d", duration, videoW, videoH)
+	}
+	thumbH := int(math.Round(float64(opts.ThumbWidth)*float64(videoH)/float64(videoW)/2)) * 2
+	count := int(math.Ceil(duration / opts.Interval))
+	perSheet := opts.Columns * opts.Rows
+	sheetCount := (count + perSheet - 1) / perSheet
+
+	// 和 GenPictureFromVideoBaseOnIframe 共用截帧参数, 只是用 fps 和 tile 代替 I 帧选择
+	sheetPattern := filepath.Join(outputDir, "sprite_%03d."+opts.Format)
+	args := frameExtractArgs(inputFile,
+		fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d", opts.Interval, opts.ThumbWidth, thumbH, opts.Columns, opts.Rows))
+	if opts.Format == "webp" {
+		args = append(args, "-c:v", "libwebp", "-quality", "75")
+	} else {
+		args = append(args, "-q:v", "3")
+	}
+	args = append(args, "-y", sheetPattern)
+
+	if _, err := runFFmpeg(args); err != nil {
+		return nil, err
+	}
+
+	result := &SpriteResult{
+		VttFile:     filepath.Join(outputDir, "thumbnails.vtt"),
+		ThumbWidth:  opts.ThumbWidth,
+		ThumbHeight: thumbH,
+		Count:       count,
+	}
+	var sheetNames []string
+	for i := 1; i <= sheetCount; i++ {
+		name := fmt.Sprintf("sprite_%03d.%s", i, opts.Format)
+		sheetNames = append(sheetNames, name)
+		result.Sheets = append(result.Sheets, filepath.Join(outputDir, name))
+	}
+
+	vtt := BuildThumbnailVTT(sheetNames, count, duration, opts.Interval, opts.Columns, opts.Rows, opts.ThumbWidth, thumbH)
+	if err := os.WriteFile(result.VttFile, []byte(vtt), 0644); err != nil {
+		return nil, err
+	}
+	log.Infof("generated %d sprite sheets with %d thumbnails, vtt:%s", sheetCount, count, result.VttFile)
+	return result, nil
+}
+
+// BuildThumbnailVTT 每个采样点一条 cue, 内容为 "雪碧图#xywh=x,y,w,h", 图片路径相对于 vtt 文件
+func BuildThumbnailVTT(sheetNames []string, count int, duration float64, interval float64, columns int, rows int, thumbW int, thumbH int) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	perSheet := columns * rows
+	for i := 0; i < count; i++ {
+		sheet := i / perSheet
+		if sheet >= len(sheetNames) {
+			break
+		}
+		pos := i % perSheet
+		start := float64(i) * interval
+		end := math.Min(float64(i+1)*interval, duration)
+		sb.WriteString(fmt.Sprintf("\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			FormatSeconds(start), FormatSeconds(end), sheetNames[sheet],
+			(pos%columns)*thumbW, (pos/columns)*thumbH, thumbW, thumbH))
+	}
+	return sb.String()
+}
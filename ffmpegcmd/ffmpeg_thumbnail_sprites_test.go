@@ -0,0 +1,37 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildThumbnailVTT(t *testing.T) {
+	// 11 秒视频, 每 2 秒一张, 2x2 的雪碧图: 6 张缩略图分布在两张雪碧图上
+	vtt := BuildThumbnailVTT([]string{"sprite_001.jpg", "sprite_002.jpg"}, 6, 11, 2, 2, 2, 160, 90)
+	want := `WEBVTT
+
+00:00:00.000 --> 00:00:02.000
+sprite_001.jpg#xywh=0,0,160,90
+
+00:00:02.000 --> 00:00:04.000
+sprite_001.jpg#xywh=160,0,160,90
+
+00:00:04.000 --> 00:00:06.000
+sprite_001.jpg#xywh=0,90,160,90
+
+00:00:06.000 --> 00:00:08.000
+sprite_001.jpg#xywh=160,90,160,90
+
+00:00:08.000 --> 00:00:10.000
+sprite_002.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:11.000
+sprite_002.jpg#xywh=160,0,160,90
+`
+	if vtt != want {
+		t.Errorf("BuildThumbnailVTT got:\n%s\nwant:\n%s", vtt, want)
+	}
+	if strings.Count(vtt, "-->") != 6 {
+		t.Errorf("expected 6 cues")
+	}
+}
@@ -59,17 +59,24 @@ func parseTime(ts string) float64 {
 }
 
 func TranscodeWithProgress(id string, in string, w int, h int, out string, duration float64, progressObj pub.ProgressCallback) error {
+	opts := &TranscodeOptions{
+		VideoCodec:   "libx264",
+		AudioCodec:   "aac",
+		Container:    "mp4",
+		Width:        w,
+		Height:       h,
+		FrameRate:    30,
+		GOP:          90,
+		AudioBitrate: 64,
+	}
+	return TranscodeWithOptions(id, in, out, opts, duration, progressObj)
+}
+
+// runFFmpegWithProgress 执行 ffmpeg, 解析 stderr 里的 time= 通过 progressObj 上报进度, task 用于进度消息
+func runFFmpegWithProgress(id string, task string, args []string, duration float64, progressObj pub.ProgressCallback) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	args := []string{
-		"-i", in,
-		"-c:v", "libx264",
-		"-vf", fmt.Sprintf("scale=%d:%d", w, h),
-		"-r", "30", "-g", "90",
-		"-c:a", "aac", "-ar", "48000", "-ac", "2", "-ab", "64k",
-		"-f", "mp4", "-y", out,
-	}
 	argsStr := strings.Join(args, " ")
 	log.Infof("Starting ffmpeg with args: %s", argsStr)
 
@@ -80,7 +87,7 @@ func TranscodeWithProgress(id string, in string, w int, h int, out string, durat
 		log.Errorf("无法获取ffmpeg stderr: %v", err)
 		progressObj.OnProgress(&pub.ProgressInfo{
 			Progress: 0,
-			Message:  "无法获取ffmpeg stderr, 转码失败",
+			Message:  fmt.Sprintf("无法获取ffmpeg stderr, %s失败", task),
 			Done:     true,
 		}, id)
 		return err
@@ -90,7 +97,7 @@ func TranscodeWithProgress(id string, in string, w int, h int, out string, durat
 		log.Errorf("无法启动ffmpeg: %v", err)
 		progressObj.OnProgress(&pub.ProgressInfo{
 			Progress: 0,
-			Message:  "无法启动ffmpeg, 转码失败",
+			Message:  fmt.Sprintf("无法启动ffmpeg, %s失败", task),
 			Done:     true,
 		}, id)
 		return err
@@ -99,19 +106,18 @@ func TranscodeWithProgress(id string, in string, w int, h int, out string, durat
 
 	progressObj.OnProgress(&pub.ProgressInfo{
 		Progress: 0,
-		Message:  "转码开始",
+		Message:  task + "开始",
 		Done:     false,
 	}, id)
 	reader := bufio.NewReader(stderr)
 	go func(cb pub.ProgressCallback, callId string) {
-		log.Infof("开始监控转码进度, callId: %s", callId)
+		log.Infof("开始监控%s进度, callId: %s", task, callId)
 		startMs := time.Now().UnixMilli()
 		for {
 			line, err := reader.ReadString('\r')
 			if err != nil {
 				break
 			}
-			// log.Infof("scanner line: %s", line)
 			m := timeRe.FindStringSubmatch(line)
 			if len(m) > 0 {
 				cur := parseTime(m[1] + ":" + m[2] + ":" + m[3])
@@ -120,7 +126,7 @@ func TranscodeWithProgress(id string, in string, w int, h int, out string, durat
 
 				cb.OnProgress(&pub.ProgressInfo{
 					Progress: float32(pct / 100),
-					Message:  fmt.Sprintf("转码中 %.1f%%, 耗时(ms): %d", pct, time.Now().UnixMilli()-startMs),
+					Message:  fmt.Sprintf("%s中 %.1f%%, 耗时(ms): %d", task, pct, time.Now().UnixMilli()-startMs),
 					Done:     false,
 				}, callId)
 			}
@@ -139,7 +145,7 @@ func TranscodeWithProgress(id string, in string, w int, h int, out string, durat
 	}
 	progressObj.OnProgress(&pub.ProgressInfo{
 		Progress: 1.0,
-		Message:  "转码完成",
+		Message:  task + "完成",
 		Done:     true,
 	}, id)
 	log.Infof("ffmpeg command finished successfully: ffmpeg %s", argsStr)
@@ -0,0 +1,176 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gollmagent/pub"
+)
+
+type TranscodeOptions struct {
+	VideoCodec   string  // libx264, libx265, libsvtav1, libaom-av1, libvpx-vp9
+	AudioCodec   string  // aac, libopus, libmp3lame
+	Container    string  // mp4, mkv, webm, mov
+	Width        int     // 输出宽度, 0 表示不缩放
+	Height       int     // 输出高度, 0 表示不缩放
+	FrameRate    float64 // 输出帧率, 0 表示保持源帧率
+	GOP          int     // 关键帧间隔(帧), 0 表示编码器默认
+	Crf          *int    // 质量参数, 越小质量越高, 0 是无损(x264/x265), nil 表示使用编码器对应的默认值
+	AudioBitrate int     // 音频码率 kbps, 默认128
+}
+
+var SupportedVideoCodecs = []string{"libx264", "libx265", "libsvtav1", "libaom-av1", "libvpx-vp9"}
+var SupportedAudioCodecs = []string{"aac", "libopus", "libmp3lame"}
+var SupportedContainers = []string{"mp4", "mkv", "webm", "mov"}
+
+// 常用别名到 ffmpeg 编码器名
+var codecAliases = map[string]string{
+	"h264": "libx264", "x264": "libx264",
+	"h265": "libx265", "hevc": "libx265", "x265": "libx265",
+	"av1": "libsvtav1", "svtav1": "libsvtav1", "aom": "libaom-av1",
+	"vp9":  "libvpx-vp9",
+	"opus": "libopus",
+	"mp3":  "libmp3lame",
+}
+
+// 容器支持的编码器
+var containerVideoCodecs = map[string][]string{
+	"mp4":  {"libx264", "libx265", "libsvtav1", "libaom-av1", "libvpx-vp9"},
+	"mkv":  {"libx264", "libx265", "libsvtav1", "libaom-av1", "libvpx-vp9"},
+	"webm": {"libsvtav1", "libaom-av1", "libvpx-vp9"},
+	"mov":  {"libx264", "libx265"},
+}
+
+var containerAudioCodecs = map[string][]string{
+	"mp4":  {"aac", "libopus", "libmp3lame"},
+	"mkv":  {"aac", "libopus", "libmp3lame"},
+	"webm": {"libopus"},
+	"mov":  {"aac", "libmp3lame"},
+}
+
+var containerFormats = map[string]string{
+	"mp4":  "mp4",
+	"mkv":  "matroska",
+	"webm": "webm",
+	"mov":  "mov",
+}
+
+// 各编码器的默认质量参数
+var videoCodecArgs = map[string][]string{
+	"libx264":    {"-crf", "23"},
+	"libx265":    {"-crf", "28"},
+	"libsvtav1":  {"-crf", "35", "-preset", "8"},
+	"libaom-av1": {"-crf", "32", "-b:v", "0", "-cpu-used", "6", "-row-mt", "1"},
+	"libvpx-vp9": {"-crf", "33", "-b:v", "0", "-row-mt", "1"},
+}
+
+// 各编码器 crf 的上限, x264/x265 是 51, av1/vp9 是 63
+var maxCrf = map[string]int{
+	"libx264": 51, "libx265": 51, "libsvtav1": 63, "libaom-av1": 63, "libvpx-vp9": 63,
+}
+
+// NormalizeCodecName 把 h265, opus 这类别名转换为 ffmpeg 编码器名
+func NormalizeCodecName(codec string) string {
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	if name, ok := codecAliases[codec]; ok {
+		return name
+	}
+	return codec
+}
+
+func (opts *TranscodeOptions) setDefaults() {
+	opts.VideoCodec = NormalizeCodecName(opts.VideoCodec)
+	opts.AudioCodec = NormalizeCodecName(opts.AudioCodec)
+	if opts.VideoCodec == "" {
+		opts.VideoCodec = "libx264"
+	}
+	if opts.AudioCodec == "" {
+		opts.AudioCodec = "aac"
+	}
+	if opts.Container == "" {
+		opts.Container = "mp4"
+	}
+	if opts.AudioBitrate <= 0 {
+		opts.AudioBitrate = 128
+	}
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTranscodeOptions 检查编码器与容器是否兼容, encoders 不为空时还检查本地 ffmpeg 是否带了对应编码器
+func ValidateTranscodeOptions(opts *TranscodeOptions, encoders map[string]string) error {
+	opts.setDefaults()
+	if !containsString(SupportedVideoCodecs, opts.VideoCodec) {
+		return fmt.Errorf("unsupported video codec: %s, supported: %v", opts.VideoCodec, SupportedVideoCodecs)
+	}
+	if !containsString(SupportedAudioCodecs, opts.AudioCodec) {
+		return fmt.Errorf("unsupported audio codec: %s, supported: %v", opts.AudioCodec, SupportedAudioCodecs)
+	}
+	videoCodecs, ok := containerVideoCodecs[opts.Container]
+	if !ok {
+		return fmt.Errorf("unsupported container: %s, supported: %v", opts.Container, SupportedContainers)
+	}
+	if !containsString(videoCodecs, opts.VideoCodec) {
+		return fmt.Errorf("container %s does not support video codec %s, supported: %v", opts.Container, opts.VideoCodec, videoCodecs)
+	}
+	if !containsString(containerAudioCodecs[opts.Container], opts.AudioCodec) {
+		return fmt.Errorf("container %s does not support audio codec %s, supported: %v",
+			opts.Container, opts.AudioCodec, containerAudioCodecs[opts.Container])
+	}
+	if opts.Crf != nil && (*opts.Crf < 0 || *opts.Crf > maxCrf[opts.VideoCodec]) {
+		return fmt.Errorf("invalid crf %d for %s, should be 0-%d", *opts.Crf, opts.VideoCodec, maxCrf[opts.VideoCodec])
+	}
+	if len(encoders) > 0 {
+		var missing []string
+		for _, codec := range []string{opts.VideoCodec, opts.AudioCodec} {
+			if _, ok := encoders[codec]; !ok {
+				missing = append(missing, codec)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return fmt.Errorf("encoders not available in local ffmpeg build: %s", strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// BuildTranscodeArgs 按选项生成转码的 ffmpeg 参数
+func BuildTranscodeArgs(in string, out string, opts *TranscodeOptions) []string {
+	opts.setDefaults()
+	args := []string{"-i", in, "-c:v", opts.VideoCodec}
+	codecArgs := append([]string{}, videoCodecArgs[opts.VideoCodec]...)
+	if opts.Crf != nil {
+		// 默认参数的第一项都是 -crf
+		codecArgs[1] = fmt.Sprintf("%d", *opts.Crf)
+	}
+	args = append(args, codecArgs...)
+	if opts.Width > 0 && opts.Height > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", opts.Width, opts.Height))
+	}
+	if opts.FrameRate > 0 {
+		args = append(args, "-r", fmt.Sprintf("%g", opts.FrameRate))
+	}
+	if opts.GOP > 0 {
+		args = append(args, "-g", fmt.Sprintf("%d", opts.GOP))
+	}
+	// Apple 设备只认 hvc1 标签的 H.265
+	if opts.VideoCodec == "libx265" && (opts.Container == "mp4" || opts.Container == "mov") {
+		args = append(args, "-tag:v", "hvc1")
+	}
+	args = append(args, "-c:a", opts.AudioCodec, "-ar", "48000", "-ac", "2", "-ab", fmt.Sprintf("%dk", opts.AudioBitrate))
+	return append(args, "-f", containerFormats[opts.Container], "-y", out)
+}
+
+// TranscodeWithOptions 按指定的编码器和容器转码, 进度通过 progressObj 上报
+func TranscodeWithOptions(id string, in string, out string, opts *TranscodeOptions, duration float64, progressObj pub.ProgressCallback) error {
+	return runFFmpegWithProgress(id, "转码", BuildTranscodeArgs(in, out, opts), duration, progressObj)
+}
@@ -0,0 +1,104 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEncodersOutput = `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libvpx-vp9           libvpx VP9 (codec vp9)
+ A....D aac                  AAC (Advanced Audio Coding)
+ A....D libopus              libopus Opus (codec opus)
+ S..... mov_text             3GPP Timed Text subtitle
+`
+
+func TestParseEncoderList(t *testing.T) {
+	encoders := ParseEncoderList(testEncodersOutput)
+	if len(encoders) != 5 {
+		t.Fatalf("expected 5 encoders, got %v", encoders)
+	}
+	if encoders["libx264"] != "V" || encoders["libopus"] != "A" || encoders["mov_text"] != "S" {
+		t.Errorf("unexpected encoder types: %v", encoders)
+	}
+	// 表头里的 V..... = Video 不能算作编码器
+	if _, ok := encoders["="]; ok {
+		t.Errorf("header line parsed as encoder")
+	}
+}
+
+func TestValidateTranscodeOptions(t *testing.T) {
+	encoders := ParseEncoderList(testEncodersOutput)
+	cases := []struct {
+		opts TranscodeOptions
+		ok   bool
+	}{
+		{TranscodeOptions{}, true},
+		{TranscodeOptions{VideoCodec: "vp9", AudioCodec: "opus", Container: "webm"}, true},
+		{TranscodeOptions{VideoCodec: "libx264", Container: "webm"}, false},
+		{TranscodeOptions{VideoCodec: "vp9", AudioCodec: "aac", Container: "webm"}, false},
+		{TranscodeOptions{VideoCodec: "vp9", Container: "mov"}, false},
+		{TranscodeOptions{Container: "avi"}, false},
+		// 容器支持但本地 ffmpeg 没有 libx265
+		{TranscodeOptions{VideoCodec: "hevc", Container: "mkv"}, false},
+	}
+	for _, c := range cases {
+		opts := c.opts
+		err := ValidateTranscodeOptions(&opts, encoders)
+		if (err == nil) != c.ok {
+			t.Errorf("ValidateTranscodeOptions(%+v) err=%v, want ok=%v", c.opts, err, c.ok)
+		}
+	}
+	// 拿不到编码器列表时只检查兼容矩阵
+	opts := TranscodeOptions{VideoCodec: "h265", Container: "mkv"}
+	if err := ValidateTranscodeOptions(&opts, nil); err != nil {
+		t.Errorf("unexpected error without encoder list: %v", err)
+	}
+}
+
+func TestBuildTranscodeArgs(t *testing.T) {
+	opts := &TranscodeOptions{Width: 854, Height: 480, FrameRate: 30, GOP: 90, AudioBitrate: 64}
+	args := strings.Join(BuildTranscodeArgs("in.mov", "out.mp4", opts), " ")
+	want := "-i in.mov -c:v libx264 -crf 23 -vf scale=854:480 -r 30 -g 90 -c:a aac -ar 48000 -ac 2 -ab 64k -f mp4 -y out.mp4"
+	if args != want {
+		t.Errorf("default args got:\n%s\nwant:\n%s", args, want)
+	}
+
+	opts = &TranscodeOptions{VideoCodec: "h265", Container: "mp4"}
+	args = strings.Join(BuildTranscodeArgs("in.mov", "out.mp4", opts), " ")
+	if !strings.Contains(args, "-tag:v hvc1") || strings.Contains(args, "-r ") {
+		t.Errorf("unexpected x265 args: %s", args)
+	}
+
+	opts = &TranscodeOptions{VideoCodec: "vp9", AudioCodec: "opus", Container: "webm"}
+	args = strings.Join(BuildTranscodeArgs("in.mov", "out.webm", opts), " ")
+	if !strings.Contains(args, "-c:v libvpx-vp9 -crf 33 -b:v 0") || !strings.HasSuffix(args, "-f webm -y out.webm") {
+		t.Errorf("unexpected vp9 args: %s", args)
+	}
+
+	crf := 40
+	opts = &TranscodeOptions{VideoCodec: "vp9", AudioCodec: "opus", Container: "webm", Crf: &crf}
+	args = strings.Join(BuildTranscodeArgs("in.mov", "out.webm", opts), " ")
+	if !strings.Contains(args, "-c:v libvpx-vp9 -crf 40 -b:v 0") || videoCodecArgs["libvpx-vp9"][1] != "33" {
+		t.Errorf("crf should override the default without changing it: %s", args)
+	}
+	// crf 0 是 x264 的无损模式, 不能被当成未设置
+	lossless := 0
+	opts = &TranscodeOptions{Crf: &lossless}
+	if err := ValidateTranscodeOptions(opts, nil); err != nil {
+		t.Errorf("crf 0 should be valid: %v", err)
+	}
+	args = strings.Join(BuildTranscodeArgs("in.mov", "out.mp4", opts), " ")
+	if !strings.Contains(args, "-c:v libx264 -crf 0 ") {
+		t.Errorf("crf 0 should reach the args: %s", args)
+	}
+	crf = 60
+	if err := ValidateTranscodeOptions(&TranscodeOptions{Crf: &crf}, nil); err == nil {
+		t.Errorf("expected error for crf 60 with libx264")
+	}
+}
@@ -0,0 +1,274 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+)
+
+// 小于这个长度的片段直接丢弃, 避免产生只有几帧的碎片
+const kMinRangeDuration = 0.05
+
+type TimeRange struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+func (r TimeRange) Duration() float64 {
+	return r.End - r.Start
+}
+
+func TotalDuration(ranges []TimeRange) float64 {
+	var total float64
+	for _, r := range ranges {
+		total += r.Duration()
+	}
+	return total
+}
+
+// NormalizeRanges 根据媒体时长校验时间段: 结束时间超出时长的截断到时长, 然后排序并合并重叠的时间段
+func NormalizeRanges(ranges []TimeRange, duration float64) ([]TimeRange, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no time range provided")
+	}
+	var valid []TimeRange
+	for _, r := range ranges {
+		if r.Start < 0 {
+			return nil, fmt.Errorf("invalid range %.3f-%.3f: start is negative", r.Start, r.End)
+		}
+		if duration > 0 && r.Start >= duration {
+			return nil, fmt.Errorf("invalid range %.3f-%.3f: start is beyond media duration %.3f", r.Start, r.End, duration)
+		}
+		if duration > 0 && r.End > duration {
+			r.End = duration
+		}
+		if r.End <= r.Start {
+			return nil, fmt.Errorf("invalid range %.3f-%.3f: end must be after start", r.Start, r.End)
+		}
+		valid = append(valid, r)
+	}
+	sort.Slice(valid, func(i, j int) bool {
+		return valid[i].Start < valid[j].Start
+	})
+
+	merged := []TimeRange{valid[0]}
+	for _, r := range valid[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// InvertRanges 返回 [0, duration] 中不在 ranges 里的部分, ranges 需要先经过 NormalizeRanges
+func InvertRanges(ranges []TimeRange, duration float64) []TimeRange {
+	var ret []TimeRange
+	cursor := 0.0
+	for _, r := range ranges {
+		if r.Start-cursor >= kMinRangeDuration {
+			ret = append(ret, TimeRange{Start: cursor, End: r.Start})
+		}
+		if r.End > cursor {
+			cursor = r.End
+		}
+	}
+	if duration-cursor >= kMinRangeDuration {
+		ret = append(ret, TimeRange{Start: cursor, End: duration})
+	}
+	return ret
+}
+
+// SnapToKeyframes 把每个时间段的开始时间移动到它之前最近的关键帧, 流拷贝只能从关键帧开始
+func SnapToKeyframes(ranges []TimeRange, keyframes []float64) []TimeRange {
+	if len(keyframes) == 0 {
+		return ranges
+	}
+	ret := make([]TimeRange, 0, len(ranges))
+	for _, r := range ranges {
+		// 找到最后一个 <= start 的关键帧, 加一点容差避免浮点误差跳到前一个关键帧
+		idx := sort.SearchFloat64s(keyframes, r.Start+0.001) - 1
+		if idx >= 0 {
+			r.Start = keyframes[idx]
+		} else {
+			r.Start = 0
+		}
+		if len(ret) > 0 && r.Start < ret[len(ret)-1].End {
+			// 前移之后和上一个时间段重叠, 合并
+			if r.End > ret[len(ret)-1].End {
+				ret[len(ret)-1].End = r.End
+			}
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// AudioEncoderForFile 按输出文件扩展名选择音频编码器
+func AudioEncoderForFile(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mp3":
+		return "libmp3lame"
+	case ".wav":
+		return "pcm_s16le"
+	case ".flac":
+		return "flac"
+	case ".opus", ".ogg", ".webm":
+		return "libopus"
+	default:
+		return "aac"
+	}
+}
+
+type TrimOptions struct {
+	Ranges   []TimeRange // 需要保留的时间段, 需要先经过 NormalizeRanges
+	Accurate bool        // true: 重新编码, 帧级精确; false: 流拷贝, 从关键帧开始
+	HasVideo bool
+	HasAudio bool
+}
+
+// TrimMedia 保留 opts.Ranges 指定的时间段, 多个时间段会按顺序拼接到一个输出文件
+func TrimMedia(inputFile string, outputFile string, opts *TrimOptions) error {
+	if len(opts.Ranges) == 0 {
+		return fmt.Errorf("no time range to keep")
+	}
+	if !opts.HasVideo && !opts.HasAudio {
+		return fmt.Errorf("input has neither video nor audio stream")
+	}
+	if opts.Accurate {
+		return trimAccurate(inputFile, outputFile, opts)
+	}
+	if len(opts.Ranges) == 1 {
+		return trimCopySegment(inputFile, opts.Ranges[0], outputFile)
+	}
+
+	// 多个时间段: 先分别流拷贝出片段, 再用 concat demuxer 无损拼接
+	tmpDir, err := os.MkdirTemp("", "gollmagent_trim_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var segments []string
+	for i, r := range opts.Ranges {
+		segment := filepath.Join(tmpDir, fmt.Sprintf("segment_%03d%s", i, filepath.Ext(outputFile)))
+		if err := trimCopySegment(inputFile, r, segment); err != nil {
+			return err
+		}
+		segments = append(segments, segment)
+	}
+	return concatDemuxerCopy(segments, outputFile, tmpDir)
+}
+
+func trimCopySegment(inputFile string, r TimeRange, outputFile string) error {
+	args := []string{
+		"-ss", fmt.Sprintf("%.3f", r.Start),
+		"-i", inputFile,
+		"-t", fmt.Sprintf("%.3f", r.Duration()),
+		"-map", "0:v?", "-map", "0:a?",
+		"-c", "copy",
+		"-avoid_negative_ts", "make_zero",
+		"-y", outputFile,
+	}
+	_, err := runFFmpeg(args)
+	return err
+}
+
+// concatDemuxerCopy 用 concat demuxer 流拷贝拼接编码参数一致的文件
+func concatDemuxerCopy(files []string, outputFile string, workDir string) error {
+	var list strings.Builder
+	for _, file := range files {
+		absFile, err := filepath.Abs(file)
+		if err != nil {
+			absFile = file
+		}
+		list.WriteString(fmt.Sprintf("file '%s'\n", strings.ReplaceAll(absFile, "'", `'\''`)))
+	}
+	listFile := filepath.Join(workDir, "concat_list.txt")
+	if err := os.WriteFile(listFile, []byte(list.String()), 0644); err != nil {
+		return err
+	}
+	args := []string{
+		"-f", "concat", "-safe", "0",
+		"-i", listFile,
+		"-c", "copy",
+		"-y", outputFile,
+	}
+	_, err := runFFmpeg(args)
+	return err
+}
+
+func trimAccurate(inputFile string, outputFile string, opts *TrimOptions) error {
+	var args []string
+	if len(opts.Ranges) == 1 {
+		// 单个时间段: 重新编码时 -ss 放在 -i 之前也是精确的, 而且不用解码前面的内容
+		r := opts.Ranges[0]
+		args = append(args,
+			"-ss", fmt.Sprintf("%.3f", r.Start),
+			"-i", inputFile,
+			"-t", fmt.Sprintf("%.3f", r.Duration()),
+			"-map", "0:v?", "-map", "0:a?",
+		)
+	} else {
+		filter, maps := buildTrimFilterComplex(opts.Ranges, opts.HasVideo, opts.HasAudio)
+		args = append(args, "-i", inputFile, "-filter_complex", filter)
+		for _, m := range maps {
+			args = append(args, "-map", m)
+		}
+	}
+	if opts.HasVideo {
+		args = append(args, "-c:v", "libx264", "-crf", "18", "-preset", "fast", "-pix_fmt", "yuv420p")
+	}
+	if opts.HasAudio {
+		args = append(args, "-c:a", AudioEncoderForFile(outputFile))
+		if AudioEncoderForFile(outputFile) == "aac" {
+			args = append(args, "-b:a", "192k")
+		}
+	}
+	args = append(args, "-y", outputFile)
+
+	log.Infof("Trimming %s, ranges:%+v, output:%s", inputFile, opts.Ranges, outputFile)
+	_, err := runFFmpeg(args)
+	return err
+}
+
+// buildTrimFilterComplex 用 trim/atrim 截取每个时间段, 再 concat 拼接
+func buildTrimFilterComplex(ranges []TimeRange, hasVideo bool, hasAudio bool) (string, []string) {
+	var filters []string
+	var concatInputs []string
+	for i, r := range ranges {
+		if hasVideo {
+			filters = append(filters, fmt.Sprintf("[0:v]trim=start=%.3f:end=%.3f,setpts=PTS-STARTPTS[v%d]", r.Start, r.End, i))
+			concatInputs = append(concatInputs, fmt.Sprintf("[v%d]", i))
+		}
+		if hasAudio {
+			filters = append(filters, fmt.Sprintf("[0:a]atrim=start=%.3f:end=%.3f,asetpts=PTS-STARTPTS[a%d]", r.Start, r.End, i))
+			concatInputs = append(concatInputs, fmt.Sprintf("[a%d]", i))
+		}
+	}
+
+	var maps []string
+	outputs := ""
+	v, a := 0, 0
+	if hasVideo {
+		v = 1
+		outputs += "[outv]"
+		maps = append(maps, "[outv]")
+	}
+	if hasAudio {
+		a = 1
+		outputs += "[outa]"
+		maps = append(maps, "[outa]")
+	}
+	concatFilter := fmt.Sprintf("%sconcat=n=%d:v=%d:a=%d%s", strings.Join(concatInputs, ""), len(ranges), v, a, outputs)
+	return strings.Join(filters, ";") + ";" + concatFilter, maps
+}
@@ -0,0 +1,92 @@
+package ffmpegcmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+)
+
+func TestParseTimeToSeconds(t *testing.T) {
+	cases := map[string]float64{
+		"90":           90,
+		"12.5":         12.5,
+		"01:30":        90,
+		"00:01:30.250": 90.25,
+		"1:02:03":      3723,
+	}
+	for input, want := range cases {
+		got, err := ParseTimeToSeconds(input)
+		if err != nil || got != want {
+			t.Errorf("ParseTimeToSeconds(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "abc", "00:61:00", "1:2:3:4", "-5", "NaN", "inf", "+Inf", "Infinity", "00:00:NaN", "1:inf"} {
+		if _, err := ParseTimeToSeconds(input); err == nil {
+			t.Errorf("ParseTimeToSeconds(%q) expected error", input)
+		}
+	}
+	if s := FormatSeconds(3723.5); s != "01:02:03.500" {
+		t.Errorf("FormatSeconds got %s", s)
+	}
+}
+
+func TestNormalizeAndInvertRanges(t *testing.T) {
+	ranges, err := NormalizeRanges([]TimeRange{{50, 80}, {10, 20}, {15, 30}, {90, 200}}, 100)
+	if err != nil {
+		t.Fatalf("NormalizeRanges failed: %v", err)
+	}
+	want := []TimeRange{{10, 30}, {50, 80}, {90, 100}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("NormalizeRanges got %+v, want %+v", ranges, want)
+	}
+
+	keep := InvertRanges(ranges, 100)
+	wantKeep := []TimeRange{{0, 10}, {30, 50}, {80, 90}}
+	if !reflect.DeepEqual(keep, wantKeep) {
+		t.Errorf("InvertRanges got %+v, want %+v", keep, wantKeep)
+	}
+	if total := TotalDuration(keep); total != 40 {
+		t.Errorf("TotalDuration got %v", total)
+	}
+
+	if _, err := NormalizeRanges([]TimeRange{{120, 130}}, 100); err == nil {
+		t.Errorf("expected error for range beyond duration")
+	}
+	if _, err := NormalizeRanges([]TimeRange{{20, 10}}, 100); err == nil {
+		t.Errorf("expected error for reversed range")
+	}
+}
+
+func TestSnapToKeyframes(t *testing.T) {
+	keyframes := ffprobe.ParseKeyframeTimes("0.000000,K__\n1.001000,___\n4.000000,K__\n8.000000,K_\n")
+	if !reflect.DeepEqual(keyframes, []float64{0, 4, 8}) {
+		t.Fatalf("ParseKeyframeTimes got %v", keyframes)
+	}
+	got := SnapToKeyframes([]TimeRange{{5, 6}, {7, 12}, {8, 9.5}}, keyframes)
+	want := []TimeRange{{4, 12}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SnapToKeyframes got %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildTrimFilterComplex(t *testing.T) {
+	filter, maps := buildTrimFilterComplex([]TimeRange{{0, 10}, {20, 25.5}}, true, true)
+	wantFilter := "[0:v]trim=start=0.000:end=10.000,setpts=PTS-STARTPTS[v0];" +
+		"[0:a]atrim=start=0.000:end=10.000,asetpts=PTS-STARTPTS[a0];" +
+		"[0:v]trim=start=20.000:end=25.500,setpts=PTS-STARTPTS[v1];" +
+		"[0:a]atrim=start=20.000:end=25.500,asetpts=PTS-STARTPTS[a1];" +
+		"[v0][a0][v1][a1]concat=n=2:v=1:a=1[outv][outa]"
+	if filter != wantFilter {
+		t.Errorf("filter got %s\nwant %s", filter, wantFilter)
+	}
+	if !reflect.DeepEqual(maps, []string{"[outv]", "[outa]"}) {
+		t.Errorf("maps got %v", maps)
+	}
+
+	filter, maps = buildTrimFilterComplex([]TimeRange{{0, 1}, {2, 3}}, false, true)
+	if filter != "[0:a]atrim=start=0.000:end=1.000,asetpts=PTS-STARTPTS[a0];[0:a]atrim=start=2.000:end=3.000,asetpts=PTS-STARTPTS[a1];[a0][a1]concat=n=2:v=0:a=1[outa]" ||
+		!reflect.DeepEqual(maps, []string{"[outa]"}) {
+		t.Errorf("audio only filter got %s, maps %v", filter, maps)
+	}
+}
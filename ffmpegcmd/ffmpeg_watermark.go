@@ -0,0 +1,282 @@
+package ffmpegcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 水印距离画面边缘的默认像素
+const kDefaultWatermarkMargin = 8
+
+var SupportedWatermarkPositions = []string{
+	"top-left", "top", "top-right", "left", "center", "right", "bottom-left", "bottom", "bottom-right",
+}
+
+var SupportedWatermarkMotions = []string{"none", "scroll", "bounce"}
+
+type WatermarkOptions struct {
+	Position string  // 命名位置(见 SupportedWatermarkPositions) 或 "x%,y%" 百分比位置, 默认 top-right
+	Margin   int     // 距离画面边缘的像素, 默认 8, 负数表示 0
+	Scale    float64 // 图片水印宽度占视频宽度的百分比(0-100), 0 表示原始大小
+	Opacity  float64 // 不透明度(0-1], 默认 1
+	Start    float64 // 开始显示的时间(秒)
+	End      float64 // 结束显示的时间(秒), 0 表示到视频结尾
+	FadeIn   float64 // 淡入时长(秒)
+	FadeOut  float64 // 淡出时长(秒)
+	Motion   string  // none 固定位置, scroll 从右向左滚动, bounce 在画面内反弹
+	Speed    float64 // 移动速度(像素/秒), 默认 100
+
+	// 以下只用于文字水印
+	FontSize    int    // 字号, 默认 24
+	Color       string // 文字颜色, 颜色名或 #RRGGBB[AA], rgba(r,g,b,a), 默认 white
+	FontFile    string // 字体文件, 优先于 Font
+	Font        string // 字体名(fontconfig), 都没指定且文字里有 CJK 字符时自动查找 CJK 字体
+	BorderWidth int    // 描边宽度, 0 表示不描边
+	BorderColor string // 描边颜色, 默认 black
+	ShadowX     int    // 阴影偏移, 都为 0 表示没有阴影
+	ShadowY     int
+	ShadowColor string // 阴影颜色, 默认 black@0.6
+	Box         bool   // 文字背景框
+	BoxColor    string // 背景框颜色, 默认 black@0.5
+	BoxPadding  int    // 背景框留白, 默认 8
+}
+
+func (opts *WatermarkOptions) setDefaults(duration float64) {
+	if opts.Position == "" {
+		opts.Position = "top-right"
+	}
+	if opts.Margin < 0 {
+		opts.Margin = 0
+	} else if opts.Margin == 0 {
+		opts.Margin = kDefaultWatermarkMargin
+	}
+	if opts.Opacity <= 0 || opts.Opacity > 1 {
+		opts.Opacity = 1
+	}
+	if opts.End <= 0 || (duration > 0 && opts.End > duration) {
+		opts.End = duration
+	}
+	if opts.Motion == "" {
+		opts.Motion = "none"
+	}
+	if opts.Speed <= 0 {
+		opts.Speed = 100
+	}
+	if opts.FontSize <= 0 {
+		opts.FontSize = 24
+	}
+	if opts.Color == "" {
+		opts.Color = "white"
+	}
+	if opts.BorderColor == "" {
+		opts.BorderColor = "black"
+	}
+	if opts.ShadowColor == "" {
+		opts.ShadowColor = "black@0.6"
+	}
+	if opts.BoxColor == "" {
+		opts.BoxColor = "black@0.5"
+	}
+	if opts.BoxPadding <= 0 {
+		opts.BoxPadding = 8
+	}
+}
+
+func (opts *WatermarkOptions) validate() error {
+	if opts.Scale < 0 || opts.Scale > 100 {
+		return fmt.Errorf("invalid scale: %g, should be a percentage of the video width in (0, 100]", opts.Scale)
+	}
+	if opts.Start < 0 || (opts.End > 0 && opts.Start >= opts.End) {
+		return fmt.Errorf("invalid time range: %g - %g", opts.Start, opts.End)
+	}
+	if opts.FadeIn < 0 || opts.FadeOut < 0 {
+		return fmt.Errorf("fade duration can not be negative")
+	}
+	if opts.End > 0 && opts.FadeIn+opts.FadeOut > opts.End-opts.Start {
+		return fmt.Errorf("fade in %gs + fade out %gs is longer than the display time %gs", opts.FadeIn, opts.FadeOut, opts.End-opts.Start)
+	}
+	if opts.FadeOut > 0 && opts.End <= 0 {
+		return fmt.Errorf("fade out needs the end time or the video duration")
+	}
+	for _, m := range SupportedWatermarkMotions {
+		if m == opts.Motion {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported motion: %s, should be one of %s", opts.Motion, strings.Join(SupportedWatermarkMotions, ", "))
+}
+
+// parsePercentPosition 解析 "x%,y%" 形式的位置, 返回 0-1 的比例
+func parsePercentPosition(position string) (float64, float64, bool) {
+	xs, ys, ok := strings.Cut(position, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	parse := func(s string) (float64, bool) {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+		return v / 100, err == nil && v >= 0 && v <= 100
+	}
+	x, okX := parse(xs)
+	y, okY := parse(ys)
+	return x, y, okX && okY
+}
+
+// watermarkPosition 返回水印左上角坐标的表达式. W/H 是视频宽高, objW/objH 是水印宽高的变量名,
+// overlay 用 w/h, drawtext 用 text_w/text_h. 百分比位置是在去掉边距后的可移动范围内的比例
+func watermarkPosition(opts *WatermarkOptions, objW, objH string) (string, string, error) {
+	m := opts.Margin
+	freeW := fmt.Sprintf("(W-%s-%d)", objW, 2*m)
+	freeH := fmt.Sprintf("(H-%s-%d)", objH, 2*m)
+
+	var x, y string
+	if px, py, ok := parsePercentPosition(opts.Position); ok {
+		x = fmt.Sprintf("%d+%s*%g", m, freeW, px)
+		y = fmt.Sprintf("%d+%s*%g", m, freeH, py)
+	} else {
+		horizontal := map[string]string{
+			"left":   strconv.Itoa(m),
+			"center": fmt.Sprintf("(W-%s)/2", objW),
+			"right":  fmt.Sprintf("W-%s-%d", objW, m),
+		}
+		vertical := map[string]string{
+			"top":    strconv.Itoa(m),
+			"center": fmt.Sprintf("(H-%s)/2", objH),
+			"bottom": fmt.Sprintf("H-%s-%d", objH, m),
+		}
+		var h, v string
+		switch opts.Position {
+		case "center":
+			h, v = "center", "center"
+		case "top", "bottom":
+			h, v = "center", opts.Position
+		case "left", "right":
+			h, v = opts.Position, "center"
+		default:
+			var ok bool
+			v, h, ok = strings.Cut(opts.Position, "-")
+			if !ok || horizontal[h] == "" || vertical[v] == "" || h == "center" || v == "center" {
+				return "", "", fmt.Errorf("invalid position: %s, should be one of %s or a percentage like 30%%,70%%",
+					opts.Position, strings.Join(SupportedWatermarkPositions, ", "))
+			}
+		}
+		x, y = horizontal[h], vertical[v]
+	}
+
+	// 移动的时间从开始显示算起
+	t := "t"
+	if opts.Start > 0 {
+		t = fmt.Sprintf("(t-%g)", opts.Start)
+	}
+	switch opts.Motion {
+	case "scroll":
+		// 从右边进入, 向左移出后重新开始, 纵向位置不变
+		x = fmt.Sprintf("W-mod(%s*%g,W+%s)", t, opts.Speed, objW)
+	case "bounce":
+		// 三角波: 在边距内来回移动, 纵向速度稍慢, 形成斜向反弹
+		x = fmt.Sprintf("%d+%s-abs(mod(%s*%g,2*%s)-%s)", m, freeW, t, opts.Speed, freeW, freeW)
+		y = fmt.Sprintf("%d+%s-abs(mod(%s*%g,2*%s)-%s)", m, freeH, t, opts.Speed*0.75, freeH, freeH)
+	}
+	return x, y, nil
+}
+
+// watermarkEnable 返回 enable 表达式, 一直显示时返回空
+func watermarkEnable(opts *WatermarkOptions, duration float64) string {
+	if opts.Start <= 0 && (opts.End <= 0 || opts.End >= duration) {
+		return ""
+	}
+	if opts.End <= 0 {
+		return fmt.Sprintf("gte(t,%g)", opts.Start)
+	}
+	return fmt.Sprintf("between(t,%g,%g)", opts.Start, opts.End)
+}
+
+// watermarkAlpha 返回文字水印的透明度表达式, 包括淡入淡出
+func watermarkAlpha(opts *WatermarkOptions) string {
+	alpha := strconv.FormatFloat(opts.Opacity, 'f', -1, 64)
+	if opts.FadeIn > 0 {
+		alpha += fmt.Sprintf("*min(1,max(0,(t-%g)/%g))", opts.Start, opts.FadeIn)
+	}
+	if opts.FadeOut > 0 {
+		alpha += fmt.Sprintf("*min(1,max(0,(%g-t)/%g))", opts.End, opts.FadeOut)
+	}
+	return alpha
+}
+
+// BuildImageWatermarkArgs 生成图片水印的 ffmpeg 参数. videoWidth/duration 用于按比例缩放和计算显示时间
+func BuildImageWatermarkArgs(inputVideo, watermarkImage, outputVideo string, videoWidth int, duration float64, opts *WatermarkOptions) ([]string, error) {
+	opts.setDefaults(duration)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	x, y, err := watermarkPosition(opts, "w", "h")
+	if err != nil {
+		return nil, err
+	}
+
+	wm := []string{"format=rgba"}
+	if opts.Scale > 0 {
+		if videoWidth <= 0 {
+			return nil, fmt.Errorf("unknown video width, can not scale the watermark")
+		}
+		wm = append(wm, fmt.Sprintf("scale=%d:-1", even(float64(videoWidth)*opts.Scale/100)))
+	}
+	if opts.Opacity < 1 {
+		wm = append(wm, fmt.Sprintf("colorchannelmixer=aa=%g", opts.Opacity))
+	}
+	if opts.FadeIn > 0 {
+		wm = append(wm, fmt.Sprintf("fade=t=in:st=%g:d=%g:alpha=1", opts.Start, opts.FadeIn))
+	}
+	if opts.FadeOut > 0 {
+		wm = append(wm, fmt.Sprintf("fade=t=out:st=%g:d=%g:alpha=1", opts.End-opts.FadeOut, opts.FadeOut))
+	}
+
+	overlay := fmt.Sprintf("overlay=x='%s':y='%s'", x, y)
+	if enable := watermarkEnable(opts, duration); enable != "" {
+		overlay += fmt.Sprintf(":enable='%s'", enable)
+	}
+
+	args := []string{"-i", inputVideo}
+	// 淡入淡出的 fade 滤镜作用在水印流上, 需要每帧都有新的水印帧, 图片循环输入, 由 shortest 截断.
+	// 移动不需要循环: overlay 每帧都会重新计算 x/y, 单张图片也能移动
+	animated := opts.FadeIn > 0 || opts.FadeOut > 0
+	if animated {
+		args = append(args, "-loop", "1")
+		overlay += ":shortest=1"
+	}
+	args = append(args, "-i", watermarkImage,
+		"-filter_complex", fmt.Sprintf("[1:v]%s[wm];[0:v][wm]%s", strings.Join(wm, ","), overlay),
+		"-codec:a", "copy",
+		"-y", outputVideo)
+	return args, nil
+}
+
+// BuildTextWatermarkArgs 生成文字水印的 ffmpeg 参数. 文字从 textFile 读取且不做 % 展开, 用 text_w/text_h 计算位置
+func BuildTextWatermarkArgs(inputVideo, textFile, outputVideo string, duration float64, opts *WatermarkOptions) ([]string, error) {
+	opts.setDefaults(duration)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	x, y, err := watermarkPosition(opts, "text_w", "text_h")
+	if err != nil {
+		return nil, err
+	}
+	style, err := buildDrawtextStyle(opts)
+	if err != nil {
+		return nil, err
+	}
+	items := append([]string{"textfile=" + EscapeFilterValue(textFile), "expansion=none"}, style...)
+	items = append(items, fmt.Sprintf("x='%s'", x), fmt.Sprintf("y='%s'", y))
+	if alpha := watermarkAlpha(opts); alpha != "1" {
+		items = append(items, fmt.Sprintf("alpha='%s'", alpha))
+	}
+	if enable := watermarkEnable(opts, duration); enable != "" {
+		items = append(items, fmt.Sprintf("enable='%s'", enable))
+	}
+	return []string{
+		"-i", inputVideo,
+		"-vf", "drawtext=" + strings.Join(items, ":"),
+		"-codec:a", "copy",
+		"-y", outputVideo,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package ffmpegcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWatermarkPosition(t *testing.T) {
+	cases := []struct {
+		position string
+		x, y     string
+	}{
+		{"top-left", "8", "8"},
+		{"bottom-right", "W-w-8", "H-h-8"},
+		{"center", "(W-w)/2", "(H-h)/2"},
+		{"bottom", "(W-w)/2", "H-h-8"},
+		{"25%,100%", "8+(W-w-16)*0.25", "8+(H-h-16)*1"},
+	}
+	for _, c := range cases {
+		opts := &WatermarkOptions{Position: c.position}
+		opts.setDefaults(10)
+		x, y, err := watermarkPosition(opts, "w", "h")
+		if err != nil || x != c.x || y != c.y {
+			t.Errorf("watermarkPosition(%s) = %s, %s, %v, want %s, %s", c.position, x, y, err, c.x, c.y)
+		}
+	}
+	for _, position := range []string{"middle", "center-left", "120%,0%", "50%"} {
+		opts := &WatermarkOptions{Position: position}
+		opts.setDefaults(10)
+		if _, _, err := watermarkPosition(opts, "w", "h"); err == nil {
+			t.Errorf("expected error for position %s", position)
+		}
+	}
+
+	opts := &WatermarkOptions{Position: "bottom-left", Motion: "scroll", Speed: 50, Start: 2}
+	opts.setDefaults(10)
+	x, y, _ := watermarkPosition(opts, "text_w", "text_h")
+	if x != "W-mod((t-2)*50,W+text_w)" || y != "H-text_h-8" {
+		t.Errorf("unexpected scroll position: %s, %s", x, y)
+	}
+}
+
+func TestBuildImageWatermarkArgs(t *testing.T) {
+	opts := &WatermarkOptions{Position: "top-right", Scale: 10, Opacity: 0.5, Start: 1, End: 8, FadeIn: 1, FadeOut: 2}
+	args, err := BuildImageWatermarkArgs("in.mp4", "logo.png", "out.mp4", 1920, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := strings.Join(args, " ")
+	for _, want := range []string{
+		"-loop 1 -i logo.png",
+		"[1:v]format=rgba,scale=192:-1,colorchannelmixer=aa=0.5,fade=t=in:st=1:d=1:alpha=1,fade=t=out:st=6:d=2:alpha=1[wm]",
+		"[0:v][wm]overlay=x='W-w-8':y='8':enable='between(t,1,8)':shortest=1",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("missing %q in %s", want, cmd)
+		}
+	}
+
+	// 原始大小, 一直显示
+	args, err = BuildImageWatermarkArgs("in.mp4", "logo.png", "out.mp4", 1920, 10, &WatermarkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd := strings.Join(args, " "); !strings.Contains(cmd, "[1:v]format=rgba[wm];[0:v][wm]overlay=x='W-w-8':y='8' ") {
+		t.Errorf("unexpected args: %s", cmd)
+	}
+
+	if _, err := BuildImageWatermarkArgs("in.mp4", "logo.png", "out.mp4", 1920, 10, &WatermarkOptions{FadeIn: 6, FadeOut: 6}); err == nil {
+		t.Errorf("expected error when fades are longer than the display time")
+	}
+	if _, err := BuildImageWatermarkArgs("in.mp4", "logo.png", "out.mp4", 1920, 10, &WatermarkOptions{Motion: "spin"}); err == nil {
+		t.Errorf("expected error for unsupported motion")
+	}
+}
+
+func TestBuildTextWatermarkArgs(t *testing.T) {
+	opts := &WatermarkOptions{Position: "bottom", Opacity: 0.8, FadeIn: 1, FontSize: 36, Color: "#ff000080",
+		FontFile: "/fonts/a:b.ttf", BorderWidth: 2, Box: true}
+	args, err := BuildTextWatermarkArgs("in.mp4", "/tmp/it's.txt", "out.mp4", 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `drawtext=textfile=/tmp/it\\\'s.txt:expansion=none:fontfile=/fonts/a\\:b.ttf:fontcolor=0xFF0000@0.502:fontsize=36:` +
+		`borderw=2:bordercolor=0x000000@1:box=1:boxcolor=0x000000@0.5:boxborderw=8:` +
+		`x='(W-text_w)/2':y='H-text_h-8':alpha='0.8*min(1,max(0,(t-0)/1))'`
+	if args[3] != want {
+		t.Errorf("drawtext = %s, want %s", args[3], want)
+	}
+
+	if _, err := BuildTextWatermarkArgs("in.mp4", "a.txt", "out.mp4", 10, &WatermarkOptions{Color: "notacolor"}); err == nil {
+		t.Errorf("expected error for invalid color")
+	}
+}
+
+func TestParseTextColor(t *testing.T) {
+	cases := map[string]string{
+		"white":                  "0xFFFFFF@1",
+		"DarkYellow@0.5":         "0x808000@0.5",
+		"#0f0":                   "0x00FF00@1",
+		"#112233":                "0x112233@1",
+		"0x112233ff":             "0x112233@1",
+		"rgba(255, 128, 0, .25)": "0xFF8000@0.25",
+		"rgb(1,2,3)":             "0x010203@1",
+	}
+	for in, want := range cases {
+		if got, err := ParseTextColor(in); err != nil || got != want {
+			t.Errorf("ParseTextColor(%s) = %s, %v, want %s", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "#12345", "rgb(256,0,0)", "white@2", "white:x=1", "red'"} {
+		if _, err := ParseTextColor(in); err == nil {
+			t.Errorf("expected error for color %q", in)
+		}
+	}
+	names := SupportedTextColor()
+	if len(names) != len(textColorNames) || names[0] != "black" {
+		t.Errorf("SupportedTextColor should list every color name in order, got %v", names)
+	}
+	for _, name := range names {
+		if _, err := ParseTextColor(name); err != nil {
+			t.Errorf("supported color %s should parse: %v", name, err)
+		}
+	}
+}
+
+func TestContainsCJK(t *testing.T) {
+	if ContainsCJK("hello, world") || !ContainsCJK("水印 logo") || !ContainsCJK("テスト") {
+		t.Errorf("ContainsCJK returned unexpected result")
+	}
+}
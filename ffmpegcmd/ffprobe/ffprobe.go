@@ -1,28 +1,153 @@
 package ffprobe
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"math"
 	"os/exec"
 	"strconv"
 	"strings"
 )
 
+// ffprobe 的 json 输出中大部分数值是字符串, 例如 "duration": "12.345000",
+// Float 和 Int 同时兼容字符串和数字两种写法
+type Float float64
+type Int int64
+
+func (f *Float) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	if len(str) == 0 || str == "null" || str == "N/A" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		*f = 0
+		return nil
+	}
+	*f = Float(v)
+	return nil
+}
+
+func (i *Int) UnmarshalJSON(data []byte) error {
+	var f Float
+	if err := f.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*i = Int(f)
+	return nil
+}
+
+// Rational 形如 "30000/1001" 的分数, 用于帧率和宽高比
+type Rational string
+
+func (r Rational) Float() float64 {
+	sep := "/"
+	if strings.Contains(string(r), ":") {
+		sep = ":"
+	}
+	numden := strings.Split(string(r), sep)
+	if len(numden) != 2 {
+		v, _ := strconv.ParseFloat(string(r), 64)
+		return v
+	}
+	num, _ := strconv.ParseFloat(numden[0], 64)
+	den, _ := strconv.ParseFloat(numden[1], 64)
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
+
+type Disposition struct {
+	Default         int `json:"default"`
+	Dub             int `json:"dub"`
+	Original        int `json:"original"`
+	Comment         int `json:"comment"`
+	Lyrics          int `json:"lyrics"`
+	Karaoke         int `json:"karaoke"`
+	Forced          int `json:"forced"`
+	HearingImpaired int `json:"hearing_impaired"`
+	VisualImpaired  int `json:"visual_impaired"`
+	AttachedPic     int `json:"attached_pic"`
+}
+
+// SideData 流的附加数据, 例如 "Display Matrix"(旋转), "Mastering display metadata"(HDR)
+type SideData struct {
+	SideDataType string  `json:"side_data_type"`
+	Rotation     float64 `json:"rotation,omitempty"`
+	DVProfile    int     `json:"dv_profile,omitempty"`
+	MaxContent   int     `json:"max_content,omitempty"`
+	MaxAverage   int     `json:"max_average,omitempty"`
+}
+
 type Stream struct {
-	CodecType    string `json:"codec_type"`
-	CodecName    string `json:"codec_name"`
-	Width        int    `json:"width,omitempty"`
-	Height       int    `json:"height,omitempty"`
-	SampleRate   string `json:"sample_rate,omitempty"`
-	Channels     int    `json:"channels,omitempty"`
-	GOPSize      int    `json:"gop_size,omitempty"` // 部分文件存在
-	AvgFrameRate string `json:"avg_frame_rate"`     // 形如 "30/1" 或 "30000/1001"
+	Index              int               `json:"index"`
+	CodecType          string            `json:"codec_type"`
+	CodecName          string            `json:"codec_name"`
+	CodecLongName      string            `json:"codec_long_name,omitempty"`
+	Profile            string            `json:"profile,omitempty"`
+	CodecTag           string            `json:"codec_tag_string,omitempty"`
+	Width              int               `json:"width,omitempty"`
+	Height             int               `json:"height,omitempty"`
+	SampleAspectRatio  Rational          `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio Rational          `json:"display_aspect_ratio,omitempty"`
+	PixFmt             string            `json:"pix_fmt,omitempty"`
+	Level              int               `json:"level,omitempty"`
+	ColorRange         string            `json:"color_range,omitempty"`
+	ColorSpace         string            `json:"color_space,omitempty"`
+	ColorTransfer      string            `json:"color_transfer,omitempty"`
+	ColorPrimaries     string            `json:"color_primaries,omitempty"`
+	FieldOrder         string            `json:"field_order,omitempty"`
+	BitsPerRawSample   Int               `json:"bits_per_raw_sample,omitempty"`
+	SampleFmt          string            `json:"sample_fmt,omitempty"`
+	SampleRate         string            `json:"sample_rate,omitempty"`
+	Channels           int               `json:"channels,omitempty"`
+	ChannelLayout      string            `json:"channel_layout,omitempty"`
+	GOPSize            int               `json:"gop_size,omitempty"` // 部分文件存在
+	RFrameRate         Rational          `json:"r_frame_rate,omitempty"`
+	AvgFrameRate       Rational          `json:"avg_frame_rate"` // 形如 "30/1" 或 "30000/1001"
+	TimeBase           Rational          `json:"time_base,omitempty"`
+	StartTime          Float             `json:"start_time,omitempty"`
+	Duration           Float             `json:"duration,omitempty"`
+	BitRate            Int               `json:"bit_rate,omitempty"`
+	NbFrames           Int               `json:"nb_frames,omitempty"`
+	Disposition        Disposition       `json:"disposition"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	SideDataList       []SideData        `json:"side_data_list,omitempty"`
+}
+
+type Format struct {
+	Filename       string            `json:"filename"`
+	NbStreams      int               `json:"nb_streams"`
+	FormatName     string            `json:"format_name"`
+	FormatLongName string            `json:"format_long_name,omitempty"`
+	StartTime      Float             `json:"start_time,omitempty"`
+	Duration       Float             `json:"duration"`
+	Size           Int               `json:"size,omitempty"`
+	BitRate        Int               `json:"bit_rate,omitempty"`
+	ProbeScore     int               `json:"probe_score,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
 }
 
+type Chapter struct {
+	ID        int64             `json:"id"`
+	TimeBase  Rational          `json:"time_base"`
+	StartTime Float             `json:"start_time"`
+	EndTime   Float             `json:"end_time"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+func (c *Chapter) Title() string {
+	return c.Tags["title"]
+}
+
+// ProbeResp ffprobe -show_format -show_streams -show_chapters 的完整结果
 type ProbeResp struct {
-	Format struct {
-		Duration string `json:"duration"`
-	} `json:"format"`
-	Streams []Stream `json:"streams"`
+	Format   Format    `json:"format"`
+	Streams  []Stream  `json:"streams"`
+	Chapters []Chapter `json:"chapters,omitempty"`
 }
 
 type FullInfo struct {
@@ -33,53 +158,215 @@ type FullInfo struct {
 	AudioCodec string  `json:"audio_codec"`
 	Width      int     `json:"width"`
 	Height     int     `json:"height"`
+	Rotation   int     `json:"rotation,omitempty"` // 顺时针旋转角度: 0, 90, 180, 270
 	FrameRate  float64 `json:"frame_rate"`
 	SampleRate int     `json:"sample_rate"`
 	Channels   int     `json:"channels"`
 }
 
-func GetMediaFullInfo(filename string) (FullInfo, error) {
+// DisplaySize 返回考虑旋转后的显示宽高, ffmpeg 默认会自动旋转, 滤镜里看到的是这个尺寸
+func (info FullInfo) DisplaySize() (int, int) {
+	if info.Rotation == 90 || info.Rotation == 270 {
+		return info.Height, info.Width
+	}
+	return info.Width, info.Height
+}
+
+func (s *Stream) IsVideo() bool {
+	// 封面图片也是 video 类型, 但不是真正的视频流
+	return s.CodecType == "video" && s.Disposition.AttachedPic == 0
+}
+
+func (s *Stream) IsAudio() bool {
+	return s.CodecType == "audio"
+}
+
+func (s *Stream) IsSubtitle() bool {
+	return s.CodecType == "subtitle"
+}
+
+func (s *Stream) Language() string {
+	return s.Tags["language"]
+}
+
+func (s *Stream) Title() string {
+	return s.Tags["title"]
+}
+
+func (s *Stream) FrameRate() float64 {
+	if fps := s.AvgFrameRate.Float(); fps > 0 {
+		return fps
+	}
+	return s.RFrameRate.Float()
+}
+
+// Rotation 返回顺时针旋转角度(0, 90, 180, 270), 兼容旧版本的 rotate 标签和新版本的 Display Matrix
+func (s *Stream) Rotation() int {
+	var rotation float64
+	if rotate, ok := s.Tags["rotate"]; ok {
+		rotation, _ = strconv.ParseFloat(rotate, 64)
+	} else {
+		for _, sd := range s.SideDataList {
+			if sd.SideDataType == "Display Matrix" {
+				// display matrix 的角度是逆时针的
+				rotation = -sd.Rotation
+				break
+			}
+		}
+	}
+	degrees := int(math.Round(rotation/90)) * 90 % 360
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// DisplaySize 返回考虑旋转和非方形像素之后的显示宽高
+func (s *Stream) DisplaySize() (int, int) {
+	w, h := s.Width, s.Height
+	if sar := s.SampleAspectRatio.Float(); sar > 0 && sar != 1 {
+		w = int(math.Round(float64(w)*sar/2)) * 2
+	}
+	if rotation := s.Rotation(); rotation == 90 || rotation == 270 {
+		return h, w
+	}
+	return w, h
+}
+
+// HDRFormat 返回 HDR 类型: HDR10, HLG, Dolby Vision, 非 HDR 返回空串
+func (s *Stream) HDRFormat() string {
+	for _, sd := range s.SideDataList {
+		if strings.HasPrefix(sd.SideDataType, "DOVI") {
+			return "Dolby Vision"
+		}
+	}
+	switch s.ColorTransfer {
+	case "smpte2084":
+		return "HDR10"
+	case "arib-std-b67":
+		return "HLG"
+	}
+	return ""
+}
+
+func (s *Stream) IsHDR() bool {
+	return len(s.HDRFormat()) > 0
+}
+
+func (pr *ProbeResp) streamsOfType(match func(s *Stream) bool) []*Stream {
+	var ret []*Stream
+	for i := range pr.Streams {
+		if match(&pr.Streams[i]) {
+			ret = append(ret, &pr.Streams[i])
+		}
+	}
+	return ret
+}
+
+func (pr *ProbeResp) VideoStreams() []*Stream {
+	return pr.streamsOfType((*Stream).IsVideo)
+}
+
+func (pr *ProbeResp) AudioStreams() []*Stream {
+	return pr.streamsOfType((*Stream).IsAudio)
+}
+
+func (pr *ProbeResp) SubtitleStreams() []*Stream {
+	return pr.streamsOfType((*Stream).IsSubtitle)
+}
+
+// FirstVideo 返回第一个视频流, 没有视频流返回 nil
+func (pr *ProbeResp) FirstVideo() *Stream {
+	if streams := pr.VideoStreams(); len(streams) > 0 {
+		return streams[0]
+	}
+	return nil
+}
+
+func (pr *ProbeResp) FirstAudio() *Stream {
+	if streams := pr.AudioStreams(); len(streams) > 0 {
+		return streams[0]
+	}
+	return nil
+}
+
+// DisplaySize 返回第一个视频流的显示宽高
+func (pr *ProbeResp) DisplaySize() (int, int) {
+	if video := pr.FirstVideo(); video != nil {
+		return video.DisplaySize()
+	}
+	return 0, 0
+}
+
+func (pr *ProbeResp) Duration() float64 {
+	if pr.Format.Duration > 0 {
+		return float64(pr.Format.Duration)
+	}
+	var duration float64
+	for _, s := range pr.Streams {
+		duration = math.Max(duration, float64(s.Duration))
+	}
+	return duration
+}
+
+// FullInfo 把完整结果简化为只包含第一个视频流和第一个音频流的信息
+func (pr *ProbeResp) FullInfo() FullInfo {
+	info := FullInfo{Duration: pr.Duration()}
+	if video := pr.FirstVideo(); video != nil {
+		info.HasVideo = true
+		info.VideoCodec = video.CodecName
+		info.Width = video.Width
+		info.Height = video.Height
+		info.Rotation = video.Rotation()
+		info.FrameRate = video.FrameRate()
+	}
+	if audio := pr.FirstAudio(); audio != nil {
+		info.HasAudio = true
+		info.AudioCodec = audio.CodecName
+		if sr, err := strconv.Atoi(audio.SampleRate); err == nil {
+			info.SampleRate = sr
+		}
+		info.Channels = audio.Channels
+	}
+	return info
+}
+
+// ParseProbeOutput 解析 ffprobe -print_format json 的输出
+func ParseProbeOutput(data []byte) (*ProbeResp, error) {
+	var pr ProbeResp
+	if err := json.Unmarshal(data, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// Probe 返回文件的全部流, 容器, 章节信息
+func Probe(filename string) (*ProbeResp, error) {
 	args := []string{
 		"-v", "quiet",
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		filename,
 	}
-	out, err := exec.Command("ffprobe", args...).Output()
+	var stderr bytes.Buffer
+	cmd := exec.Command("ffprobe", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
 	if err != nil {
-		return FullInfo{}, err
-	}
-	var pr ProbeResp
-	if err := json.Unmarshal(out, &pr); err != nil {
-		return FullInfo{}, err
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
 	}
+	return ParseProbeOutput(out)
+}
 
-	dur, _ := strconv.ParseFloat(pr.Format.Duration, 64)
-	info := FullInfo{Duration: dur}
-	for _, s := range pr.Streams {
-		switch s.CodecType {
-		case "video":
-			info.HasVideo = true
-			info.VideoCodec = s.CodecName
-			info.Width = s.Width
-			info.Height = s.Height
-			// 解析帧率
-			if numden := strings.Split(s.AvgFrameRate, "/"); len(numden) == 2 {
-				num, _ := strconv.ParseFloat(numden[0], 64)
-				den, _ := strconv.ParseFloat(numden[1], 64)
-				if den != 0 {
-					info.FrameRate = num / den
-				}
-			}
-		case "audio":
-			info.HasAudio = true
-			info.AudioCodec = s.CodecName
-			if sr, err := strconv.Atoi(s.SampleRate); err == nil {
-				info.SampleRate = sr
-			}
-			info.Channels = s.Channels
-		}
+func GetMediaFullInfo(filename string) (FullInfo, error) {
+	pr, err := Probe(filename)
+	if err != nil {
+		return FullInfo{}, err
 	}
-	return info, nil
+	return pr.FullInfo(), nil
 }
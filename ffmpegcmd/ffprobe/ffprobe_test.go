@@ -17,3 +17,88 @@ func TestGetMediaInfo(t *testing.T) {
 	fmt.Println("Media info:", string(data))
 	t.Logf("Media info: %+v", info)
 }
+
+const testProbeOutput = `{
+	"streams": [
+		{
+			"index": 0, "codec_name": "hevc", "codec_type": "video", "profile": "Main 10",
+			"width": 3840, "height": 2160, "sample_aspect_ratio": "1:1", "pix_fmt": "yuv420p10le",
+			"color_transfer": "smpte2084", "color_primaries": "bt2020",
+			"r_frame_rate": "30000/1001", "avg_frame_rate": "30000/1001", "bit_rate": "45000000",
+			"disposition": {"default": 1},
+			"side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]
+		},
+		{
+			"index": 1, "codec_name": "aac", "codec_type": "audio", "sample_rate": "48000",
+			"channels": 2, "channel_layout": "stereo", "bit_rate": "128000",
+			"disposition": {"default": 1}, "tags": {"language": "eng"}
+		},
+		{
+			"index": 2, "codec_name": "mov_text", "codec_type": "subtitle",
+			"disposition": {"default": 0, "forced": 1}, "tags": {"language": "chi", "title": "中文"}
+		},
+		{
+			"index": 3, "codec_name": "mjpeg", "codec_type": "video", "width": 600, "height": 600,
+			"disposition": {"attached_pic": 1}
+		}
+	],
+	"chapters": [
+		{"id": 0, "time_base": "1/1000", "start_time": "0.000000", "end_time": "12.500000", "tags": {"title": "Intro"}}
+	],
+	"format": {
+		"filename": "hdr.mov", "nb_streams": 4, "format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+		"duration": "62.562000", "size": "352000000", "bit_rate": "45133000",
+		"tags": {"creation_time": "2024-05-01T10:00:00.000000Z"}
+	}
+}`
+
+func TestParseProbeOutput(t *testing.T) {
+	pr, err := ParseProbeOutput([]byte(testProbeOutput))
+	if err != nil {
+		t.Fatalf("ParseProbeOutput failed: %v", err)
+	}
+	if len(pr.VideoStreams()) != 1 || len(pr.AudioStreams()) != 1 || len(pr.SubtitleStreams()) != 1 {
+		t.Fatalf("unexpected stream split: %+v", pr.Streams)
+	}
+	video := pr.FirstVideo()
+	if video.Rotation() != 90 {
+		t.Errorf("expected rotation 90, got %d", video.Rotation())
+	}
+	if w, h := video.DisplaySize(); w != 2160 || h != 3840 {
+		t.Errorf("expected display size 2160x3840, got %dx%d", w, h)
+	}
+	if video.HDRFormat() != "HDR10" {
+		t.Errorf("expected HDR10, got %q", video.HDRFormat())
+	}
+	if video.BitRate != 45000000 || video.FrameRate() < 29.96 || video.FrameRate() > 29.98 {
+		t.Errorf("unexpected bitrate/framerate: %d %f", video.BitRate, video.FrameRate())
+	}
+	if pr.SubtitleStreams()[0].Language() != "chi" || pr.SubtitleStreams()[0].Disposition.Forced != 1 {
+		t.Errorf("unexpected subtitle stream: %+v", pr.SubtitleStreams()[0])
+	}
+	if len(pr.Chapters) != 1 || pr.Chapters[0].Title() != "Intro" || pr.Chapters[0].EndTime != 12.5 {
+		t.Errorf("unexpected chapters: %+v", pr.Chapters)
+	}
+	if pr.Format.Size != 352000000 || pr.Duration() != 62.562 {
+		t.Errorf("unexpected format: %+v", pr.Format)
+	}
+
+	info := pr.FullInfo()
+	if !info.HasVideo || !info.HasAudio || info.VideoCodec != "hevc" || info.SampleRate != 48000 {
+		t.Errorf("unexpected full info: %+v", info)
+	}
+	if w, h := info.DisplaySize(); w != 2160 || h != 3840 {
+		t.Errorf("expected full info display size 2160x3840, got %dx%d", w, h)
+	}
+}
+
+func TestStreamRotationTag(t *testing.T) {
+	s := Stream{Width: 1920, Height: 1080, Tags: map[string]string{"rotate": "270"}}
+	if s.Rotation() != 270 {
+		t.Errorf("expected rotation 270, got %d", s.Rotation())
+	}
+	s = Stream{Width: 1920, Height: 1080, SideDataList: []SideData{{SideDataType: "Display Matrix", Rotation: 180}}}
+	if w, h := s.DisplaySize(); w != 1920 || h != 1080 {
+		t.Errorf("expected display size 1920x1080, got %dx%d", w, h)
+	}
+}
@@ -0,0 +1,43 @@
+package ffprobe
+
+import (
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GetKeyframeTimes 返回第一个视频流所有关键帧的时间戳(秒), 只读取包头不解码, 长视频也很快
+func GetKeyframeTimes(filename string) ([]float64, error) {
+	args := []string{
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "packet=pts_time,flags",
+		"-of", "csv=print_section=0",
+		filename,
+	}
+	out, err := exec.Command("ffprobe", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyframeTimes(string(out)), nil
+}
+
+// ParseKeyframeTimes 解析 "pts_time,flags" 格式的 csv 输出, 例如 "2.002000,K__"
+func ParseKeyframeTimes(output string) []float64 {
+	var times []float64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "K") {
+			continue
+		}
+		pts, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		times = append(times, pts)
+	}
+	// b帧的包顺序和显示顺序不一致, 关键帧也按时间排个序
+	sort.Float64s(times)
+	return times
+}
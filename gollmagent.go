@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
+	"github.com/gollmagent/asrfile"
 	"github.com/gollmagent/ffmpegcmd"
 	"github.com/gollmagent/llmproxy"
 	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/medialib"
 	"github.com/gollmagent/progressmgr"
 	"github.com/gollmagent/pub"
 	"github.com/gollmagent/websocket"
@@ -20,6 +23,8 @@ var (
 	wsPort     = flag.Int("wsport", 8080, "WebSocket server port")
 	serverMode = flag.Bool("server", false, "Run in server mode")
 	llmType    = flag.String("llmtype", "yuanbao", "LLM type: qwen, yuanbao")
+	mediaDirs  = flag.String("mediadirs", "", "Media library directories, separated by comma")
+	mediaCache = flag.String("mediacache", "media_library.json", "Media library cache file path")
 )
 
 var supportedLLMTypes map[string]pub.LLMTypeInfo
@@ -104,8 +109,24 @@ func main() {
 
 	llmproxy.CreateFunctionToolsHandler()
 
+	// generate_subtitles uses tencent flash asr when the tencent cloud keys are set
+	if len(appId) > 0 && len(SecretId) > 0 && len(SecretKey) > 0 {
+		llmproxy.SetASRProvider(asrfile.NewTencentFlashProvider(appId, SecretId, SecretKey))
+	}
+
+	// media library is optional, it indexes the media files under -mediadirs for list_media/search_media
+	if len(*mediaDirs) > 0 {
+		mediaLib := medialib.NewMediaLibrary(strings.Split(*mediaDirs, ","), *mediaCache)
+		if err := mediaLib.Load(); err != nil {
+			log.Errorf("load media library cache failed: %v", err)
+		}
+		llmproxy.SetMediaLibrary(mediaLib)
+		go mediaLib.Scan()
+	}
+
 	// create llm proxy object
 	llmProxyObj := llmproxy.NewLLMProxy(llmUrl, model, llmSecKey, voiceAuth)
+	llmproxy.SetTextCompleter(llmProxyObj)
 
 	// create progress manager, it will manage the progress of tools execution
 	progressmgr := progressmgr.NewProgressMgr(llmProxyObj, llmproxy.FunctionTools)
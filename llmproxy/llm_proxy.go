@@ -213,9 +213,8 @@ func (proxy *LLMProxy) ToolResultCompletions(text string, callId string) (*pub.C
 	return resp, nil
 }
 
-func (proxy *LLMProxy) ChatCompletions(prompt string, FunctionTools []*pub.ToolDefinition) (*pub.ChatCompletionsResponse, error) {
-	secretKey := proxy.llmSecKey
-
+// postChatCompletions 发送 chat/completions 请求并解析响应, 至少要有一个 choice
+func (proxy *LLMProxy) postChatCompletions(info *pub.ChatCompletionsInfo) (*pub.ChatCompletionsResponse, error) {
 	isHttps, hostname, port, subpath, err := utils.ParseURL(proxy.llmUrl)
 	if err != nil {
 		log.Errorf("Failed to parse LLM URL: %v", err)
@@ -226,32 +225,15 @@ func (proxy *LLMProxy) ChatCompletions(prompt string, FunctionTools []*pub.ToolD
 		return nil, fmt.Errorf("only https is supported for llmUrl")
 	}
 
-	prompt = fmt.Sprintf("%s, 回答请简短，并且消息不使用markdown格式", prompt)
-	newMsg := &pub.ChatCompletionsMessage{
-		Role:    "user",
-		Content: prompt,
-	}
-	proxy.addMessage(newMsg)
-
-	msgs := proxy.getMessages()
-
-	info := &pub.ChatCompletionsInfo{
-		Model:    proxy.model,
-		Messages: msgs,
-	}
-	for _, tool := range FunctionTools {
-		info.Tools = append(info.Tools, *tool)
-	}
 	jsonData, err := json.Marshal(info)
 	if err != nil {
 		return nil, err
 	}
 	header := make(http.Header)
 	header.Set("Content-Type", "application/json")
-	header.Set("Authorization", "Bearer "+secretKey)
+	header.Set("Authorization", "Bearer "+proxy.llmSecKey)
 
 	log.Infof("Sending request to %s:%d%s with data: %s", hostname, port, subpath, string(jsonData))
-	log.Infof("header: %v", header)
 
 	respData, err := httpclient.HTTPSPost(hostname, port, subpath, jsonData, header)
 	if err != nil {
@@ -268,6 +250,30 @@ func (proxy *LLMProxy) ChatCompletions(prompt string, FunctionTools []*pub.ToolD
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("no choices found in response")
 	}
+	return resp, nil
+}
+
+func (proxy *LLMProxy) ChatCompletions(prompt string, FunctionTools []*pub.ToolDefinition) (*pub.ChatCompletionsResponse, error) {
+	prompt = fmt.Sprintf("%s, 回答请简短，并且消息不使用markdown格式", prompt)
+	newMsg := &pub.ChatCompletionsMessage{
+		Role:    "user",
+		Content: prompt,
+	}
+	proxy.addMessage(newMsg)
+
+	msgs := proxy.getMessages()
+
+	info := &pub.ChatCompletionsInfo{
+		Model:    proxy.model,
+		Messages: msgs,
+	}
+	for _, tool := range FunctionTools {
+		info.Tools = append(info.Tools, *tool)
+	}
+	resp, err := proxy.postChatCompletions(info)
+	if err != nil {
+		return nil, err
+	}
 	for _, choice := range resp.Choices {
 		if choice.Message.Role == "assistant" {
 			proxy.addMessage(&choice.Message)
@@ -276,6 +282,21 @@ func (proxy *LLMProxy) ChatCompletions(prompt string, FunctionTools []*pub.ToolD
 	return resp, nil
 }
 
+// Complete 单轮补全, 不带工具, 也不读写对话历史, 供字幕翻译等工具内部调用
+func (proxy *LLMProxy) Complete(prompt string) (string, error) {
+	info := &pub.ChatCompletionsInfo{
+		Model: proxy.model,
+		Messages: []pub.ChatCompletionsMessage{
+			{Role: "user", Content: prompt},
+		},
+	}
+	resp, err := proxy.postChatCompletions(info)
+	if err != nil {
+		return "", err
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
 func (proxy *LLMProxy) handleClientMessage(info *pub.ChatMessageInfo, ws pub.WsStreamI) error {
 	resp, err := proxy.ChatCompletions(info.Content, nil)
 	if err != nil {
@@ -0,0 +1,118 @@
+package llmproxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+)
+
+// tool arguments come from the llm as decoded json, numbers are float64 and
+// some models send everything as string, so the helpers below accept both.
+
+func getStringArg(args map[string]interface{}, key string, def string) string {
+	value, ok := args[key]
+	if !ok || value == nil {
+		return def
+	}
+	switch v := value.(type) {
+	case string:
+		if len(strings.TrimSpace(v)) == 0 {
+			return def
+		}
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return def
+}
+
+func getFloatArg(args map[string]interface{}, key string, def float64) float64 {
+	value, ok := args[key]
+	if !ok || value == nil {
+		return def
+	}
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+func getIntArg(args map[string]interface{}, key string, def int) int {
+	return int(getFloatArg(args, key, float64(def)))
+}
+
+func getBoolArg(args map[string]interface{}, key string, def bool) bool {
+	value, ok := args[key]
+	if !ok || value == nil {
+		return def
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err == nil {
+			return b
+		}
+	case float64:
+		return v != 0
+	}
+	return def
+}
+
+// hasArg reports whether the llm provided a value for the key
+func hasArg(args map[string]interface{}, key string) bool {
+	value, ok := args[key]
+	if !ok || value == nil {
+		return false
+	}
+	if str, ok := value.(string); ok {
+		return len(strings.TrimSpace(str)) > 0
+	}
+	return true
+}
+
+func getStringSliceArg(args map[string]interface{}, key string) []string {
+	var ret []string
+	switch v := args[key].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok && len(strings.TrimSpace(str)) > 0 {
+				ret = append(ret, strings.TrimSpace(str))
+			}
+		}
+	case []string:
+		ret = append(ret, v...)
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if len(strings.TrimSpace(item)) > 0 {
+				ret = append(ret, strings.TrimSpace(item))
+			}
+		}
+	}
+	return ret
+}
+
+// getTimeArg 读取时间参数, 支持秒数和 HH:MM:SS 格式, 参数不存在时 ok 为 false
+func getTimeArg(args map[string]interface{}, key string) (seconds float64, ok bool, err error) {
+	if !hasArg(args, key) {
+		return 0, false, nil
+	}
+	seconds, err = ffmpegcmd.ParseTimeToSeconds(getStringArg(args, key, ""))
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return seconds, true, nil
+}
@@ -3,6 +3,7 @@ package llmproxy
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -38,6 +39,37 @@ func InitTools() string {
 	FunctionTools = append(FunctionTools, AddGenPictureFromVideoBasedOnIFrame())
 	FunctionTools = append(FunctionTools, AddScreenshotAtMomentTool())
 	FunctionTools = append(FunctionTools, AddM3U8ToMP4Tool())
+	FunctionTools = append(FunctionTools, AddListMediaTool())
+	FunctionTools = append(FunctionTools, AddSearchMediaTool())
+	FunctionTools = append(FunctionTools, AddGetMediaInfoTool())
+	FunctionTools = append(FunctionTools, AddTrimMediaTool())
+	FunctionTools = append(FunctionTools, AddDetectScenesTool())
+	FunctionTools = append(FunctionTools, AddThumbnailSpritesTool())
+	FunctionTools = append(FunctionTools, AddVideoToGifTool())
+	FunctionTools = append(FunctionTools, AddPackageHLSTool())
+	FunctionTools = append(FunctionTools, AddPackageDashTool())
+	FunctionTools = append(FunctionTools, AddTranscodeToTargetSizeTool())
+	FunctionTools = append(FunctionTools, AddCompareQualityTool())
+	FunctionTools = append(FunctionTools, AddNormalizeLoudnessTool())
+	FunctionTools = append(FunctionTools, AddDetectSilenceTool())
+	FunctionTools = append(FunctionTools, AddRemoveSilenceTool())
+	FunctionTools = append(FunctionTools, AddBackgroundMusicTool())
+	FunctionTools = append(FunctionTools, AddGenerateSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddBurnSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddSubtitleTracksTool())
+	FunctionTools = append(FunctionTools, AddConvertSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddRetimeSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddMergeSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddCleanSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddTranslateSubtitlesTool())
+	FunctionTools = append(FunctionTools, AddChangeSpeedTool())
+	FunctionTools = append(FunctionTools, AddReverseVideoTool())
+	FunctionTools = append(FunctionTools, AddLoopVideoTool())
+	FunctionTools = append(FunctionTools, AddCropVideoTool())
+	FunctionTools = append(FunctionTools, AddRotateVideoTool())
+	FunctionTools = append(FunctionTools, AddFlipVideoTool())
+	FunctionTools = append(FunctionTools, AddComposeVideosTool())
+	FunctionTools = append(FunctionTools, AddImagesToVideoTool())
 
 	var desc string
 	for _, tool := range FunctionTools {
@@ -94,61 +126,33 @@ func AddTranscodeWithProgressTool() *pub.ToolDefinition {
 				"type":        "string",
 				"description": vResDesc,
 			},
-		},
-		"required": []string{"input_file", "video_resolution"},
-	}
-	tool := AddFunctionTool("transcode_with_progress", "将多媒体文件转换为mp4格式, 主要为视频文件，并显示进度", transcodeWithProgressParams)
-	return tool
-}
-
-func AddImageWatermark2VideoTool() *pub.ToolDefinition {
-	imageWatermark2VideoParams := map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"input_file": map[string]interface{}{
-				"type":        "string",
-				"description": "输入的多媒体文件路径",
-			},
-			"watermark_file": map[string]interface{}{
+			"video_codec": map[string]interface{}{
 				"type":        "string",
-				"description": "水印图片文件路径",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedVideoCodecs),
+				"description": "视频编码器, 默认 libx264",
 			},
-			"position": map[string]interface{}{
+			"audio_codec": map[string]interface{}{
 				"type":        "string",
-				"description": "水印位置, 可选值: top-left, top-right, bottom-left, bottom-right",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedAudioCodecs),
+				"description": "音频编码器, 默认 aac",
 			},
-		},
-		"required": []string{"input_file", "watermark_file"},
-	}
-	tool := AddFunctionTool("image_watermark_to_video", "给视频添加图片水印", imageWatermark2VideoParams)
-	return tool
-}
-
-func AddTextWatermark2VideoTool() *pub.ToolDefinition {
-	colorDesc := fmt.Sprintf("水印文字颜色, 可选值: %v", ffmpegcmd.SupportedTextColor())
-	textWatermark2VideoParams := map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"input_file": map[string]interface{}{
-				"type":        "string",
-				"description": "输入的多媒体文件路径",
-			},
-			"watermark_text": map[string]interface{}{
+			"container": map[string]interface{}{
 				"type":        "string",
-				"description": "水印文字内容",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedContainers),
+				"description": "输出容器格式, 默认 mp4, webm 只支持 vp9/av1 + opus, mov 只支持 h264/h265",
 			},
-			"position": map[string]interface{}{
-				"type":        "string",
-				"description": "水印位置, 可选值: top-left, top-right, bottom-left, bottom-right",
+			"frame_rate": map[string]interface{}{
+				"type":        "number",
+				"description": "输出帧率, 默认30, 0 表示保持源帧率",
 			},
-			"color": map[string]interface{}{
-				"type":        "string",
-				"description": colorDesc,
+			"crf": map[string]interface{}{
+				"type":        "integer",
+				"description": "质量参数, 越小质量越高、文件越大. h264/h265 范围 0-51(0 为无损), av1/vp9 范围 0-63, 默认使用编码器对应的值(h264 23, h265 28, svtav1 35, vp9 33)",
 			},
 		},
-		"required": []string{"input_file", "watermark_text"},
+		"required": []string{"input_file", "video_resolution"},
 	}
-	tool := AddFunctionTool("text_watermark_to_video", "给视频添加文字水印", textWatermark2VideoParams)
+	tool := AddFunctionTool("transcode_with_progress", "转码多媒体文件, 可选视频编码(h264/h265/av1/vp9)、音频编码和容器格式(mp4/mkv/webm/mov), 并显示进度", transcodeWithProgressParams)
 	return tool
 }
 
@@ -162,7 +166,11 @@ func AddSrt2Video() *pub.ToolDefinition {
 			},
 			"srt_file": map[string]interface{}{
 				"type":        "string",
-				"description": "字幕文件路径",
+				"description": "字幕文件路径, 支持 srt, vtt, ass",
+			},
+			"offset": map[string]interface{}{
+				"type":        "number",
+				"description": "封装前把字幕整体平移的秒数, 正数延后, 负数提前",
 			},
 		},
 		"required": []string{"input_file", "srt_file"},
@@ -245,10 +253,23 @@ func AddConcatMediaFilesTool() *pub.ToolDefinition {
 				"items":       map[string]interface{}{"type": "string"},
 				"description": "输入的多媒体文件路径列表",
 			},
+			"transition": map[string]interface{}{
+				"type":        "string",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedTransitions),
+				"description": "片段之间的转场效果, 默认 none 直接拼接",
+			},
+			"transition_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "转场时长(秒), 默认 1",
+			},
+			"force_reencode": map[string]interface{}{
+				"type":        "boolean",
+				"description": "输入参数一致时也重新编码, 默认 false(参数一致时直接拷贝, 不损失画质)",
+			},
 		},
 		"required": []string{"input_files"},
 	}
-	tool := AddFunctionTool("concat_media_files", "合并多个多媒体文件为一个带有视频和音频的mp4文件", concatMediaFilesParams)
+	tool := AddFunctionTool("concat_media_files", "合并多个视频文件为一个mp4文件. 编码参数一致时直接拷贝, 否则统一分辨率和帧率重新编码, 没有音频的片段补静音, 可选转场效果", concatMediaFilesParams)
 	return tool
 }
 
@@ -413,22 +434,50 @@ func TranscodeWithProgressTool(args map[string]interface{}) interface{} {
 	if !ok {
 		return "invalid progress_cb arguments for TranscodeWithProgressTool"
 	}
-	out := fmt.Sprintf("%s_%s.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), vRes)
-	log.Infof("Starting transcode with progress for file: %s, output:%s,callId:%s",
-		inputFile, out, callId)
+	opts := &ffmpegcmd.TranscodeOptions{
+		VideoCodec:   getStringArg(args, "video_codec", "libx264"),
+		AudioCodec:   getStringArg(args, "audio_codec", "aac"),
+		Container:    getStringArg(args, "container", "mp4"),
+		FrameRate:    getFloatArg(args, "frame_rate", 30),
+		AudioBitrate: 64,
+	}
+	if hasArg(args, "crf") {
+		crf := getIntArg(args, "crf", 0)
+		opts.Crf = &crf
+	}
+	encoders, err := ffmpegcmd.GetEncoders()
+	if err != nil {
+		log.Warningf("error getting ffmpeg encoders, skip encoder check: %v", err)
+	}
+	if err := ffmpegcmd.ValidateTranscodeOptions(opts, encoders); err != nil {
+		log.Errorf("invalid transcode options: %v, options: %+v", err, opts)
+		return fmt.Sprintf("invalid transcode options: %v", err)
+	}
+	if opts.FrameRate > 0 {
+		// 关键帧间隔固定为 3 秒
+		opts.GOP = int(opts.FrameRate*3 + 0.5)
+	}
+
+	out := fmt.Sprintf("%s_%s.%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), vRes, opts.Container)
+	log.Infof("Starting transcode with progress for file: %s, output:%s,callId:%s, options:%+v",
+		inputFile, out, callId, opts)
 
 	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
 	if err != nil {
 		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
 		return fmt.Sprintf("error getting media info: %v", err)
 	}
-	w, h, err := ffmpegcmd.GetVideoResolution(vRes, mediaInfo.Width, mediaInfo.Height)
+	// 按显示宽高计算, 带旋转信息的竖屏视频才不会被缩放成横屏比例
+	srcW, srcH := mediaInfo.DisplaySize()
+	rendition, err := ffmpegcmd.RenditionFor(vRes, srcW, srcH)
 	if err != nil {
-		log.Errorf("GetVideoResolution failed: %v, vRes: %s, mediaInfo: %+v", err, vRes, mediaInfo)
-		return fmt.Sprintf("GetVideoResolution failed: %v", err)
+		log.Errorf("RenditionFor failed: %v, vRes: %s, mediaInfo: %+v", err, vRes, mediaInfo)
+		return fmt.Sprintf("invalid video_resolution: %v", err)
 	}
 
-	go ffmpegcmd.TranscodeWithProgress(callId, inputFile, w, h, out, mediaInfo.Duration, progressObj)
+	opts.Width, opts.Height = rendition.Width, rendition.Height
+
+	go ffmpegcmd.TranscodeWithOptions(callId, inputFile, out, opts, mediaInfo.Duration, progressObj)
 
 	mediaDesc, _ := json.Marshal(&mediaInfo)
 
@@ -476,12 +525,35 @@ func ConcatMediaFiles(args map[string]interface{}) interface{} {
 	index := time.Now().UnixMilli() % 10000
 	output = fmt.Sprintf("%s_concat_%d.mp4", strings.TrimSuffix(filepath.Base(inputFileStrs[0]), filepath.Ext(inputFileStrs[0])), index)
 
-	log.Infof("Starting to concat media files: %+v, output:%s", inputFileStrs, output)
-	err := ffmpegcmd.ConcatVideosWithResize(inputFileStrs, output)
+	var inputs []ffmpegcmd.ConcatInput
+	for _, f := range inputFileStrs {
+		pr, err := ffprobe.Probe(f)
+		if err != nil {
+			log.Errorf("error probing media file: %v, file:%s", err, f)
+			return fmt.Sprintf("error probing %s: %v", f, err)
+		}
+		in, err := ffmpegcmd.NewConcatInput(f, pr)
+		if err != nil {
+			return err.Error()
+		}
+		inputs = append(inputs, in)
+	}
+	opts := &ffmpegcmd.ConcatOptions{
+		Transition:         getStringArg(args, "transition", "none"),
+		TransitionDuration: getFloatArg(args, "transition_duration", 1),
+		ForceReencode:      getBoolArg(args, "force_reencode", false),
+	}
+
+	log.Infof("Starting to concat media files: %+v, options:%+v, output:%s", inputFileStrs, opts, output)
+	mode, err := ffmpegcmd.ConcatMedia(inputs, output, opts)
 	if err != nil {
+		log.Errorf("error concatenating media files: %v, mode:%s", err, mode)
 		return fmt.Sprintf("error concatenating media files: %v", err)
 	}
-	return output
+	if mode == "copy" {
+		return fmt.Sprintf("%s (编码参数一致, 直接拷贝合并)", output)
+	}
+	return fmt.Sprintf("%s (重新编码合并)", output)
 }
 
 func ConcatAudioFiles(args map[string]interface{}) interface{} {
@@ -516,101 +588,6 @@ func ConcatAudioFiles(args map[string]interface{}) interface{} {
 	return output
 }
 
-func ImageWatermark2Video(args map[string]interface{}) interface{} {
-	inputFile, ok := args["input_file"].(string)
-	if !ok {
-		log.Errorf("invalid input_file arguments for ImageWatermark2Video: %+v", args)
-		return "invalid input_file arguments for ImageWatermark2Video"
-	}
-	watermarkFile, ok := args["watermark_file"].(string)
-	if !ok {
-		log.Errorf("invalid watermark_file arguments for ImageWatermark2Video: %+v", args)
-		return "invalid watermark_file arguments for ImageWatermark2Video"
-	}
-	position, ok := args["position"].(string)
-	if !ok {
-		position = "top-right"
-	}
-	videoInfo, err := ffprobe.GetMediaFullInfo(inputFile)
-	if err != nil {
-		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
-		return "error getting media info"
-	}
-	imgInfo, err := ffprobe.GetMediaFullInfo(watermarkFile)
-	if err != nil {
-		log.Errorf("error getting media info: %v, file:%s", err, watermarkFile)
-		return "error getting media info"
-	}
-
-	x, y, err := ffmpegcmd.GetPosition(position, videoInfo.Width, videoInfo.Height, imgInfo.Width, imgInfo.Height)
-	if err != nil {
-		log.Errorf("error getting position: %v", err)
-		return "error getting position"
-	}
-	output := fmt.Sprintf("%s_watermarked.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)))
-
-	log.Infof("Starting to add image watermark to video: %s, watermark:%s, position:%s, output:%s",
-		inputFile, watermarkFile, position, output)
-	err = ffmpegcmd.ImageWatermark2Video(inputFile, watermarkFile, x, y, output)
-	if err != nil {
-		return fmt.Sprintf("error adding image watermark to video: %v", err)
-	}
-	return output
-}
-
-func TextWatermark2Video(args map[string]interface{}) interface{} {
-	inputFile, ok := args["input_file"].(string)
-	if !ok {
-		log.Errorf("invalid input_file arguments for TextWatermark2Video: %+v", args)
-		return "invalid input_file arguments for TextWatermark2Video"
-	}
-	watermarkText, ok := args["watermark_text"].(string)
-	if !ok {
-		log.Errorf("invalid watermark_text arguments for TextWatermark2Video: %+v", args)
-		return "invalid watermark_text arguments for TextWatermark2Video"
-	}
-	position, ok := args["position"].(string)
-	if !ok {
-		position = "top-right"
-	}
-	colorString, ok := args["color"].(string)
-	if !ok {
-		colorString = "white"
-	}
-
-	configs, err := ffmpegcmd.GetFFmpegConfig()
-	if err != nil {
-		log.Errorf("error getting ffmpeg config: %v", err)
-		return "error getting ffmpeg config"
-	}
-	// check if ffmpeg is compiled with --enable-gpl --enable-freetype
-	if !strings.Contains(strings.Join(configs, " "), "--enable-gpl") || !strings.Contains(strings.Join(configs, " "), "--enable-freetype") {
-		log.Errorf("ffmpeg is not compiled with --enable-gpl --enable-freetype, cannot add text watermark")
-		return "ffmpeg is not compiled with --enable-gpl --enable-freetype, cannot add text watermark"
-	}
-
-	videoInfo, err := ffprobe.GetMediaFullInfo(inputFile)
-	if err != nil {
-		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
-		return fmt.Errorf("error getting media info: %v", err)
-	}
-	x, y, err := ffmpegcmd.GetTextPosition(position, videoInfo.Width, videoInfo.Height, 24)
-	if err != nil {
-		log.Errorf("error getting text position: %v", err)
-		return fmt.Errorf("error getting text position: %v", err)
-	}
-	output := fmt.Sprintf("%s_text_watermarked.mp4", strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile)))
-
-	log.Infof("Starting to add text watermark to video: %s, text:%s, position:%s, color:%s, output:%s",
-		inputFile, watermarkText, position, colorString, output)
-	err = ffmpegcmd.TextWatermark2Video(inputFile, watermarkText, x, y, colorString, output)
-	if err != nil {
-		log.Errorf("error adding text watermark to video: %v", err)
-		return fmt.Sprintf("error adding text watermark to video: %v", err)
-	}
-	return output
-}
-
 func Srt2Video(args map[string]interface{}) interface{} {
 	inputFile, ok := args["input_file"].(string)
 	if !ok {
@@ -624,8 +601,20 @@ func Srt2Video(args map[string]interface{}) interface{} {
 	}
 	output := fmt.Sprintf("%s_srt.mp4", strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile)))
 
-	log.Infof("Starting to add srt to video: %s, srt:%s, output:%s",
-		inputFile, srtFile, output)
+	// 需要平移或者不是 srt 时先转换成临时 srt 再封装
+	offset := getFloatArg(args, "offset", 0)
+	if offset != 0 || !strings.EqualFold(filepath.Ext(srtFile), ".srt") {
+		tmpFile, err := prepareSrtForMux(srtFile, offset)
+		if err != nil {
+			log.Errorf("error preparing subtitle file: %v", err)
+			return fmt.Sprintf("error preparing subtitle file: %v", err)
+		}
+		defer os.Remove(tmpFile)
+		srtFile = tmpFile
+	}
+
+	log.Infof("Starting to add srt to video: %s, srt:%s, offset:%g, output:%s",
+		inputFile, srtFile, offset, output)
 	err := ffmpegcmd.Srt2Video(inputFile, srtFile, output)
 	if err != nil {
 		log.Errorf("error adding srt to video: %v", err)
@@ -728,4 +717,35 @@ func CreateFunctionToolsHandler() {
 	functions["gen_pictures_from_video"] = GenPicturesFromVideoBaseOnIFrame
 	functions["screenshot_at_moment"] = ScreenshotOnePictureAtMoment
 	functions["m3u8_to_mp4"] = MergeM3U8ToMP4
+	functions["list_media"] = ListMedia
+	functions["search_media"] = SearchMedia
+	functions["get_media_info"] = GetMediaInfo
+	functions["trim_media"] = TrimMedia
+	functions["detect_scenes"] = DetectScenes
+	functions["gen_thumbnail_sprites"] = GenThumbnailSprites
+	functions["video_to_gif"] = VideoToGif
+	functions["package_hls"] = PackageHLS
+	functions["package_dash"] = PackageDash
+	functions["transcode_to_target_size"] = TranscodeToTargetSize
+	functions["compare_quality"] = CompareQuality
+	functions["normalize_loudness"] = NormalizeLoudness
+	functions["detect_silence"] = DetectSilence
+	functions["remove_silence"] = RemoveSilence
+	functions["add_background_music"] = AddBackgroundMusic
+	functions["generate_subtitles"] = GenerateSubtitles
+	functions["burn_subtitles"] = BurnSubtitles
+	functions["add_subtitle_tracks"] = AddSubtitleTracks
+	functions["convert_subtitles"] = ConvertSubtitles
+	functions["retime_subtitles"] = RetimeSubtitles
+	functions["merge_subtitles"] = MergeSubtitles
+	functions["clean_subtitles"] = CleanSubtitles
+	functions["translate_subtitles"] = TranslateSubtitles
+	functions["change_speed"] = ChangeSpeed
+	functions["reverse_video"] = ReverseVideo
+	functions["loop_video"] = LoopVideo
+	functions["crop_video"] = CropVideo
+	functions["rotate_video"] = RotateVideo
+	functions["flip_video"] = FlipVideo
+	functions["compose_videos"] = ComposeVideos
+	functions["images_to_video"] = ImagesToVideo
 }
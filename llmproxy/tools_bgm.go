@@ -0,0 +1,120 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddBackgroundMusicTool() *pub.ToolDefinition {
+	backgroundMusicParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频或音频文件路径",
+			},
+			"music_file": map[string]interface{}{
+				"type":        "string",
+				"description": "背景音乐文件路径, 比输入短时自动循环, 比输入长时截断",
+			},
+			"music_volume": map[string]interface{}{
+				"type":        "number",
+				"description": "背景音乐音量, 1.0 为原始音量, 默认0.3",
+			},
+			"voice_volume": map[string]interface{}{
+				"type":        "number",
+				"description": "原音频音量, 默认1.0",
+			},
+			"music_start": map[string]interface{}{
+				"type":        "number",
+				"description": "从背景音乐的第几秒开始, 音乐不够长时循环的也是这之后的部分, 默认0",
+			},
+			"fade_in": map[string]interface{}{
+				"type":        "number",
+				"description": "背景音乐淡入时长, 单位秒, 默认2",
+			},
+			"fade_out": map[string]interface{}{
+				"type":        "number",
+				"description": "背景音乐淡出时长, 单位秒, 默认3",
+			},
+			"ducking": map[string]interface{}{
+				"type":        "boolean",
+				"description": "有人声时自动压低背景音乐, 默认 true",
+			},
+			"duck_ratio": map[string]interface{}{
+				"type":        "number",
+				"description": "压低的强度(压缩比), 越大压得越低, 默认8",
+			},
+			"duck_threshold": map[string]interface{}{
+				"type":        "number",
+				"description": "触发压低的原音频电平, 0-1, 越小越容易触发, 默认0.05",
+			},
+		},
+		"required": []string{"input_file", "music_file"},
+	}
+	tool := AddFunctionTool("add_background_music", "给视频或音频添加背景音乐, 支持循环/截断、淡入淡出, 以及有人声时自动压低音乐", backgroundMusicParams)
+	return tool
+}
+
+func AddBackgroundMusic(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for AddBackgroundMusic: %+v", args)
+		return "invalid input_file arguments for AddBackgroundMusic"
+	}
+	musicFile, ok := args["music_file"].(string)
+	if !ok {
+		log.Errorf("invalid music_file arguments for AddBackgroundMusic: %+v", args)
+		return "invalid music_file arguments for AddBackgroundMusic"
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	musicInfo, err := ffprobe.GetMediaFullInfo(musicFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, musicFile)
+		return fmt.Sprintf("error getting music info: %v", err)
+	}
+	if !musicInfo.HasAudio {
+		return "music file has no audio stream"
+	}
+
+	opts := &ffmpegcmd.BackgroundMusicOptions{
+		Duration:      mediaInfo.Duration,
+		HasVoice:      mediaInfo.HasAudio,
+		MusicStart:    getFloatArg(args, "music_start", 0),
+		MusicVolume:   getFloatArg(args, "music_volume", 0.3),
+		VoiceVolume:   getFloatArg(args, "voice_volume", 1.0),
+		FadeIn:        getFloatArg(args, "fade_in", 2),
+		FadeOut:       getFloatArg(args, "fade_out", 3),
+		Ducking:       getBoolArg(args, "ducking", true),
+		DuckRatio:     getFloatArg(args, "duck_ratio", 8),
+		DuckThreshold: getFloatArg(args, "duck_threshold", 0.05),
+		MusicDuration: musicInfo.Duration,
+	}
+	if opts.MusicStart >= musicInfo.Duration {
+		return fmt.Sprintf("music_start %.2f is beyond music duration %.2f", opts.MusicStart, musicInfo.Duration)
+	}
+
+	output := fmt.Sprintf("%s_bgm%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), filepath.Ext(inputFile))
+	log.Infof("Starting to add background music: %s, music:%s, options:%+v, output:%s", inputFile, musicFile, opts, output)
+
+	if err := ffmpegcmd.AddBackgroundMusic(inputFile, musicFile, output, opts); err != nil {
+		log.Errorf("error adding background music: %v", err)
+		return fmt.Sprintf("error adding background music: %v", err)
+	}
+	desc := fmt.Sprintf("添加背景音乐完成, 输出文件: %s, 时长: %.2f秒", output, opts.Duration)
+	if !mediaInfo.HasAudio {
+		desc += ", 原文件没有音频, 只包含背景音乐"
+	}
+	return desc
+}
@@ -0,0 +1,121 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddComposeVideosTool() *pub.ToolDefinition {
+	composeVideosParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_files": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "输入的视频文件路径列表, 至少两个. pip 布局时第一个是主画面",
+			},
+			"layout": map[string]interface{}{
+				"type":        "string",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedComposeLayouts),
+				"description": "pip 画中画, hstack 左右并排, vstack 上下排列, grid 宫格",
+			},
+			"height": map[string]interface{}{
+				"type":        "integer",
+				"description": "hstack/grid 每格的高度, pip 主画面的高度, 默认 720",
+			},
+			"width": map[string]interface{}{
+				"type":        "integer",
+				"description": "vstack 每格的宽度, 默认 1280",
+			},
+			"columns": map[string]interface{}{
+				"type":        "integer",
+				"description": "grid 的列数, 默认自动",
+			},
+			"pip_scale": map[string]interface{}{
+				"type":        "number",
+				"description": "画中画小窗宽度占主画面宽度的比例, 默认 0.3",
+			},
+			"pip_position": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"top_right", "bottom_right", "bottom_left", "top_left"},
+				"description": "第一个小窗的位置, 多个小窗依次放在其他角落, 默认 top_right",
+			},
+			"audio": map[string]interface{}{
+				"type":        "string",
+				"description": "音频来源: 输入序号(从 0 开始), mix 混合所有音频, none 不要音频. 默认 0",
+			},
+			"duration": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"shortest", "longest"},
+				"description": "输出时长: shortest 以最短的视频为准, longest 以最长的为准(短的停在最后一帧), 默认 shortest",
+			},
+		},
+		"required": []string{"input_files", "layout"},
+	}
+	tool := AddFunctionTool("compose_videos", "把多个视频合成到一个画面: 画中画、左右/上下并排或宫格, 常用于对比视频, 并显示进度", composeVideosParams)
+	return tool
+}
+
+func ComposeVideos(args map[string]interface{}) interface{} {
+	log.Infof("ComposeVideos called with args: %+v", args)
+	files := getStringSliceArg(args, "input_files")
+	if len(files) < 2 {
+		return "at least two input_files are required for ComposeVideos"
+	}
+	callId, ok := args["call_id"].(string)
+	if !ok {
+		return "invalid call_id arguments for ComposeVideos"
+	}
+	progressObj, ok := args["progress_cb"].(pub.ProgressCallback)
+	if !ok {
+		return "invalid progress_cb arguments for ComposeVideos"
+	}
+
+	var inputs []ffmpegcmd.ComposeInput
+	for _, f := range files {
+		mediaInfo, err := ffprobe.GetMediaFullInfo(f)
+		if err != nil {
+			log.Errorf("error getting media info: %v, file:%s", err, f)
+			return fmt.Sprintf("error getting media info of %s: %v", f, err)
+		}
+		if !mediaInfo.HasVideo {
+			return fmt.Sprintf("%s has no video stream", f)
+		}
+		w, h := mediaInfo.DisplaySize()
+		inputs = append(inputs, ffmpegcmd.ComposeInput{
+			File:     f,
+			Width:    w,
+			Height:   h,
+			Duration: mediaInfo.Duration,
+			HasAudio: mediaInfo.HasAudio,
+		})
+	}
+
+	opts := &ffmpegcmd.ComposeOptions{
+		Layout:      getStringArg(args, "layout", ""),
+		Height:      getIntArg(args, "height", 0),
+		Width:       getIntArg(args, "width", 0),
+		Columns:     getIntArg(args, "columns", 0),
+		PipScale:    getFloatArg(args, "pip_scale", 0),
+		PipPosition: getStringArg(args, "pip_position", ""),
+		Audio:       getStringArg(args, "audio", ""),
+		Duration:    getStringArg(args, "duration", ""),
+	}
+	output := fmt.Sprintf("%s_%s.mp4", strings.TrimSuffix(files[0], filepath.Ext(files[0])), opts.Layout)
+	ffmpegArgs, duration, err := ffmpegcmd.BuildComposeArgs(inputs, output, opts)
+	if err != nil {
+		log.Errorf("BuildComposeArgs failed: %v, options:%+v", err, opts)
+		return err.Error()
+	}
+
+	log.Infof("Starting to compose videos: %v, options:%+v, output:%s, callId:%s", files, opts, output, callId)
+	go ffmpegcmd.ComposeVideos(callId, ffmpegArgs, duration, progressObj)
+
+	return fmt.Sprintf("多画面合成任务已启动, 布局:%s, 输出时长 %.1f 秒, 输出文件: %s", opts.Layout, duration, output)
+}
@@ -0,0 +1,114 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/utils"
+)
+
+func AddPackageDashTool() *pub.ToolDefinition {
+	vResDesc := "输出的分辨率档位列表, 默认全部档位, 高于源分辨率的档位会被跳过, 可选值: "
+	for res := range ffmpegcmd.VideoResolutions {
+		vResDesc += " " + res
+	}
+	packageDashParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"resolutions": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": vResDesc,
+			},
+			"segment_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "分片时长, 单位秒, 默认4",
+			},
+			"init_seg_name": map[string]interface{}{
+				"type":        "string",
+				"description": "init 分片命名模板, 必须包含 $RepresentationID$, 默认 init-$RepresentationID$.$ext$",
+			},
+			"media_seg_name": map[string]interface{}{
+				"type":        "string",
+				"description": "media 分片命名模板, 必须包含 $RepresentationID$, 默认 chunk-$RepresentationID$-$Number%05d$.$ext$",
+			},
+			"stereo": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否把音频混缩为立体声, 默认 false 保持源声道数(5.1/7.1 保持多声道)",
+			},
+			"use_timeline": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否在 MPD 中使用 SegmentTimeline, 默认 true",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("package_dash", "把视频转码成多档码率的 MPEG-DASH (mpd), 每路音频单独一个 adaptation set, 默认保持源声道数, 并显示进度", packageDashParams)
+	return tool
+}
+
+func PackageDash(args map[string]interface{}) interface{} {
+	log.Infof("PackageDash called with args: %+v", args)
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		return "invalid input_file arguments for PackageDash"
+	}
+	callId, ok := args["call_id"].(string)
+	if !ok {
+		return "invalid call_id arguments for PackageDash"
+	}
+	progressObj, ok := args["progress_cb"].(pub.ProgressCallback)
+	if !ok {
+		return "invalid progress_cb arguments for PackageDash"
+	}
+	opts := &ffmpegcmd.DashOptions{
+		SegmentDuration: getFloatArg(args, "segment_duration", 4),
+		InitSegName:     getStringArg(args, "init_seg_name", ""),
+		MediaSegName:    getStringArg(args, "media_seg_name", ""),
+		UseTimeline:     getBoolArg(args, "use_timeline", true),
+		DownmixStereo:   getBoolArg(args, "stereo", false),
+	}
+
+	probe, err := ffprobe.Probe(inputFile)
+	if err != nil {
+		log.Errorf("error probing media: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if probe.FirstVideo() == nil {
+		return "input file has no video stream"
+	}
+	opts.AudioStreams = len(probe.AudioStreams())
+
+	w, h := probe.DisplaySize()
+	opts.Ladder, err = ffmpegcmd.BuildRenditionLadder(getStringSliceArg(args, "resolutions"), w, h)
+	if err != nil {
+		log.Errorf("BuildRenditionLadder failed: %v, size: %dx%d", err, w, h)
+		return fmt.Sprintf("BuildRenditionLadder failed: %v", err)
+	}
+	if err := ffmpegcmd.CheckDashOptions(opts); err != nil {
+		return fmt.Sprintf("invalid dash options: %v", err)
+	}
+
+	outputDir := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "_dash"
+	if err := utils.EnsureDir(outputDir); err != nil {
+		log.Errorf("error ensuring output directory: %v", err)
+		return fmt.Sprintf("error ensuring output directory: %v", err)
+	}
+	log.Infof("Starting to package dash: %s, options:%+v, outputDir:%s, callId:%s", inputFile, opts, outputDir, callId)
+
+	go ffmpegcmd.PackageDash(callId, inputFile, outputDir, opts, probe.Duration(), progressObj)
+
+	ladderDesc, _ := json.Marshal(opts.Ladder)
+	return fmt.Sprintf("DASH 打包任务已启动, 需要几分钟, 码率档位:%s, 音频流数量:%d\n输出目录: %s, mpd: %s",
+		string(ladderDesc), opts.AudioStreams, outputDir, ffmpegcmd.DashManifest(outputDir))
+}
@@ -0,0 +1,123 @@
+package llmproxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/asrfile"
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/subtitle"
+)
+
+var asrProvider asrfile.Provider
+
+// 提取识别用音频, 测试时替换掉以免依赖 ffmpeg
+var extractAudioForASR = ffmpegcmd.ExtractAudioForASR
+
+// SetASRProvider 设置 generate_subtitles 使用的录音文件识别服务
+func SetASRProvider(provider asrfile.Provider) {
+	asrProvider = provider
+}
+
+func AddGenerateSubtitlesTool() *pub.ToolDefinition {
+	generateSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频或音频文件路径",
+			},
+			"language": map[string]interface{}{
+				"type":        "string",
+				"description": "语音的语言, 例如 zh, en, ja, ko, yue, 默认 zh",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        toInterfaceSlice(subtitle.SupportedFormats),
+				"description": "字幕格式, 默认 srt",
+			},
+			"add_to_video": map[string]interface{}{
+				"type":        "boolean",
+				"description": "生成字幕后是否直接添加到视频中(同 srt_to_video), 默认 false",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("generate_subtitles", "通过语音识别为视频或音频自动生成带时间轴的字幕文件(srt/vtt), 可选直接添加到视频", generateSubtitlesParams)
+	return tool
+}
+
+func GenerateSubtitles(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for GenerateSubtitles: %+v", args)
+		return "invalid input_file arguments for GenerateSubtitles"
+	}
+	if asrProvider == nil {
+		return "speech recognition service is not configured, please set APP_ID, SECRET_ID and SECRET_KEY"
+	}
+	language := getStringArg(args, "language", "zh")
+	format := getStringArg(args, "format", "srt")
+	if _, err := subtitle.Format(nil, format); err != nil {
+		return err.Error()
+	}
+	addToVideo := getBoolArg(args, "add_to_video", false)
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasAudio {
+		return "input file has no audio stream"
+	}
+	if addToVideo && !mediaInfo.HasVideo {
+		return "add_to_video requires a video file"
+	}
+
+	base := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
+	log.Infof("Starting to generate subtitles: %s, provider:%s, language:%s", inputFile, asrProvider.Name(), language)
+	subtitleFile := base + "." + format
+	count, err := recognizeSubtitles(inputFile, base+"_asr.m4a", language, subtitleFile)
+	if err != nil {
+		log.Errorf("error generating subtitles: %v, file:%s", err, inputFile)
+		return err.Error()
+	}
+	desc := fmt.Sprintf("字幕生成完成, 共 %d 条, 字幕文件: %s", count, subtitleFile)
+	if !addToVideo {
+		return desc
+	}
+
+	output := base + "_srt.mp4"
+	if err := ffmpegcmd.Srt2Video(inputFile, subtitleFile, output); err != nil {
+		log.Errorf("error adding subtitles to video: %v", err)
+		return fmt.Sprintf("%s\nerror adding subtitles to video: %v", desc, err)
+	}
+	return fmt.Sprintf("%s\n带字幕的视频: %s", desc, output)
+}
+
+// recognizeSubtitles 提取音频, 语音识别, 按每行 42 字、最多 2 行拆分过长的句子后写出字幕文件, 返回字幕条数
+func recognizeSubtitles(inputFile string, audioFile string, language string, subtitleFile string) (int, error) {
+	if err := extractAudioForASR(inputFile, audioFile); err != nil {
+		return 0, fmt.Errorf("error extracting audio: %v", err)
+	}
+	defer os.Remove(audioFile)
+
+	cues, err := asrProvider.Recognize(audioFile, language)
+	if err != nil {
+		return 0, fmt.Errorf("error recognizing speech: %v", err)
+	}
+	cues = subtitle.FixOverlaps(subtitle.SplitLongLines(cues, 42, 2), 0.04)
+	if len(cues) == 0 {
+		return 0, fmt.Errorf("no speech recognized in the input file")
+	}
+	if err := subtitle.WriteFile(subtitleFile, cues); err != nil {
+		return 0, fmt.Errorf("error writing subtitle file: %v", err)
+	}
+	return len(cues), nil
+}
@@ -0,0 +1,70 @@
+package llmproxy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gollmagent/asrfile"
+	"github.com/gollmagent/subtitle"
+)
+
+func TestRecognizeSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "talk.mp4")
+	audio := filepath.Join(dir, "talk_asr.m4a")
+
+	var extracted string
+	origExtract := extractAudioForASR
+	defer func() { extractAudioForASR = origExtract }()
+	extractAudioForASR = func(inputFile string, outputFile string) error {
+		extracted = inputFile
+		return os.WriteFile(outputFile, []byte("audio"), 0644)
+	}
+	long := strings.Repeat("这是一句很长的识别结果", 12)
+	fake := &asrfile.FakeProvider{Cues: []subtitle.Cue{
+		{Start: 0.5, End: 2, Text: "第一句"},
+		{Start: 1.9, End: 4, Text: "第二句"},
+		{Start: 5, End: 17, Text: long},
+	}}
+	SetASRProvider(fake)
+	defer SetASRProvider(nil)
+
+	for _, format := range []string{"srt", "vtt"} {
+		output := filepath.Join(dir, "talk."+format)
+		count, err := recognizeSubtitles(input, audio, "zh", output)
+		if err != nil {
+			t.Fatalf("recognizeSubtitles(%s) failed: %v", format, err)
+		}
+		if extracted != input || fake.LastAudioFile != audio || fake.LastLanguage != "zh" {
+			t.Errorf("unexpected call arguments, extracted:%s, fake:%+v", extracted, fake)
+		}
+		if _, err := os.Stat(audio); !os.IsNotExist(err) {
+			t.Errorf("temporary audio file should be removed")
+		}
+		cues, err := subtitle.ReadFile(output)
+		if err != nil {
+			t.Fatalf("read %s failed: %v", output, err)
+		}
+		// 过长的句子被拆成多条, 重叠的时间被调整
+		if count != len(cues) || len(cues) <= 3 {
+			t.Fatalf("%s: expected long cue to be split, count:%d, cues:%v", format, count, cues)
+		}
+		if cues[0].End > cues[1].Start {
+			t.Errorf("%s: overlap not fixed: %v", format, cues[:2])
+		}
+		for _, c := range cues {
+			for _, line := range strings.Split(c.Text, "\n") {
+				if n := len([]rune(line)); n > 42 {
+					t.Errorf("%s: line too long (%d): %s", format, n, line)
+				}
+			}
+		}
+	}
+
+	fake.Cues = nil
+	if _, err := recognizeSubtitles(input, audio, "zh", filepath.Join(dir, "empty.srt")); err == nil {
+		t.Errorf("expected error when nothing is recognized")
+	}
+}
@@ -0,0 +1,211 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddCropVideoTool() *pub.ToolDefinition {
+	cropVideoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"rect", "aspect", "auto"},
+				"description": "rect: 按 x/y/width/height 裁剪; aspect: 从画面中心裁出指定比例; auto: 自动检测并去除黑边. 默认 rect",
+			},
+			"x": map[string]interface{}{
+				"type":        "integer",
+				"description": "裁剪区域左上角 x 坐标(像素), 默认 0",
+			},
+			"y": map[string]interface{}{
+				"type":        "integer",
+				"description": "裁剪区域左上角 y 坐标(像素), 默认 0",
+			},
+			"width": map[string]interface{}{
+				"type":        "integer",
+				"description": "裁剪区域宽度(像素), 需为偶数",
+			},
+			"height": map[string]interface{}{
+				"type":        "integer",
+				"description": "裁剪区域高度(像素), 需为偶数",
+			},
+			"aspect": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("目标画面比例, 例如 %s, 也可以是任意 W:H", strings.Join(ffmpegcmd.AspectPresets, ", ")),
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("crop_video", "裁剪视频画面: 指定区域, 按比例(如竖屏 9:16)居中裁剪, 或自动检测去除黑边", cropVideoParams)
+	return tool
+}
+
+func AddRotateVideoTool() *pub.ToolDefinition {
+	rotateVideoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"degrees": map[string]interface{}{
+				"type":        "integer",
+				"enum":        []interface{}{90, 180, 270},
+				"description": "顺时针旋转角度",
+			},
+			"metadata_only": map[string]interface{}{
+				"type":        "boolean",
+				"description": "只修改旋转元数据, 不重新编码, 速度快无损, 但依赖播放器支持, 默认 false",
+			},
+		},
+		"required": []string{"input_file", "degrees"},
+	}
+	tool := AddFunctionTool("rotate_video", "旋转视频画面 90/180/270 度, 可选只修改旋转元数据", rotateVideoParams)
+	return tool
+}
+
+func AddFlipVideoTool() *pub.ToolDefinition {
+	flipVideoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"direction": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"horizontal", "vertical", "both"},
+				"description": "horizontal 水平翻转(镜像), vertical 垂直翻转, both 两者都翻转",
+			},
+		},
+		"required": []string{"input_file", "direction"},
+	}
+	tool := AddFunctionTool("flip_video", "水平或垂直翻转视频画面", flipVideoParams)
+	return tool
+}
+
+func CropVideo(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for CropVideo: %+v", args)
+		return "invalid input_file arguments for CropVideo"
+	}
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+	// ffmpeg 默认自动旋转, 裁剪区域按显示尺寸计算
+	w, h := mediaInfo.DisplaySize()
+
+	var rect ffmpegcmd.CropRect
+	mode := getStringArg(args, "mode", "rect")
+	switch mode {
+	case "rect":
+		rect = ffmpegcmd.CropRect{
+			X:      getIntArg(args, "x", 0),
+			Y:      getIntArg(args, "y", 0),
+			Width:  getIntArg(args, "width", 0),
+			Height: getIntArg(args, "height", 0),
+		}
+	case "aspect":
+		rect, err = ffmpegcmd.AspectCropRect(w, h, getStringArg(args, "aspect", ""))
+	case "auto":
+		rect, err = ffmpegcmd.DetectCrop(inputFile, mediaInfo.Duration, 0)
+	default:
+		return fmt.Sprintf("invalid mode: %s, should be rect, aspect or auto", mode)
+	}
+	if err != nil {
+		log.Errorf("error computing crop rect: %v, args:%+v", err, args)
+		return fmt.Sprintf("error computing crop rect: %v", err)
+	}
+	if err := rect.Validate(w, h); err != nil {
+		return err.Error()
+	}
+	if mode == "auto" && rect.Width == w && rect.Height == h {
+		return fmt.Sprintf("no black bars detected in %s (%dx%d), nothing to crop", inputFile, w, h)
+	}
+
+	output := fmt.Sprintf("%s_crop.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)))
+	log.Infof("Starting to crop video: %s, mode:%s, rect:%+v, output:%s", inputFile, mode, rect, output)
+	if err := ffmpegcmd.TransformVideo(inputFile, output, rect.Filter()); err != nil {
+		log.Errorf("error cropping video: %v", err)
+		return fmt.Sprintf("error cropping video: %v", err)
+	}
+	rectDesc, _ := json.Marshal(rect)
+	return fmt.Sprintf("裁剪完成, 裁剪区域:%s, 输出文件: %s", string(rectDesc), output)
+}
+
+func RotateVideo(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for RotateVideo: %+v", args)
+		return "invalid input_file arguments for RotateVideo"
+	}
+	degrees := getIntArg(args, "degrees", 0)
+	filter, err := ffmpegcmd.RotateFilter(degrees)
+	if err != nil {
+		return err.Error()
+	}
+	base := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
+
+	if getBoolArg(args, "metadata_only", false) {
+		mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+		if err != nil {
+			log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+			return fmt.Sprintf("error getting media info: %v", err)
+		}
+		rotation := (mediaInfo.Rotation + degrees) % 360
+		output := fmt.Sprintf("%s_rotate%d%s", base, degrees, filepath.Ext(inputFile))
+		log.Infof("Starting to set display rotation: %s, %d -> %d, output:%s", inputFile, mediaInfo.Rotation, rotation, output)
+		if err := ffmpegcmd.SetDisplayRotation(inputFile, output, rotation); err != nil {
+			log.Errorf("error setting display rotation: %v", err)
+			return fmt.Sprintf("error setting display rotation: %v", err)
+		}
+		return fmt.Sprintf("旋转元数据已修改为顺时针 %d 度, 输出文件: %s", rotation, output)
+	}
+
+	output := fmt.Sprintf("%s_rotate%d.mp4", base, degrees)
+	log.Infof("Starting to rotate video: %s, degrees:%d, output:%s", inputFile, degrees, output)
+	if err := ffmpegcmd.TransformVideo(inputFile, output, filter); err != nil {
+		log.Errorf("error rotating video: %v", err)
+		return fmt.Sprintf("error rotating video: %v", err)
+	}
+	return fmt.Sprintf("旋转完成, 输出文件: %s", output)
+}
+
+func FlipVideo(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for FlipVideo: %+v", args)
+		return "invalid input_file arguments for FlipVideo"
+	}
+	direction := getStringArg(args, "direction", "horizontal")
+	filter, err := ffmpegcmd.FlipFilter(direction)
+	if err != nil {
+		return err.Error()
+	}
+
+	output := fmt.Sprintf("%s_flip.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)))
+	log.Infof("Starting to flip video: %s, direction:%s, output:%s", inputFile, direction, output)
+	if err := ffmpegcmd.TransformVideo(inputFile, output, filter); err != nil {
+		log.Errorf("error flipping video: %v", err)
+		return fmt.Sprintf("error flipping video: %v", err)
+	}
+	return fmt.Sprintf("翻转完成, 输出文件: %s", output)
+}
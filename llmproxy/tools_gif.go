@@ -0,0 +1,117 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddVideoToGifTool() *pub.ToolDefinition {
+	videoToGifParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"start": map[string]interface{}{
+				"type":        "string",
+				"description": "开始时间, 秒数或 HH:MM:SS 格式, 默认0",
+			},
+			"duration": map[string]interface{}{
+				"type":        "number",
+				"description": "时长, 单位秒, 默认到视频结尾",
+			},
+			"fps": map[string]interface{}{
+				"type":        "integer",
+				"description": "帧率, 默认12",
+			},
+			"width": map[string]interface{}{
+				"type":        "integer",
+				"description": "输出宽度, 高度按比例计算, 默认480",
+			},
+			"loop": map[string]interface{}{
+				"type":        "integer",
+				"description": "播放次数, 0表示无限循环(默认), 1表示只播放一次",
+			},
+			"dither": map[string]interface{}{
+				"type":        "string",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedGifDithers),
+				"description": "gif 调色板抖动算法, 默认 sierra2_4a, bayer 文件更小, none 无抖动",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"gif", "webp"},
+				"description": "输出格式: gif(默认) 或 webp(动态webp, 体积更小)",
+			},
+			"max_size_mb": map[string]interface{}{
+				"type":        "number",
+				"description": "目标文件大小上限, 单位MB(1MB=1000*1000字节), 超过时自动降低帧率和宽度重新生成",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("video_to_gif", "把视频片段转换为高质量 gif 或动态 webp 图片", videoToGifParams)
+	return tool
+}
+
+func toInterfaceSlice(items []string) []interface{} {
+	ret := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item)
+	}
+	return ret
+}
+
+func VideoToGif(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for VideoToGif: %+v", args)
+		return "invalid input_file arguments for VideoToGif"
+	}
+	start, _, err := getTimeArg(args, "start")
+	if err != nil {
+		return err.Error()
+	}
+	opts := &ffmpegcmd.GifOptions{
+		Start:    start,
+		Duration: getFloatArg(args, "duration", 0),
+		Fps:      getIntArg(args, "fps", 12),
+		Width:    getIntArg(args, "width", 480),
+		Loop:     getIntArg(args, "loop", 0),
+		Dither:   getStringArg(args, "dither", "sierra2_4a"),
+		Format:   getStringArg(args, "format", "gif"),
+		MaxBytes: int64(getFloatArg(args, "max_size_mb", 0) * ffmpegcmd.BytesPerMB),
+	}
+	if opts.Format != "gif" && opts.Format != "webp" {
+		return fmt.Sprintf("unsupported format: %s", opts.Format)
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+	if opts.Start >= mediaInfo.Duration {
+		return fmt.Sprintf("start %.2f is beyond video duration %.2f", opts.Start, mediaInfo.Duration)
+	}
+
+	output := fmt.Sprintf("%s.%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), opts.Format)
+	log.Infof("Starting to convert video to %s: %s, options:%+v, output:%s", opts.Format, inputFile, opts, output)
+
+	used, size, err := ffmpegcmd.VideoToAnimatedImageWithLimit(inputFile, output, opts)
+	if err != nil {
+		log.Errorf("error converting video to %s: %v", opts.Format, err)
+		return fmt.Sprintf("error converting video to %s: %v", opts.Format, err)
+	}
+	return fmt.Sprintf("输出文件: %s, 大小: %.2fMB, 帧率: %d, 宽度: %d",
+		output, float64(size)/ffmpegcmd.BytesPerMB, used.Fps, used.Width)
+}
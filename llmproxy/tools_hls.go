@@ -0,0 +1,110 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/utils"
+)
+
+func AddPackageHLSTool() *pub.ToolDefinition {
+	vResDesc := "输出的分辨率档位列表, 默认全部档位, 高于源分辨率的档位会被跳过, 可选值: "
+	for res := range ffmpegcmd.VideoResolutions {
+		vResDesc += " " + res
+	}
+	packageHLSParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"resolutions": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": vResDesc,
+			},
+			"segment_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "分片时长, 单位秒, 默认6",
+			},
+			"segment_type": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"ts", "fmp4"},
+				"description": "分片格式: ts(默认) 或 fmp4",
+			},
+			"encrypt": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否使用 AES-128 加密分片, 会自动生成 key",
+			},
+			"key_url": map[string]interface{}{
+				"type":        "string",
+				"description": "加密时播放列表中的 key 地址, 默认与播放列表同目录的 enc.key",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("package_hls", "把视频转码成多档码率的 HLS (m3u8), 生成 master 播放列表, 并显示进度", packageHLSParams)
+	return tool
+}
+
+func PackageHLS(args map[string]interface{}) interface{} {
+	log.Infof("PackageHLS called with args: %+v", args)
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		return "invalid input_file arguments for PackageHLS"
+	}
+	callId, ok := args["call_id"].(string)
+	if !ok {
+		return "invalid call_id arguments for PackageHLS"
+	}
+	progressObj, ok := args["progress_cb"].(pub.ProgressCallback)
+	if !ok {
+		return "invalid progress_cb arguments for PackageHLS"
+	}
+	opts := &ffmpegcmd.HLSOptions{
+		SegmentDuration: getFloatArg(args, "segment_duration", 6),
+		SegmentType:     getStringArg(args, "segment_type", "ts"),
+		Encrypt:         getBoolArg(args, "encrypt", false),
+		KeyURL:          getStringArg(args, "key_url", ""),
+	}
+	if opts.SegmentType != "ts" && opts.SegmentType != "fmp4" {
+		return fmt.Sprintf("unsupported segment_type: %s", opts.SegmentType)
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+	opts.HasAudio = mediaInfo.HasAudio
+
+	w, h := mediaInfo.DisplaySize()
+	opts.Ladder, err = ffmpegcmd.BuildRenditionLadder(getStringSliceArg(args, "resolutions"), w, h)
+	if err != nil {
+		log.Errorf("BuildRenditionLadder failed: %v, mediaInfo: %+v", err, mediaInfo)
+		return fmt.Sprintf("BuildRenditionLadder failed: %v", err)
+	}
+
+	outputDir := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "_hls"
+	if err := utils.EnsureDir(outputDir); err != nil {
+		log.Errorf("error ensuring output directory: %v", err)
+		return fmt.Sprintf("error ensuring output directory: %v", err)
+	}
+	log.Infof("Starting to package hls: %s, options:%+v, outputDir:%s, callId:%s", inputFile, opts, outputDir, callId)
+
+	go ffmpegcmd.PackageHLS(callId, inputFile, outputDir, opts, mediaInfo.Duration, progressObj)
+
+	ladderDesc, _ := json.Marshal(opts.Ladder)
+	return fmt.Sprintf("HLS 打包任务已启动, 需要几分钟, 码率档位:%s\n输出目录: %s, master 播放列表: %s",
+		string(ladderDesc), outputDir, ffmpegcmd.HLSMasterPlaylist(outputDir))
+}
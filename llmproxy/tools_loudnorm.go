@@ -0,0 +1,84 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddNormalizeLoudnessTool() *pub.ToolDefinition {
+	normalizeLoudnessParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的音频或视频文件路径, 视频、字幕和章节直接拷贝; 只处理并保留第一路音频, 其他音轨会被丢掉",
+			},
+			"preset": map[string]interface{}{
+				"type": "string",
+				"enum": toInterfaceSlice(ffmpegcmd.LoudnessPresetNames()),
+				"description": "响度预设, 默认 streaming: streaming/youtube/spotify -14 LUFS, podcast/apple -16 LUFS, " +
+					"ebu_r128 -23 LUFS(广播), atsc -24 LUFS(北美广播)",
+			},
+			"target_i": map[string]interface{}{
+				"type":        "number",
+				"description": "目标综合响度 LUFS, 范围 -70 到 -5, 设置后覆盖预设",
+			},
+			"target_tp": map[string]interface{}{
+				"type":        "number",
+				"description": "真峰值上限 dBTP, 范围 -9 到 0, 设置后覆盖预设",
+			},
+			"target_lra": map[string]interface{}{
+				"type":        "number",
+				"description": "响度范围 LU, 范围 1 到 50, 设置后覆盖预设",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("normalize_loudness", "按 EBU R128 两遍 loudnorm 把音频或视频的响度统一到目标值(LUFS)", normalizeLoudnessParams)
+	return tool
+}
+
+func NormalizeLoudness(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for NormalizeLoudness: %+v", args)
+		return "invalid input_file arguments for NormalizeLoudness"
+	}
+	preset := getStringArg(args, "preset", "streaming")
+	target, ok := ffmpegcmd.LoudnessPresets[preset]
+	if !ok {
+		return fmt.Sprintf("unsupported preset: %s, supported: %v", preset, ffmpegcmd.LoudnessPresetNames())
+	}
+	target.I = getFloatArg(args, "target_i", target.I)
+	target.TP = getFloatArg(args, "target_tp", target.TP)
+	target.LRA = getFloatArg(args, "target_lra", target.LRA)
+	if err := target.Validate(); err != nil {
+		return fmt.Sprintf("invalid loudness target: %v", err)
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasAudio {
+		return "input file has no audio stream"
+	}
+
+	output := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "_loudnorm" + filepath.Ext(inputFile)
+	log.Infof("Starting to normalize loudness: %s, target:%+v, output:%s", inputFile, target, output)
+
+	m, err := ffmpegcmd.NormalizeLoudness(inputFile, output, target, mediaInfo.SampleRate)
+	if err != nil {
+		log.Errorf("error normalizing loudness: %v", err)
+		return fmt.Sprintf("error normalizing loudness: %v", err)
+	}
+	return fmt.Sprintf("响度标准化完成, 输出文件: %s\n原始响度: %s LUFS, 真峰值: %s dBTP, 响度范围: %s LU; 目标: %g LUFS, %g dBTP, %g LU",
+		output, m.InputI, m.InputTP, m.InputLRA, target.I, target.TP, target.LRA)
+}
@@ -0,0 +1,75 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+// mediaStreamInfo 在 ffprobe 原始字段之外补充计算好的显示尺寸, 旋转和 HDR 信息, 方便模型直接使用
+type mediaStreamInfo struct {
+	*ffprobe.Stream
+	DisplayWidth  int     `json:"display_width,omitempty"`
+	DisplayHeight int     `json:"display_height,omitempty"`
+	Rotation      int     `json:"rotation,omitempty"`
+	FrameRateFps  float64 `json:"frame_rate_fps,omitempty"`
+	HDR           string  `json:"hdr,omitempty"`
+}
+
+type mediaInfoResult struct {
+	Format   ffprobe.Format    `json:"format"`
+	Streams  []mediaStreamInfo `json:"streams"`
+	Chapters []ffprobe.Chapter `json:"chapters,omitempty"`
+}
+
+func AddGetMediaInfoTool() *pub.ToolDefinition {
+	getMediaInfoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的多媒体文件路径",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("get_media_info", "获取多媒体文件的完整信息: 所有音视频字幕流, 码率, 像素格式, 旋转, HDR, 语言, 章节和容器元数据", getMediaInfoParams)
+	return tool
+}
+
+func GetMediaInfo(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		return "invalid input_file arguments for GetMediaInfo"
+	}
+
+	pr, err := ffprobe.Probe(inputFile)
+	if err != nil {
+		log.Errorf("error probing media file: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+
+	result := mediaInfoResult{
+		Format:   pr.Format,
+		Chapters: pr.Chapters,
+	}
+	for i := range pr.Streams {
+		s := &pr.Streams[i]
+		item := mediaStreamInfo{Stream: s}
+		if s.IsVideo() {
+			item.DisplayWidth, item.DisplayHeight = s.DisplaySize()
+			item.Rotation = s.Rotation()
+			item.FrameRateFps = s.FrameRate()
+			item.HDR = s.HDRFormat()
+		}
+		result.Streams = append(result.Streams, item)
+	}
+	data, err := json.Marshal(&result)
+	if err != nil {
+		return fmt.Sprintf("error marshaling media info: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,204 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/medialib"
+	"github.com/gollmagent/pub"
+)
+
+const kMediaSearchLimit = 50
+
+var mediaLibrary *medialib.MediaLibrary
+
+func SetMediaLibrary(lib *medialib.MediaLibrary) {
+	mediaLibrary = lib
+}
+
+type mediaItem struct {
+	Path        string  `json:"path"`
+	SizeMB      float64 `json:"size_mb"`
+	ModTime     string  `json:"mod_time"`
+	Duration    float64 `json:"duration"`
+	Width       int     `json:"width,omitempty"`
+	Height      int     `json:"height,omitempty"`
+	Orientation string  `json:"orientation,omitempty"`
+	VideoCodec  string  `json:"video_codec,omitempty"`
+	AudioCodec  string  `json:"audio_codec,omitempty"`
+	HasAudio    bool    `json:"has_audio"`
+}
+
+func AddListMediaTool() *pub.ToolDefinition {
+	listMediaParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"dir": map[string]interface{}{
+				"type":        "string",
+				"description": "只列出该目录下的文件, 不填则列出媒体库全部目录",
+			},
+			"rescan": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否先重新扫描目录, 默认true, 只会探测新增或修改过的文件",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("最多返回的文件数量, 默认%d", kMediaSearchLimit),
+			},
+		},
+		"required": []string{},
+	}
+	tool := AddFunctionTool("list_media", "列出媒体库目录中的多媒体文件及其时长、分辨率、编码等信息", listMediaParams)
+	return tool
+}
+
+func AddSearchMediaTool() *pub.ToolDefinition {
+	searchMediaParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"dir": map[string]interface{}{
+				"type":        "string",
+				"description": "只搜索该目录下的文件",
+			},
+			"name_contains": map[string]interface{}{
+				"type":        "string",
+				"description": "文件名包含的关键字",
+			},
+			"min_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "最小时长, 单位秒",
+			},
+			"max_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "最大时长, 单位秒",
+			},
+			"min_width": map[string]interface{}{
+				"type":        "integer",
+				"description": "最小视频宽度",
+			},
+			"min_height": map[string]interface{}{
+				"type":        "integer",
+				"description": "最小视频高度",
+			},
+			"orientation": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"vertical", "horizontal", "square"},
+				"description": "画面方向: vertical(竖屏), horizontal(横屏), square(方形)",
+			},
+			"video_codec": map[string]interface{}{
+				"type":        "string",
+				"description": "视频编码, 例如 h264, hevc, vp9, av1",
+			},
+			"audio_codec": map[string]interface{}{
+				"type":        "string",
+				"description": "音频编码, 例如 aac, opus, mp3",
+			},
+			"has_video": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否包含视频流",
+			},
+			"has_audio": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否包含音频流",
+			},
+			"modified_after": map[string]interface{}{
+				"type":        "string",
+				"description": "修改日期不早于, 格式 YYYY-MM-DD",
+			},
+			"modified_before": map[string]interface{}{
+				"type":        "string",
+				"description": "修改日期早于, 格式 YYYY-MM-DD",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("最多返回的文件数量, 默认%d", kMediaSearchLimit),
+			},
+		},
+		"required": []string{},
+	}
+	tool := AddFunctionTool("search_media", "按时长、分辨率、横竖屏、编码、是否有音频、修改日期等条件搜索媒体库中的文件", searchMediaParams)
+	return tool
+}
+
+func ListMedia(args map[string]interface{}) interface{} {
+	filter := &medialib.SearchFilter{
+		Dir:   getStringArg(args, "dir", ""),
+		Limit: getIntArg(args, "limit", kMediaSearchLimit),
+	}
+	return searchMediaLibrary(filter, getBoolArg(args, "rescan", true))
+}
+
+func SearchMedia(args map[string]interface{}) interface{} {
+	filter := &medialib.SearchFilter{
+		Dir:          getStringArg(args, "dir", ""),
+		NameContains: getStringArg(args, "name_contains", ""),
+		MinDuration:  getFloatArg(args, "min_duration", 0),
+		MaxDuration:  getFloatArg(args, "max_duration", 0),
+		MinWidth:     getIntArg(args, "min_width", 0),
+		MinHeight:    getIntArg(args, "min_height", 0),
+		Orientation:  getStringArg(args, "orientation", ""),
+		VideoCodec:   getStringArg(args, "video_codec", ""),
+		AudioCodec:   getStringArg(args, "audio_codec", ""),
+		Limit:        getIntArg(args, "limit", kMediaSearchLimit),
+	}
+	if hasArg(args, "has_video") {
+		hasVideo := getBoolArg(args, "has_video", true)
+		filter.HasVideo = &hasVideo
+	}
+	if hasArg(args, "has_audio") {
+		hasAudio := getBoolArg(args, "has_audio", true)
+		filter.HasAudio = &hasAudio
+	}
+	var err error
+	if after := getStringArg(args, "modified_after", ""); len(after) > 0 {
+		filter.ModifiedAfter, err = time.ParseInLocation("2006-01-02", after, time.Local)
+		if err != nil {
+			return fmt.Sprintf("invalid modified_after: %s, should be YYYY-MM-DD", after)
+		}
+	}
+	if before := getStringArg(args, "modified_before", ""); len(before) > 0 {
+		filter.ModifiedBefore, err = time.ParseInLocation("2006-01-02", before, time.Local)
+		if err != nil {
+			return fmt.Sprintf("invalid modified_before: %s, should be YYYY-MM-DD", before)
+		}
+	}
+	return searchMediaLibrary(filter, true)
+}
+
+func searchMediaLibrary(filter *medialib.SearchFilter, rescan bool) string {
+	if mediaLibrary == nil || len(mediaLibrary.Dirs()) == 0 {
+		return "media library is not configured, please start agent with -mediadirs"
+	}
+	if rescan {
+		if _, err := mediaLibrary.Scan(); err != nil {
+			log.Errorf("media library scan failed: %v", err)
+			return fmt.Sprintf("media library scan failed: %v", err)
+		}
+	}
+	log.Infof("Searching media library with filter: %+v", filter)
+
+	entries := mediaLibrary.Search(filter)
+	items := make([]mediaItem, 0, len(entries))
+	for _, entry := range entries {
+		width, height := entry.Info.DisplaySize()
+		items = append(items, mediaItem{
+			Path:        entry.Path,
+			SizeMB:      float64(entry.Size*100/1024/1024) / 100,
+			ModTime:     entry.ModTime.Format("2006-01-02 15:04:05"),
+			Duration:    entry.Info.Duration,
+			Width:       width,
+			Height:      height,
+			Orientation: entry.Orientation(),
+			VideoCodec:  entry.Info.VideoCodec,
+			AudioCodec:  entry.Info.AudioCodec,
+			HasAudio:    entry.Info.HasAudio,
+		})
+	}
+	if len(items) == 0 {
+		return "no media files matched"
+	}
+	data, _ := json.Marshal(items)
+	return fmt.Sprintf("共找到 %d 个文件: %s", len(items), string(data))
+}
@@ -0,0 +1,84 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddCompareQualityTool() *pub.ToolDefinition {
+	compareQualityParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"reference_file": map[string]interface{}{
+				"type":        "string",
+				"description": "参考视频(原始文件)路径",
+			},
+			"distorted_file": map[string]interface{}{
+				"type":        "string",
+				"description": "待评估的视频(例如转码后的文件)路径, 分辨率不同时会缩放到参考视频的尺寸",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{ffmpegcmd.QualityMethodAuto, ffmpegcmd.QualityMethodVMAF, ffmpegcmd.QualityMethodPSNRSSIM},
+				"description": "评估方法, 默认 auto: ffmpeg 支持 libvmaf 时用 vmaf, 否则用 psnr 和 ssim",
+			},
+			"segment_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "分段统计的时长, 单位秒, 默认10",
+			},
+			"worst_count": map[string]interface{}{
+				"type":        "integer",
+				"description": "返回质量最差的时刻个数, 默认5",
+			},
+		},
+		"required": []string{"reference_file", "distorted_file"},
+	}
+	tool := AddFunctionTool("compare_quality", "对比两个视频的客观画质(VMAF 或 PSNR/SSIM), 返回整体分数、分段分数和最差时刻", compareQualityParams)
+	return tool
+}
+
+func CompareQuality(args map[string]interface{}) interface{} {
+	reference, ok := args["reference_file"].(string)
+	if !ok {
+		log.Errorf("invalid reference_file arguments for CompareQuality: %+v", args)
+		return "invalid reference_file arguments for CompareQuality"
+	}
+	distorted, ok := args["distorted_file"].(string)
+	if !ok {
+		log.Errorf("invalid distorted_file arguments for CompareQuality: %+v", args)
+		return "invalid distorted_file arguments for CompareQuality"
+	}
+
+	probe, err := ffprobe.Probe(reference)
+	if err != nil {
+		log.Errorf("error probing reference: %v, file:%s", err, reference)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	video := probe.FirstVideo()
+	if video == nil {
+		return "reference file has no video stream"
+	}
+	w, h := probe.DisplaySize()
+	opts := &ffmpegcmd.QualityOptions{
+		Method:          getStringArg(args, "method", ffmpegcmd.QualityMethodAuto),
+		RefWidth:        w,
+		RefHeight:       h,
+		RefFps:          video.FrameRate(),
+		SegmentDuration: getFloatArg(args, "segment_duration", 10),
+		WorstCount:      getIntArg(args, "worst_count", 5),
+	}
+	log.Infof("Starting to compare quality: reference:%s, distorted:%s, options:%+v", reference, distorted, opts)
+
+	report, err := ffmpegcmd.CompareQuality(distorted, reference, opts)
+	if err != nil {
+		log.Errorf("error comparing quality: %v", err)
+		return fmt.Sprintf("error comparing quality: %v", err)
+	}
+	reportDesc, _ := json.Marshal(report)
+	return fmt.Sprintf("画质对比结果(时间单位秒): %s", string(reportDesc))
+}
@@ -0,0 +1,123 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/utils"
+)
+
+func AddDetectScenesTool() *pub.ToolDefinition {
+	detectScenesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"threshold": map[string]interface{}{
+				"type":        "number",
+				"description": "场景变化阈值, 0-1 之间, 越小越敏感, 默认0.3",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"select", "scdet"},
+				"description": "检测方式: select(场景分数, 默认) 或 scdet 滤镜",
+			},
+			"min_scene_length": map[string]interface{}{
+				"type":        "number",
+				"description": "两个场景之间的最小间隔, 单位秒, 默认1",
+			},
+			"thumbnails": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否为每个场景截取一张缩略图, 默认false",
+			},
+			"write_chapters": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否把场景作为章节写入输出文件, 默认false",
+			},
+			"chapter_format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"mp4", "mkv"},
+				"description": "写入章节的输出文件格式, 默认mp4",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("detect_scenes", "检测视频的场景切换(镜头变化)时间点, 可选生成每个场景的缩略图和章节", detectScenesParams)
+	return tool
+}
+
+func DetectScenes(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for DetectScenes: %+v", args)
+		return "invalid input_file arguments for DetectScenes"
+	}
+	opts := &ffmpegcmd.SceneDetectOptions{
+		Threshold:      getFloatArg(args, "threshold", 0.3),
+		Method:         getStringArg(args, "method", "select"),
+		MinSceneLength: getFloatArg(args, "min_scene_length", 1),
+	}
+	chapterFormat := getStringArg(args, "chapter_format", "mp4")
+	if chapterFormat != "mp4" && chapterFormat != "mkv" {
+		return fmt.Sprintf("unsupported chapter_format: %s", chapterFormat)
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+
+	log.Infof("Starting to detect scenes: %s, options:%+v", inputFile, opts)
+	scenes, err := ffmpegcmd.DetectScenes(inputFile, opts)
+	if err != nil {
+		log.Errorf("error detecting scenes: %v", err)
+		return fmt.Sprintf("error detecting scenes: %v", err)
+	}
+
+	baseName := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
+	result := fmt.Sprintf("检测到 %d 个场景切换", len(scenes))
+
+	if getBoolArg(args, "thumbnails", false) && len(scenes) > 0 {
+		outputDir := baseName + "_scenes"
+		if err := utils.EnsureDir(outputDir); err != nil {
+			log.Errorf("error ensuring output directory: %v", err)
+			return fmt.Sprintf("error ensuring output directory: %v", err)
+		}
+		for i := range scenes {
+			thumbnail := filepath.Join(outputDir, fmt.Sprintf("scene_%03d.jpg", i+1))
+			err := ffmpegcmd.ScreenshotOnePictureAtMoment(inputFile, fmt.Sprintf("%.3f", scenes[i].Time), thumbnail)
+			if err != nil {
+				log.Errorf("error generating scene thumbnail: %v, time:%.3f", err, scenes[i].Time)
+				continue
+			}
+			scenes[i].Thumbnail = thumbnail
+		}
+		result += fmt.Sprintf(", 缩略图目录: %s", outputDir)
+	}
+
+	if getBoolArg(args, "write_chapters", false) {
+		chapters := ffmpegcmd.ChaptersFromScenes(scenes, mediaInfo.Duration)
+		output := fmt.Sprintf("%s_chapters.%s", baseName, chapterFormat)
+		log.Infof("Writing %d chapters to %s", len(chapters), output)
+		if err := ffmpegcmd.WriteChapters(inputFile, chapters, output); err != nil {
+			log.Errorf("error writing chapters: %v", err)
+			return fmt.Sprintf("error writing chapters: %v", err)
+		}
+		result += fmt.Sprintf(", 已写入 %d 个章节, 输出文件: %s", len(chapters), output)
+	}
+
+	scenesDesc, _ := json.Marshal(scenes)
+	return fmt.Sprintf("%s, 场景列表(time为秒, score为变化程度): %s", result, string(scenesDesc))
+}
@@ -0,0 +1,129 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func silenceParams(extra map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{
+		"input_file": map[string]interface{}{
+			"type":        "string",
+			"description": "输入的音频或视频文件路径",
+		},
+		"noise_db": map[string]interface{}{
+			"type":        "number",
+			"description": "静音阈值, 单位dB, 低于这个音量算静音, 默认-30",
+		},
+		"min_silence": map[string]interface{}{
+			"type":        "number",
+			"description": "静音至少持续多少秒才算, 默认0.5",
+		},
+	}
+	for k, v := range extra {
+		properties[k] = v
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"input_file"},
+	}
+}
+
+func AddDetectSilenceTool() *pub.ToolDefinition {
+	tool := AddFunctionTool("detect_silence", "检测音频或视频中的静音时间段", silenceParams(nil))
+	return tool
+}
+
+func AddRemoveSilenceTool() *pub.ToolDefinition {
+	params := silenceParams(map[string]interface{}{
+		"min_gap": map[string]interface{}{
+			"type":        "number",
+			"description": "只删除长度不小于这个值(秒)的静音, 默认1",
+		},
+		"padding": map[string]interface{}{
+			"type":        "number",
+			"description": "每段静音两端保留的时长(秒), 避免切掉语音的开头和结尾, 默认0.25",
+		},
+	})
+	tool := AddFunctionTool("remove_silence", "自动删除音频或视频中的静音片段(例如录屏和讲座的空白), 输出紧凑的文件并给出删除的时长", params)
+	return tool
+}
+
+func detectSilence(args map[string]interface{}, funcName string) (string, ffprobe.FullInfo, []ffmpegcmd.TimeRange, error) {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for %s: %+v", funcName, args)
+		return "", ffprobe.FullInfo{}, nil, fmt.Errorf("invalid input_file arguments for %s", funcName)
+	}
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return "", mediaInfo, nil, fmt.Errorf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasAudio {
+		return "", mediaInfo, nil, fmt.Errorf("input file has no audio stream")
+	}
+	opts := &ffmpegcmd.SilenceOptions{
+		NoiseDB:    getFloatArg(args, "noise_db", -30),
+		MinSilence: getFloatArg(args, "min_silence", 0.5),
+	}
+	log.Infof("Starting to detect silence: %s, options:%+v", inputFile, opts)
+	silences, err := ffmpegcmd.DetectSilence(inputFile, opts, mediaInfo.Duration)
+	if err != nil {
+		log.Errorf("error detecting silence: %v", err)
+		return "", mediaInfo, nil, fmt.Errorf("error detecting silence: %v", err)
+	}
+	return inputFile, mediaInfo, silences, nil
+}
+
+func DetectSilence(args map[string]interface{}) interface{} {
+	_, mediaInfo, silences, err := detectSilence(args, "DetectSilence")
+	if err != nil {
+		return err.Error()
+	}
+	silencesDesc, _ := json.Marshal(silences)
+	return fmt.Sprintf("检测到 %d 段静音, 共 %.2f秒 (总时长 %.2f秒), 静音时间段(秒): %s",
+		len(silences), ffmpegcmd.TotalDuration(silences), mediaInfo.Duration, string(silencesDesc))
+}
+
+func RemoveSilence(args map[string]interface{}) interface{} {
+	inputFile, mediaInfo, silences, err := detectSilence(args, "RemoveSilence")
+	if err != nil {
+		return err.Error()
+	}
+	minGap := getFloatArg(args, "min_gap", 1)
+	padding := getFloatArg(args, "padding", 0.25)
+	keep, removed := ffmpegcmd.BuildSilenceKeepRanges(silences, mediaInfo.Duration, padding, minGap)
+	if removed <= 0 {
+		return fmt.Sprintf("没有找到长于 %.2f秒 的静音, 无需处理", minGap)
+	}
+	if len(keep) == 0 {
+		return "the whole file is silent, nothing to keep"
+	}
+
+	output := fmt.Sprintf("%s_nosilence%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), filepath.Ext(inputFile))
+	log.Infof("Starting to remove silence: %s, keep:%+v, removed:%.2f, output:%s", inputFile, keep, removed, output)
+
+	// 删除的片段很多且不在关键帧上, 必须重新编码才能精确拼接
+	err = ffmpegcmd.TrimMedia(inputFile, output, &ffmpegcmd.TrimOptions{
+		Ranges:   keep,
+		Accurate: true,
+		HasVideo: mediaInfo.HasVideo,
+		HasAudio: mediaInfo.HasAudio,
+	})
+	if err != nil {
+		log.Errorf("error removing silence: %v", err)
+		return fmt.Sprintf("error removing silence: %v", err)
+	}
+	keepDesc, _ := json.Marshal(keep)
+	return fmt.Sprintf("删除静音完成, 输出文件: %s, 删除了 %.2f秒, 时长 %.2f秒 -> %.2f秒, 保留的时间段(秒): %s",
+		output, removed, mediaInfo.Duration, ffmpegcmd.TotalDuration(keep), string(keepDesc))
+}
@@ -0,0 +1,124 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddImagesToVideoTool() *pub.ToolDefinition {
+	imagesToVideoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"images": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "图片文件路径列表, 按顺序播放, 与 image_dir 二选一",
+			},
+			"image_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "图片目录, 按文件名顺序使用其中的 jpg/png/webp/bmp 图片",
+			},
+			"image_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "每张图片显示的秒数, 默认 3",
+			},
+			"transition": map[string]interface{}{
+				"type":        "string",
+				"enum":        toInterfaceSlice(ffmpegcmd.SupportedTransitions),
+				"description": "图片之间的转场效果, 默认 fade, none 表示直接切换",
+			},
+			"transition_duration": map[string]interface{}{
+				"type":        "number",
+				"description": "转场时长(秒), 默认 1, 必须小于 image_duration",
+			},
+			"ken_burns": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否使用缩放平移的动态效果, 默认 true; false 时静止显示完整图片",
+			},
+			"resolution": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"480p", "720p", "1080p"},
+				"description": "输出分辨率(短边), 默认 1080p",
+			},
+			"aspect": map[string]interface{}{
+				"type":        "string",
+				"description": "画面比例, 例如 16:9, 9:16, 1:1, 4:3, 默认 16:9",
+			},
+			"music_file": map[string]interface{}{
+				"type":        "string",
+				"description": "背景音乐文件路径, 会循环或截断到视频时长",
+			},
+			"music_volume": map[string]interface{}{
+				"type":        "number",
+				"description": "背景音乐音量, 默认 1.0",
+			},
+		},
+		"required": []string{},
+	}
+	tool := AddFunctionTool("images_to_video", "把多张图片做成轮播视频, 支持转场、缩放平移动态效果、背景音乐和画面比例, 并显示进度", imagesToVideoParams)
+	return tool
+}
+
+func ImagesToVideo(args map[string]interface{}) interface{} {
+	log.Infof("ImagesToVideo called with args: %+v", args)
+	callId, ok := args["call_id"].(string)
+	if !ok {
+		return "invalid call_id arguments for ImagesToVideo"
+	}
+	progressObj, ok := args["progress_cb"].(pub.ProgressCallback)
+	if !ok {
+		return "invalid progress_cb arguments for ImagesToVideo"
+	}
+
+	images := getStringSliceArg(args, "images")
+	var output string
+	if dir := getStringArg(args, "image_dir", ""); dir != "" {
+		if len(images) > 0 {
+			return "please set either images or image_dir, not both"
+		}
+		var err error
+		images, err = ffmpegcmd.ListImages(dir)
+		if err != nil {
+			log.Errorf("error listing images: %v, dir:%s", err, dir)
+			return fmt.Sprintf("error listing images: %v", err)
+		}
+		if len(images) == 0 {
+			return fmt.Sprintf("no image found in %s", dir)
+		}
+		output = filepath.Clean(dir) + "_slideshow.mp4"
+	} else if len(images) > 0 {
+		output = strings.TrimSuffix(images[0], filepath.Ext(images[0])) + "_slideshow.mp4"
+	} else {
+		return "images or image_dir is required"
+	}
+
+	w, h, err := ffmpegcmd.SlideshowSize(getStringArg(args, "resolution", "1080p"), getStringArg(args, "aspect", "16:9"))
+	if err != nil {
+		return err.Error()
+	}
+	opts := &ffmpegcmd.SlideshowOptions{
+		ImageDuration:      getFloatArg(args, "image_duration", 3),
+		Transition:         getStringArg(args, "transition", "fade"),
+		TransitionDuration: getFloatArg(args, "transition_duration", 1),
+		KenBurns:           getBoolArg(args, "ken_burns", true),
+		Width:              w,
+		Height:             h,
+		MusicFile:          getStringArg(args, "music_file", ""),
+		MusicVolume:        getFloatArg(args, "music_volume", 1.0),
+	}
+	ffmpegArgs, duration, err := ffmpegcmd.BuildSlideshowArgs(images, output, opts)
+	if err != nil {
+		log.Errorf("BuildSlideshowArgs failed: %v, options:%+v", err, opts)
+		return err.Error()
+	}
+
+	log.Infof("Starting to make slideshow: %d images, options:%+v, output:%s, callId:%s", len(images), opts, output, callId)
+	go ffmpegcmd.ImagesToVideo(callId, ffmpegArgs, duration, progressObj)
+
+	return fmt.Sprintf("图片合成视频任务已启动, 共 %d 张图片, %dx%d, 时长 %.1f 秒, 输出文件: %s", len(images), w, h, duration, output)
+}
@@ -0,0 +1,179 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddChangeSpeedTool() *pub.ToolDefinition {
+	changeSpeedParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"factor": map[string]interface{}{
+				"type":        "number",
+				"description": "倍速, 例如 2 表示两倍速, 0.5 表示半速慢放, 范围 0.1~10",
+			},
+			"drop_audio": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否去掉音频, 默认 false(音频变速不变调)",
+			},
+			"interpolate": map[string]interface{}{
+				"type":        "boolean",
+				"description": "慢放时是否运动补偿插帧, 画面更流畅但处理很慢, 默认 false",
+			},
+			"frame_rate": map[string]interface{}{
+				"type":        "number",
+				"description": "插帧的目标帧率, 默认与源视频相同",
+			},
+		},
+		"required": []string{"input_file", "factor"},
+	}
+	tool := AddFunctionTool("change_speed", "视频变速(快放或慢放), 音频同步变速不变调, 慢放可选插帧, 并显示进度", changeSpeedParams)
+	return tool
+}
+
+func AddReverseVideoTool() *pub.ToolDefinition {
+	reverseVideoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径, 时长不超过60秒",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"reverse", "boomerang"},
+				"description": "reverse 倒放, boomerang 正放后接倒放(回旋), 默认 reverse",
+			},
+			"keep_audio": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否保留(同样倒放的)音频, 默认 true",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("reverse_video", "视频倒放或回旋效果(正放+倒放)", reverseVideoParams)
+	return tool
+}
+
+func AddLoopVideoTool() *pub.ToolDefinition {
+	loopVideoParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频或音频文件路径",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": "总共播放的次数, 至少 2",
+			},
+		},
+		"required": []string{"input_file", "count"},
+	}
+	tool := AddFunctionTool("loop_video", "把视频或音频循环播放指定次数, 不重新编码", loopVideoParams)
+	return tool
+}
+
+func ChangeSpeed(args map[string]interface{}) interface{} {
+	log.Infof("ChangeSpeed called with args: %+v", args)
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		return "invalid input_file arguments for ChangeSpeed"
+	}
+	callId, ok := args["call_id"].(string)
+	if !ok {
+		return "invalid call_id arguments for ChangeSpeed"
+	}
+	progressObj, ok := args["progress_cb"].(pub.ProgressCallback)
+	if !ok {
+		return "invalid progress_cb arguments for ChangeSpeed"
+	}
+	opts := &ffmpegcmd.SpeedOptions{
+		Factor:      getFloatArg(args, "factor", 0),
+		DropAudio:   getBoolArg(args, "drop_audio", false),
+		Interpolate: getBoolArg(args, "interpolate", false),
+		FrameRate:   getFloatArg(args, "frame_rate", 0),
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+	// 先校验参数, 出错时直接返回给模型
+	if _, _, err := ffmpegcmd.BuildSpeedFilters(opts, mediaInfo.HasAudio, mediaInfo.FrameRate); err != nil {
+		return err.Error()
+	}
+
+	output := fmt.Sprintf("%s_%gx.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), opts.Factor)
+	log.Infof("Starting to change speed: %s, options:%+v, output:%s, callId:%s", inputFile, opts, output, callId)
+
+	go ffmpegcmd.ChangeSpeed(callId, inputFile, output, opts, mediaInfo.HasAudio, mediaInfo.FrameRate, mediaInfo.Duration, progressObj)
+
+	desc := fmt.Sprintf("变速任务已启动, 输出文件: %s, 预计时长 %.1f 秒", output, mediaInfo.Duration/opts.Factor)
+	if opts.Interpolate && opts.Factor < 1 {
+		desc += ", 插帧处理较慢, 请耐心等待"
+	}
+	return desc
+}
+
+func ReverseVideo(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for ReverseVideo: %+v", args)
+		return "invalid input_file arguments for ReverseVideo"
+	}
+	mode := getStringArg(args, "mode", "reverse")
+	if mode != "reverse" && mode != "boomerang" {
+		return fmt.Sprintf("invalid mode: %s, should be reverse or boomerang", mode)
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+	withAudio := mediaInfo.HasAudio && getBoolArg(args, "keep_audio", true)
+
+	output := fmt.Sprintf("%s_%s.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), mode)
+	log.Infof("Starting to reverse video: %s, mode:%s, audio:%v, output:%s", inputFile, mode, withAudio, output)
+	if err := ffmpegcmd.ReverseVideo(inputFile, output, mode == "boomerang", withAudio, mediaInfo.Duration); err != nil {
+		log.Errorf("error reversing video: %v", err)
+		return fmt.Sprintf("error reversing video: %v", err)
+	}
+	return fmt.Sprintf("倒放完成, 输出文件: %s", output)
+}
+
+func LoopVideo(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for LoopVideo: %+v", args)
+		return "invalid input_file arguments for LoopVideo"
+	}
+	count := getIntArg(args, "count", 0)
+
+	output := fmt.Sprintf("%s_loop%d%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), count, filepath.Ext(inputFile))
+	log.Infof("Starting to loop media: %s, count:%d, output:%s", inputFile, count, output)
+	if err := ffmpegcmd.LoopMedia(inputFile, output, count); err != nil {
+		log.Errorf("error looping media: %v", err)
+		return fmt.Sprintf("error looping media: %v", err)
+	}
+	return fmt.Sprintf("循环完成, 共播放 %d 次, 输出文件: %s", count, output)
+}
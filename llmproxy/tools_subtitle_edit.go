@@ -0,0 +1,307 @@
+package llmproxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/subtitle"
+)
+
+func subtitleFormatParam() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        toInterfaceSlice(subtitle.SupportedFormats),
+		"description": "输出的字幕格式, 默认与输入相同",
+	}
+}
+
+// subtitleOutput 生成 <name><suffix>.<format> 输出路径, format 为空时沿用输入的扩展名
+func subtitleOutput(inputFile string, suffix string, format string) (string, error) {
+	ext := filepath.Ext(inputFile)
+	if format != "" {
+		if _, err := subtitle.Format(nil, format); err != nil {
+			return "", err
+		}
+		ext = "." + strings.ToLower(format)
+	}
+	return strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + suffix + ext, nil
+}
+
+func writeSubtitleResult(output string, cues []subtitle.Cue, action string) string {
+	if err := subtitle.WriteFile(output, cues); err != nil {
+		log.Errorf("error writing subtitle file: %v", err)
+		return fmt.Sprintf("error writing subtitle file: %v", err)
+	}
+	return fmt.Sprintf("字幕%s完成, 共 %d 条, 输出文件: %s", action, len(cues), output)
+}
+
+// prepareSrtForMux 把字幕平移后转换为临时 srt 文件, 供 mov_text 封装使用, 调用方负责删除
+func prepareSrtForMux(subtitleFile string, offset float64) (string, error) {
+	cues, err := subtitle.ReadFile(subtitleFile)
+	if err != nil {
+		return "", err
+	}
+	cues = subtitle.Shift(cues, offset)
+	if len(cues) == 0 {
+		return "", fmt.Errorf("no subtitle left after shifting %gs", offset)
+	}
+	f, err := os.CreateTemp("", "gollmagent_subtitle_*.srt")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	if err := subtitle.WriteFile(f.Name(), cues); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func AddConvertSubtitlesTool() *pub.ToolDefinition {
+	convertSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subtitle_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的字幕文件路径, 支持 srt, vtt, ass",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        toInterfaceSlice(subtitle.SupportedFormats),
+				"description": "目标字幕格式",
+			},
+		},
+		"required": []string{"subtitle_file", "format"},
+	}
+	tool := AddFunctionTool("convert_subtitles", "字幕格式转换(srt, vtt, ass 互转), 转换为 ass 时使用默认样式", convertSubtitlesParams)
+	return tool
+}
+
+func AddRetimeSubtitlesTool() *pub.ToolDefinition {
+	retimeSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subtitle_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的字幕文件路径",
+			},
+			"offset": map[string]interface{}{
+				"type":        "number",
+				"description": "整体平移的秒数, 正数延后, 负数提前",
+			},
+			"sync_from1": map[string]interface{}{
+				"type":        "string",
+				"description": "同步点1: 字幕中的原时间, 秒数或 HH:MM:SS.mmm",
+			},
+			"sync_to1": map[string]interface{}{
+				"type":        "string",
+				"description": "同步点1: 这句字幕在视频中实际应出现的时间",
+			},
+			"sync_from2": map[string]interface{}{
+				"type":        "string",
+				"description": "同步点2: 字幕中的原时间, 应与同步点1相距较远",
+			},
+			"sync_to2": map[string]interface{}{
+				"type":        "string",
+				"description": "同步点2: 这句字幕在视频中实际应出现的时间",
+			},
+			"from_fps": map[string]interface{}{
+				"type":        "number",
+				"description": "字幕制作时的视频帧率, 例如 23.976, 与 to_fps 一起使用",
+			},
+			"to_fps": map[string]interface{}{
+				"type":        "number",
+				"description": "目标视频帧率, 例如 25",
+			},
+			"format": subtitleFormatParam(),
+		},
+		"required": []string{"subtitle_file"},
+	}
+	tool := AddFunctionTool("retime_subtitles", "校正字幕时间轴: 整体平移, 按两个同步点线性拉伸, 或帧率转换, 可同时指定, 按帧率、同步点、平移的顺序执行", retimeSubtitlesParams)
+	return tool
+}
+
+func AddMergeSubtitlesTool() *pub.ToolDefinition {
+	mergeSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subtitle_files": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "要合并的字幕文件路径列表, 例如两种语言的字幕合成双语字幕",
+			},
+			"format": subtitleFormatParam(),
+		},
+		"required": []string{"subtitle_files"},
+	}
+	tool := AddFunctionTool("merge_subtitles", "合并多个字幕文件, 按时间排序", mergeSubtitlesParams)
+	return tool
+}
+
+func AddCleanSubtitlesTool() *pub.ToolDefinition {
+	cleanSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subtitle_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的字幕文件路径",
+			},
+			"max_chars": map[string]interface{}{
+				"type":        "integer",
+				"description": "每行最多字符数, 默认 42, 中文建议 16 左右, 0 表示不折行",
+			},
+			"max_lines": map[string]interface{}{
+				"type":        "integer",
+				"description": "每条字幕最多行数, 超过时按字数拆成多条, 默认 2",
+			},
+			"min_gap": map[string]interface{}{
+				"type":        "number",
+				"description": "相邻字幕之间的最小间隔(秒), 默认 0.04",
+			},
+			"format": subtitleFormatParam(),
+		},
+		"required": []string{"subtitle_file"},
+	}
+	tool := AddFunctionTool("clean_subtitles", "整理字幕: 长行折行并拆分过长的字幕, 修正重叠的时间轴, 去掉空字幕", cleanSubtitlesParams)
+	return tool
+}
+
+func ConvertSubtitles(args map[string]interface{}) interface{} {
+	subtitleFile, ok := args["subtitle_file"].(string)
+	if !ok {
+		log.Errorf("invalid subtitle_file arguments for ConvertSubtitles: %+v", args)
+		return "invalid subtitle_file arguments for ConvertSubtitles"
+	}
+	format := strings.ToLower(getStringArg(args, "format", ""))
+	if format == "" {
+		return "format is required"
+	}
+	cues, err := subtitle.ReadFile(subtitleFile)
+	if err != nil {
+		log.Errorf("error reading subtitle file: %v", err)
+		return fmt.Sprintf("error reading subtitle file: %v", err)
+	}
+	suffix := ""
+	if strings.EqualFold(filepath.Ext(subtitleFile), "."+format) {
+		suffix = "_converted"
+	}
+	output, err := subtitleOutput(subtitleFile, suffix, format)
+	if err != nil {
+		return err.Error()
+	}
+	log.Infof("Starting to convert subtitles: %s, output:%s", subtitleFile, output)
+	return writeSubtitleResult(output, cues, "格式转换")
+}
+
+func RetimeSubtitles(args map[string]interface{}) interface{} {
+	subtitleFile, ok := args["subtitle_file"].(string)
+	if !ok {
+		log.Errorf("invalid subtitle_file arguments for RetimeSubtitles: %+v", args)
+		return "invalid subtitle_file arguments for RetimeSubtitles"
+	}
+	cues, err := subtitle.ReadFile(subtitleFile)
+	if err != nil {
+		log.Errorf("error reading subtitle file: %v", err)
+		return fmt.Sprintf("error reading subtitle file: %v", err)
+	}
+
+	changed := false
+	if hasArg(args, "from_fps") || hasArg(args, "to_fps") {
+		cues, err = subtitle.ConvertFrameRate(cues, getFloatArg(args, "from_fps", 0), getFloatArg(args, "to_fps", 0))
+		if err != nil {
+			return err.Error()
+		}
+		changed = true
+	}
+
+	var sync [4]float64
+	syncCount := 0
+	for i, key := range []string{"sync_from1", "sync_to1", "sync_from2", "sync_to2"} {
+		t, ok, err := getTimeArg(args, key)
+		if err != nil {
+			return err.Error()
+		}
+		if ok {
+			sync[i] = t
+			syncCount++
+		}
+	}
+	if syncCount != 0 && syncCount != 4 {
+		return "sync_from1, sync_to1, sync_from2 and sync_to2 must be set together"
+	}
+	if syncCount == 4 {
+		cues, err = subtitle.Stretch(cues, sync[0], sync[1], sync[2], sync[3])
+		if err != nil {
+			return err.Error()
+		}
+		changed = true
+	}
+
+	if offset := getFloatArg(args, "offset", 0); offset != 0 {
+		cues = subtitle.Shift(cues, offset)
+		changed = true
+	}
+	if !changed {
+		return "nothing to do, please set offset, sync points or from_fps/to_fps"
+	}
+
+	output, err := subtitleOutput(subtitleFile, "_retimed", getStringArg(args, "format", ""))
+	if err != nil {
+		return err.Error()
+	}
+	log.Infof("Starting to retime subtitles: %s, args:%+v, output:%s", subtitleFile, args, output)
+	return writeSubtitleResult(output, cues, "时间轴校正")
+}
+
+func MergeSubtitles(args map[string]interface{}) interface{} {
+	files := getStringSliceArg(args, "subtitle_files")
+	if len(files) < 2 {
+		log.Errorf("invalid subtitle_files arguments for MergeSubtitles: %+v", args)
+		return "at least two subtitle files are required"
+	}
+	var lists [][]subtitle.Cue
+	for _, f := range files {
+		cues, err := subtitle.ReadFile(f)
+		if err != nil {
+			log.Errorf("error reading subtitle file: %v", err)
+			return fmt.Sprintf("error reading subtitle file: %v", err)
+		}
+		lists = append(lists, cues)
+	}
+	output, err := subtitleOutput(files[0], "_merged", getStringArg(args, "format", ""))
+	if err != nil {
+		return err.Error()
+	}
+	log.Infof("Starting to merge subtitles: %v, output:%s", files, output)
+	return writeSubtitleResult(output, subtitle.Merge(lists...), "合并")
+}
+
+func CleanSubtitles(args map[string]interface{}) interface{} {
+	subtitleFile, ok := args["subtitle_file"].(string)
+	if !ok {
+		log.Errorf("invalid subtitle_file arguments for CleanSubtitles: %+v", args)
+		return "invalid subtitle_file arguments for CleanSubtitles"
+	}
+	cues, err := subtitle.ReadFile(subtitleFile)
+	if err != nil {
+		log.Errorf("error reading subtitle file: %v", err)
+		return fmt.Sprintf("error reading subtitle file: %v", err)
+	}
+	minGap := getFloatArg(args, "min_gap", 0.04)
+	if minGap < 0 {
+		return "min_gap must not be negative"
+	}
+	cues = subtitle.SplitLongLines(cues, getIntArg(args, "max_chars", 42), getIntArg(args, "max_lines", 2))
+	cues = subtitle.FixOverlaps(cues, minGap)
+
+	output, err := subtitleOutput(subtitleFile, "_clean", getStringArg(args, "format", ""))
+	if err != nil {
+		return err.Error()
+	}
+	log.Infof("Starting to clean subtitles: %s, output:%s", subtitleFile, output)
+	return writeSubtitleResult(output, cues, "整理")
+}
@@ -0,0 +1,211 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddBurnSubtitlesTool() *pub.ToolDefinition {
+	burnSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"subtitle_file": map[string]interface{}{
+				"type":        "string",
+				"description": "字幕文件路径, 支持 srt, vtt, ass",
+			},
+			"font_name": map[string]interface{}{
+				"type":        "string",
+				"description": "字体名, 例如 Noto Sans CJK SC, 中文字幕需要使用支持中文的字体",
+			},
+			"font_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "字号",
+			},
+			"color": map[string]interface{}{
+				"type":        "string",
+				"description": "文字颜色, 颜色名(white, yellow 等)或 #RRGGBB",
+			},
+			"outline_color": map[string]interface{}{
+				"type":        "string",
+				"description": "描边颜色, 颜色名或 #RRGGBB",
+			},
+			"outline": map[string]interface{}{
+				"type":        "number",
+				"description": "描边宽度",
+			},
+			"margin_v": map[string]interface{}{
+				"type":        "integer",
+				"description": "字幕距离画面上下边缘的距离(像素)",
+			},
+			"position": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"bottom", "middle", "top"},
+				"description": "字幕位置, 默认 bottom",
+			},
+			"bold": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否加粗",
+			},
+			"fonts_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "额外的字体目录",
+			},
+		},
+		"required": []string{"input_file", "subtitle_file"},
+	}
+	tool := AddFunctionTool("burn_subtitles", "把字幕烧录到视频画面中(硬字幕), 可设置字体、字号、颜色、描边、边距和位置", burnSubtitlesParams)
+	return tool
+}
+
+func AddSubtitleTracksTool() *pub.ToolDefinition {
+	subtitleTracksParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"tracks": map[string]interface{}{
+				"type":        "array",
+				"description": "要添加的字幕轨道列表",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"file": map[string]interface{}{
+							"type":        "string",
+							"description": "字幕文件路径",
+						},
+						"language": map[string]interface{}{
+							"type":        "string",
+							"description": "语言代码(ISO 639-2), 例如 chi, eng, jpn",
+						},
+						"title": map[string]interface{}{
+							"type":        "string",
+							"description": "轨道标题",
+						},
+						"default": map[string]interface{}{
+							"type":        "boolean",
+							"description": "是否默认显示",
+						},
+						"forced": map[string]interface{}{
+							"type":        "boolean",
+							"description": "是否为强制字幕",
+						},
+					},
+					"required": []string{"file"},
+				},
+			},
+			"container": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"auto", "mp4", "mkv", "mov"},
+				"description": "输出容器, 默认 auto: mp4/mov 输入且字幕都是 srt/vtt 时保持原容器, 否则输出 mkv",
+			},
+		},
+		"required": []string{"input_file", "tracks"},
+	}
+	tool := AddFunctionTool("add_subtitle_tracks", "把多个字幕文件作为可切换的软字幕轨道封装进视频, 可设置语言、标题和默认/强制标记", subtitleTracksParams)
+	return tool
+}
+
+func BurnSubtitles(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for BurnSubtitles: %+v", args)
+		return "invalid input_file arguments for BurnSubtitles"
+	}
+	subtitleFile, ok := args["subtitle_file"].(string)
+	if !ok {
+		log.Errorf("invalid subtitle_file arguments for BurnSubtitles: %+v", args)
+		return "invalid subtitle_file arguments for BurnSubtitles"
+	}
+	style := &ffmpegcmd.SubtitleStyle{
+		FontName:     getStringArg(args, "font_name", ""),
+		FontSize:     getIntArg(args, "font_size", 0),
+		Color:        getStringArg(args, "color", ""),
+		OutlineColor: getStringArg(args, "outline_color", ""),
+		Outline:      getFloatArg(args, "outline", 0),
+		MarginV:      getIntArg(args, "margin_v", 0),
+		Position:     getStringArg(args, "position", ""),
+		Bold:         getBoolArg(args, "bold", false),
+		FontsDir:     getStringArg(args, "fonts_dir", ""),
+	}
+
+	output := fmt.Sprintf("%s_hardsub.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)))
+	log.Infof("Starting to burn subtitles: %s, subtitle:%s, style:%+v, output:%s", inputFile, subtitleFile, style, output)
+	if err := ffmpegcmd.BurnSubtitles(inputFile, subtitleFile, output, style); err != nil {
+		log.Errorf("error burning subtitles: %v", err)
+		return fmt.Sprintf("error burning subtitles: %v", err)
+	}
+	return fmt.Sprintf("硬字幕烧录完成, 输出文件: %s", output)
+}
+
+func parseSubtitleTracks(args map[string]interface{}) ([]ffmpegcmd.SubtitleTrack, error) {
+	items, ok := args["tracks"].([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("tracks is required")
+	}
+	var tracks []ffmpegcmd.SubtitleTrack
+	for i, item := range items {
+		trackArgs, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid tracks[%d]", i)
+		}
+		file := getStringArg(trackArgs, "file", "")
+		if file == "" {
+			return nil, fmt.Errorf("tracks[%d]: file is required", i)
+		}
+		tracks = append(tracks, ffmpegcmd.SubtitleTrack{
+			File:     file,
+			Language: getStringArg(trackArgs, "language", ""),
+			Title:    getStringArg(trackArgs, "title", ""),
+			Default:  getBoolArg(trackArgs, "default", false),
+			Forced:   getBoolArg(trackArgs, "forced", false),
+		})
+	}
+	return tracks, nil
+}
+
+func AddSubtitleTracks(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for AddSubtitleTracks: %+v", args)
+		return "invalid input_file arguments for AddSubtitleTracks"
+	}
+	tracks, err := parseSubtitleTracks(args)
+	if err != nil {
+		log.Errorf("invalid subtitle tracks: %v, args:%+v", err, args)
+		return fmt.Sprintf("invalid subtitle tracks: %v", err)
+	}
+	container, err := ffmpegcmd.ChooseSubtitleContainer(inputFile, tracks, getStringArg(args, "container", "auto"))
+	if err != nil {
+		return err.Error()
+	}
+
+	probe, err := ffprobe.Probe(inputFile)
+	if err != nil {
+		log.Errorf("error probing media: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	var existing []string
+	for _, st := range probe.SubtitleStreams() {
+		existing = append(existing, st.CodecName)
+	}
+
+	output := fmt.Sprintf("%s_subs.%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), container)
+	log.Infof("Starting to add subtitle tracks: %s, tracks:%+v, existing:%v, output:%s", inputFile, tracks, existing, output)
+	if err := ffmpegcmd.AddSubtitleTracks(inputFile, tracks, output, container, existing); err != nil {
+		log.Errorf("error adding subtitle tracks: %v", err)
+		return fmt.Sprintf("error adding subtitle tracks: %v", err)
+	}
+	return fmt.Sprintf("字幕轨道添加完成, 输出文件: %s, 新增 %d 条字幕轨道, 共 %d 条", output, len(tracks), len(existing)+len(tracks))
+}
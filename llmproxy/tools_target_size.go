@@ -0,0 +1,84 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddTranscodeToTargetSizeTool() *pub.ToolDefinition {
+	targetSizeParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"target_size_mb": map[string]interface{}{
+				"type":        "number",
+				"description": "目标文件大小, 单位MB(1MB=1000*1000字节), 编码时会预留少量余量, 输出会略小于这个值",
+			},
+			"audio_bitrate": map[string]interface{}{
+				"type":        "integer",
+				"description": "音频码率, 单位kbps, 默认128",
+			},
+		},
+		"required": []string{"input_file", "target_size_mb"},
+	}
+	tool := AddFunctionTool("transcode_to_target_size", "把视频两遍编码压缩到指定文件大小以内(例如聊天软件上传限制), 码率过低时自动降低分辨率, 并显示进度", targetSizeParams)
+	return tool
+}
+
+func TranscodeToTargetSize(args map[string]interface{}) interface{} {
+	log.Infof("TranscodeToTargetSize called with args: %+v", args)
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		return "invalid input_file arguments for TranscodeToTargetSize"
+	}
+	targetMB := getFloatArg(args, "target_size_mb", 0)
+	if targetMB <= 0 {
+		return "invalid target_size_mb arguments for TranscodeToTargetSize"
+	}
+	callId, ok := args["call_id"].(string)
+	if !ok {
+		return "invalid call_id arguments for TranscodeToTargetSize"
+	}
+	progressObj, ok := args["progress_cb"].(pub.ProgressCallback)
+	if !ok {
+		return "invalid progress_cb arguments for TranscodeToTargetSize"
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+	audioKbps := 0
+	if mediaInfo.HasAudio {
+		audioKbps = getIntArg(args, "audio_bitrate", 128)
+	}
+
+	w, h := mediaInfo.DisplaySize()
+	plan, err := ffmpegcmd.PlanTargetSize(int64(targetMB*ffmpegcmd.BytesPerMB), mediaInfo.Duration, audioKbps, w, h)
+	if err != nil {
+		log.Errorf("PlanTargetSize failed: %v, mediaInfo: %+v", err, mediaInfo)
+		return fmt.Sprintf("cannot reach target size: %v", err)
+	}
+
+	out := fmt.Sprintf("%s_%gmb.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), targetMB)
+	log.Infof("Starting two-pass encode: %s, plan:%+v, output:%s, callId:%s", inputFile, plan, out, callId)
+
+	go ffmpegcmd.TranscodeToTargetSize(callId, inputFile, out, plan, mediaInfo.Duration, progressObj)
+
+	planDesc, _ := json.Marshal(plan)
+	return fmt.Sprintf("两遍编码任务已启动, 需要几分钟, 编码参数:%s\n输出文件: %s, 完成后进度信息中会给出实际文件大小", string(planDesc), out)
+}
@@ -0,0 +1,90 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/utils"
+)
+
+func AddThumbnailSpritesTool() *pub.ToolDefinition {
+	thumbnailSpritesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的视频文件路径",
+			},
+			"interval": map[string]interface{}{
+				"type":        "number",
+				"description": "采样间隔, 单位秒, 默认5",
+			},
+			"columns": map[string]interface{}{
+				"type":        "integer",
+				"description": "每张雪碧图的列数, 默认5",
+			},
+			"rows": map[string]interface{}{
+				"type":        "integer",
+				"description": "每张雪碧图的行数, 默认5",
+			},
+			"width": map[string]interface{}{
+				"type":        "integer",
+				"description": "每个缩略图的宽度, 高度按比例计算, 默认160",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"jpg", "webp"},
+				"description": "雪碧图格式, 默认jpg",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("gen_thumbnail_sprites", "生成视频缩略图雪碧图和 WebVTT 文件, 用于播放器进度条拖动预览", thumbnailSpritesParams)
+	return tool
+}
+
+func GenThumbnailSprites(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for GenThumbnailSprites: %+v", args)
+		return "invalid input_file arguments for GenThumbnailSprites"
+	}
+	opts := &ffmpegcmd.SpriteOptions{
+		Interval:   getFloatArg(args, "interval", 5),
+		Columns:    getIntArg(args, "columns", 5),
+		Rows:       getIntArg(args, "rows", 5),
+		ThumbWidth: getIntArg(args, "width", 160),
+		Format:     getStringArg(args, "format", "jpg"),
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	if !mediaInfo.HasVideo {
+		return "input file has no video stream"
+	}
+
+	outputDir := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "_sprites"
+	if err := utils.EnsureDir(outputDir); err != nil {
+		log.Errorf("error ensuring output directory: %v", err)
+		return fmt.Sprintf("error ensuring output directory: %v", err)
+	}
+
+	log.Infof("Starting to gen thumbnail sprites: %s, options:%+v, outputDir:%s", inputFile, opts, outputDir)
+	w, h := mediaInfo.DisplaySize()
+	result, err := ffmpegcmd.GenThumbnailSprites(inputFile, outputDir, mediaInfo.Duration, w, h, opts)
+	if err != nil {
+		log.Errorf("error generating thumbnail sprites: %v", err)
+		return fmt.Sprintf("error generating thumbnail sprites: %v", err)
+	}
+	resultDesc, _ := json.Marshal(result)
+	return fmt.Sprintf("雪碧图生成完成: %s", string(resultDesc))
+}
@@ -0,0 +1,101 @@
+package llmproxy
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+	"github.com/gollmagent/subtitle"
+)
+
+var textCompleter subtitle.TextCompleter
+
+// SetTextCompleter 设置 translate_subtitles 使用的 llm
+func SetTextCompleter(completer subtitle.TextCompleter) {
+	textCompleter = completer
+}
+
+func AddTranslateSubtitlesTool() *pub.ToolDefinition {
+	translateSubtitlesParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subtitle_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的字幕文件路径, 支持 srt, vtt, ass",
+			},
+			"target_language": map[string]interface{}{
+				"type":        "string",
+				"description": "目标语言, 例如 中文, English, 日本語",
+			},
+			"source_language": map[string]interface{}{
+				"type":        "string",
+				"description": "原文语言, 默认自动判断",
+			},
+			"bilingual": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否输出原文加译文的双语字幕, 默认 false",
+			},
+			"batch_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "每次请求翻译的字幕条数, 默认 30",
+			},
+			"format": subtitleFormatParam(),
+		},
+		"required": []string{"subtitle_file", "target_language"},
+	}
+	tool := AddFunctionTool("translate_subtitles", "用大模型分批翻译字幕, 保持序号和时间轴不变, 可输出双语字幕", translateSubtitlesParams)
+	return tool
+}
+
+// languageSuffix 把目标语言转换为可以放进文件名的后缀
+func languageSuffix(language string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' || r == ':' {
+			return '-'
+		}
+		return r
+	}, language)
+}
+
+func TranslateSubtitles(args map[string]interface{}) interface{} {
+	subtitleFile, ok := args["subtitle_file"].(string)
+	if !ok {
+		log.Errorf("invalid subtitle_file arguments for TranslateSubtitles: %+v", args)
+		return "invalid subtitle_file arguments for TranslateSubtitles"
+	}
+	targetLanguage := getStringArg(args, "target_language", "")
+	if targetLanguage == "" {
+		return "target_language is required"
+	}
+	if textCompleter == nil {
+		return "llm is not configured for subtitle translation"
+	}
+	cues, err := subtitle.ReadFile(subtitleFile)
+	if err != nil {
+		log.Errorf("error reading subtitle file: %v", err)
+		return fmt.Sprintf("error reading subtitle file: %v", err)
+	}
+	if len(cues) == 0 {
+		return "no subtitle found in the input file"
+	}
+
+	opts := subtitle.TranslateOptions{
+		TargetLanguage: targetLanguage,
+		SourceLanguage: getStringArg(args, "source_language", ""),
+		BatchSize:      getIntArg(args, "batch_size", 30),
+		Bilingual:      getBoolArg(args, "bilingual", false),
+	}
+	output, err := subtitleOutput(subtitleFile, languageSuffix(targetLanguage), getStringArg(args, "format", ""))
+	if err != nil {
+		return err.Error()
+	}
+
+	log.Infof("Starting to translate subtitles: %s, cues:%d, options:%+v, output:%s", subtitleFile, len(cues), opts, output)
+	translated, err := subtitle.Translate(textCompleter, cues, opts)
+	if err != nil {
+		log.Errorf("error translating subtitles: %v", err)
+		return fmt.Sprintf("error translating subtitles: %v", err)
+	}
+	return writeSubtitleResult(output, translated, "翻译")
+}
@@ -0,0 +1,162 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+func AddTrimMediaTool() *pub.ToolDefinition {
+	trimMediaParams := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input_file": map[string]interface{}{
+				"type":        "string",
+				"description": "输入的多媒体文件路径",
+			},
+			"start": map[string]interface{}{
+				"type":        "string",
+				"description": "开始时间, 秒数或 HH:MM:SS 格式, 默认从头开始",
+			},
+			"end": map[string]interface{}{
+				"type":        "string",
+				"description": "结束时间, 秒数或 HH:MM:SS 格式, 和 duration 二选一",
+			},
+			"duration": map[string]interface{}{
+				"type":        "string",
+				"description": "截取时长, 秒数或 HH:MM:SS 格式, 和 end 二选一",
+			},
+			"ranges": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"start": map[string]interface{}{"type": "string"},
+						"end":   map[string]interface{}{"type": "string"},
+					},
+				},
+				"description": "多个时间段, 每项包含 start 和 end, 提供后忽略 start/end/duration",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []interface{}{"keep", "remove"},
+				"description": "keep: 保留指定时间段(默认); remove: 删除指定时间段, 保留其余部分",
+			},
+			"accurate": map[string]interface{}{
+				"type":        "boolean",
+				"description": "true: 重新编码, 精确到帧; false: 流拷贝, 速度快但开始时间会对齐到前一个关键帧. 默认false",
+			},
+		},
+		"required": []string{"input_file"},
+	}
+	tool := AddFunctionTool("trim_media", "剪切音视频: 截取一段或多段, 或者删除指定的时间段", trimMediaParams)
+	return tool
+}
+
+func parseTrimRanges(args map[string]interface{}) ([]ffmpegcmd.TimeRange, error) {
+	var ranges []ffmpegcmd.TimeRange
+	if items, ok := args["ranges"].([]interface{}); ok && len(items) > 0 {
+		for i, item := range items {
+			rangeArgs, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid ranges[%d]", i)
+			}
+			start, _, err := getTimeArg(rangeArgs, "start")
+			if err != nil {
+				return nil, fmt.Errorf("ranges[%d]: %v", i, err)
+			}
+			end, ok, err := getTimeArg(rangeArgs, "end")
+			if err != nil || !ok {
+				return nil, fmt.Errorf("ranges[%d]: end is required", i)
+			}
+			ranges = append(ranges, ffmpegcmd.TimeRange{Start: start, End: end})
+		}
+		return ranges, nil
+	}
+
+	start, _, err := getTimeArg(args, "start")
+	if err != nil {
+		return nil, err
+	}
+	end, hasEnd, err := getTimeArg(args, "end")
+	if err != nil {
+		return nil, err
+	}
+	duration, hasDuration, err := getTimeArg(args, "duration")
+	if err != nil {
+		return nil, err
+	}
+	if !hasEnd && !hasDuration {
+		return nil, fmt.Errorf("end or duration is required")
+	}
+	if !hasEnd {
+		end = start + duration
+	}
+	return []ffmpegcmd.TimeRange{{Start: start, End: end}}, nil
+}
+
+func TrimMedia(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for TrimMedia: %+v", args)
+		return "invalid input_file arguments for TrimMedia"
+	}
+	mode := getStringArg(args, "mode", "keep")
+	if mode != "keep" && mode != "remove" {
+		return fmt.Sprintf("invalid mode: %s, should be keep or remove", mode)
+	}
+	accurate := getBoolArg(args, "accurate", false)
+
+	ranges, err := parseTrimRanges(args)
+	if err != nil {
+		log.Errorf("invalid trim ranges: %v, args:%+v", err, args)
+		return fmt.Sprintf("invalid trim ranges: %v", err)
+	}
+
+	mediaInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	ranges, err = ffmpegcmd.NormalizeRanges(ranges, mediaInfo.Duration)
+	if err != nil {
+		return fmt.Sprintf("invalid trim ranges: %v", err)
+	}
+	if mode == "remove" {
+		ranges = ffmpegcmd.InvertRanges(ranges, mediaInfo.Duration)
+		if len(ranges) == 0 {
+			return "nothing left after removing the given ranges"
+		}
+	}
+	if !accurate && mediaInfo.HasVideo {
+		keyframes, err := ffprobe.GetKeyframeTimes(inputFile)
+		if err != nil {
+			log.Warningf("get keyframes failed: %v, file:%s", err, inputFile)
+		} else {
+			ranges = ffmpegcmd.SnapToKeyframes(ranges, keyframes)
+		}
+	}
+
+	output := fmt.Sprintf("%s_trim%s", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)), filepath.Ext(inputFile))
+	log.Infof("Starting to trim media: %s, ranges:%+v, accurate:%v, output:%s", inputFile, ranges, accurate, output)
+
+	err = ffmpegcmd.TrimMedia(inputFile, output, &ffmpegcmd.TrimOptions{
+		Ranges:   ranges,
+		Accurate: accurate,
+		HasVideo: mediaInfo.HasVideo,
+		HasAudio: mediaInfo.HasAudio,
+	})
+	if err != nil {
+		log.Errorf("error trimming media: %v", err)
+		return fmt.Sprintf("error trimming media: %v", err)
+	}
+	rangesDesc, _ := json.Marshal(ranges)
+	return fmt.Sprintf("剪切完成, 输出文件: %s, 保留的时间段(秒): %s, 输出时长: %.2f秒",
+		output, string(rangesDesc), ffmpegcmd.TotalDuration(ranges))
+}
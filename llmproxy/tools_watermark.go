@@ -0,0 +1,257 @@
+package llmproxy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gollmagent/ffmpegcmd"
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+	"github.com/gollmagent/pub"
+)
+
+// watermarkCommonParams 图片和文字水印共用的参数
+func watermarkCommonParams() map[string]interface{} {
+	return map[string]interface{}{
+		"input_file": map[string]interface{}{
+			"type":        "string",
+			"description": "输入的多媒体文件路径",
+		},
+		"position": map[string]interface{}{
+			"type": "string",
+			"description": fmt.Sprintf("水印位置, 可选值: %s; 也可以是百分比位置 \"x%%,y%%\", 例如 \"50%%,90%%\" 表示水平居中靠近底部. 默认 top-right",
+				strings.Join(ffmpegcmd.SupportedWatermarkPositions, ", ")),
+		},
+		"margin": map[string]interface{}{
+			"type":        "integer",
+			"description": "水印距离画面边缘的像素, 默认 8",
+		},
+		"opacity": map[string]interface{}{
+			"type":        "number",
+			"description": "不透明度, 0-1, 默认 1",
+		},
+		"start_time": map[string]interface{}{
+			"type":        "string",
+			"description": "开始显示的时间, 秒数或 HH:MM:SS 格式, 默认从头开始",
+		},
+		"end_time": map[string]interface{}{
+			"type":        "string",
+			"description": "结束显示的时间, 秒数或 HH:MM:SS 格式, 默认到视频结尾",
+		},
+		"fade_in": map[string]interface{}{
+			"type":        "number",
+			"description": "淡入时长(秒), 默认 0",
+		},
+		"fade_out": map[string]interface{}{
+			"type":        "number",
+			"description": "淡出时长(秒), 默认 0",
+		},
+		"motion": map[string]interface{}{
+			"type":        "string",
+			"enum":        toInterfaceSlice(ffmpegcmd.SupportedWatermarkMotions),
+			"description": "none 固定位置; scroll 从右向左循环滚动(纵向按 position); bounce 在画面内来回反弹. 默认 none",
+		},
+		"speed": map[string]interface{}{
+			"type":        "number",
+			"description": "scroll/bounce 的移动速度(像素/秒), 默认 100",
+		},
+	}
+}
+
+// parseWatermarkOptions 读取共用的水印参数
+func parseWatermarkOptions(args map[string]interface{}) (*ffmpegcmd.WatermarkOptions, error) {
+	start, _, err := getTimeArg(args, "start_time")
+	if err != nil {
+		return nil, err
+	}
+	end, _, err := getTimeArg(args, "end_time")
+	if err != nil {
+		return nil, err
+	}
+	return &ffmpegcmd.WatermarkOptions{
+		Position: getStringArg(args, "position", "top-right"),
+		Margin:   getIntArg(args, "margin", 0),
+		Opacity:  getFloatArg(args, "opacity", 1),
+		Start:    start,
+		End:      end,
+		FadeIn:   getFloatArg(args, "fade_in", 0),
+		FadeOut:  getFloatArg(args, "fade_out", 0),
+		Motion:   getStringArg(args, "motion", "none"),
+		Speed:    getFloatArg(args, "speed", 0),
+	}, nil
+}
+
+func AddImageWatermark2VideoTool() *pub.ToolDefinition {
+	properties := watermarkCommonParams()
+	properties["watermark_file"] = map[string]interface{}{
+		"type":        "string",
+		"description": "水印图片文件路径",
+	}
+	properties["scale"] = map[string]interface{}{
+		"type":        "number",
+		"description": "水印宽度占视频宽度的百分比(0-100], 例如 15, 默认使用图片原始大小",
+	}
+	imageWatermark2VideoParams := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"input_file", "watermark_file"},
+	}
+	tool := AddFunctionTool("image_watermark_to_video", "给视频添加图片水印, 支持按比例缩放、透明度、位置和边距、显示时间段、淡入淡出和滚动/反弹移动", imageWatermark2VideoParams)
+	return tool
+}
+
+func AddTextWatermark2VideoTool() *pub.ToolDefinition {
+	properties := watermarkCommonParams()
+	properties["watermark_text"] = map[string]interface{}{
+		"type":        "string",
+		"description": "水印文字内容",
+	}
+	properties["color"] = map[string]interface{}{
+		"type": "string",
+		"description": fmt.Sprintf("水印文字颜色, 颜色名(%s), 可带透明度如 white@0.5, 或 #RRGGBB, #RRGGBBAA, rgba(r,g,b,a). 默认 white",
+			strings.Join(ffmpegcmd.SupportedTextColor(), ", ")),
+	}
+	properties["font_size"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "字号, 默认 24",
+	}
+	properties["font_file"] = map[string]interface{}{
+		"type":        "string",
+		"description": "字体文件路径(ttf/otf/ttc), 不指定时中日韩文字会自动查找系统的 CJK 字体",
+	}
+	properties["font"] = map[string]interface{}{
+		"type":        "string",
+		"description": "字体名, 例如 Noto Sans CJK SC, 需要 ffmpeg 支持 fontconfig, 指定 font_file 时忽略",
+	}
+	properties["border_width"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "文字描边宽度(像素), 默认 0 不描边",
+	}
+	properties["border_color"] = map[string]interface{}{
+		"type":        "string",
+		"description": "描边颜色, 格式同 color, 默认 black",
+	}
+	properties["shadow_x"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "阴影水平偏移(像素), shadow_x 和 shadow_y 都为 0 时没有阴影",
+	}
+	properties["shadow_y"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "阴影垂直偏移(像素)",
+	}
+	properties["shadow_color"] = map[string]interface{}{
+		"type":        "string",
+		"description": "阴影颜色, 格式同 color, 默认 black@0.6",
+	}
+	properties["box"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "是否给文字加背景框, 默认 false",
+	}
+	properties["box_color"] = map[string]interface{}{
+		"type":        "string",
+		"description": "背景框颜色, 格式同 color, 默认 black@0.5",
+	}
+	properties["box_padding"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "背景框和文字之间的留白(像素), 默认 8",
+	}
+	textWatermark2VideoParams := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"input_file", "watermark_text"},
+	}
+	tool := AddFunctionTool("text_watermark_to_video", "给视频添加文字水印, 支持任意文字(含引号、冒号、中文)、字体、颜色、描边、阴影、背景框、透明度、位置和边距、显示时间段、淡入淡出和滚动/反弹移动", textWatermark2VideoParams)
+	return tool
+}
+
+func ImageWatermark2Video(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for ImageWatermark2Video: %+v", args)
+		return "invalid input_file arguments for ImageWatermark2Video"
+	}
+	watermarkFile, ok := args["watermark_file"].(string)
+	if !ok {
+		log.Errorf("invalid watermark_file arguments for ImageWatermark2Video: %+v", args)
+		return "invalid watermark_file arguments for ImageWatermark2Video"
+	}
+	opts, err := parseWatermarkOptions(args)
+	if err != nil {
+		return err.Error()
+	}
+	opts.Scale = getFloatArg(args, "scale", 0)
+
+	videoInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	videoWidth, _ := videoInfo.DisplaySize()
+	output := fmt.Sprintf("%s_watermarked.mp4", strings.TrimSuffix(inputFile, filepath.Ext(inputFile)))
+
+	log.Infof("Starting to add image watermark to video: %s, watermark:%s, options:%+v, output:%s",
+		inputFile, watermarkFile, opts, output)
+	err = ffmpegcmd.ImageWatermark2Video(inputFile, watermarkFile, output, videoWidth, videoInfo.Duration, opts)
+	if err != nil {
+		log.Errorf("error adding image watermark to video: %v", err)
+		return fmt.Sprintf("error adding image watermark to video: %v", err)
+	}
+	return output
+}
+
+func TextWatermark2Video(args map[string]interface{}) interface{} {
+	inputFile, ok := args["input_file"].(string)
+	if !ok {
+		log.Errorf("invalid input_file arguments for TextWatermark2Video: %+v", args)
+		return "invalid input_file arguments for TextWatermark2Video"
+	}
+	watermarkText, ok := args["watermark_text"].(string)
+	if !ok {
+		log.Errorf("invalid watermark_text arguments for TextWatermark2Video: %+v", args)
+		return "invalid watermark_text arguments for TextWatermark2Video"
+	}
+	opts, err := parseWatermarkOptions(args)
+	if err != nil {
+		return err.Error()
+	}
+	opts.Color = getStringArg(args, "color", "white")
+	opts.FontSize = getIntArg(args, "font_size", 24)
+	opts.FontFile = getStringArg(args, "font_file", "")
+	opts.Font = getStringArg(args, "font", "")
+	opts.BorderWidth = getIntArg(args, "border_width", 0)
+	opts.BorderColor = getStringArg(args, "border_color", "")
+	opts.ShadowX = getIntArg(args, "shadow_x", 0)
+	opts.ShadowY = getIntArg(args, "shadow_y", 0)
+	opts.ShadowColor = getStringArg(args, "shadow_color", "")
+	opts.Box = getBoolArg(args, "box", false)
+	opts.BoxColor = getStringArg(args, "box_color", "")
+	opts.BoxPadding = getIntArg(args, "box_padding", 0)
+
+	configs, err := ffmpegcmd.GetFFmpegConfig()
+	if err != nil {
+		log.Errorf("error getting ffmpeg config: %v", err)
+		return "error getting ffmpeg config"
+	}
+	// check if ffmpeg is compiled with --enable-gpl --enable-freetype
+	if !strings.Contains(strings.Join(configs, " "), "--enable-gpl") || !strings.Contains(strings.Join(configs, " "), "--enable-freetype") {
+		log.Errorf("ffmpeg is not compiled with --enable-gpl --enable-freetype, cannot add text watermark")
+		return "ffmpeg is not compiled with --enable-gpl --enable-freetype, cannot add text watermark"
+	}
+
+	videoInfo, err := ffprobe.GetMediaFullInfo(inputFile)
+	if err != nil {
+		log.Errorf("error getting media info: %v, file:%s", err, inputFile)
+		return fmt.Sprintf("error getting media info: %v", err)
+	}
+	output := fmt.Sprintf("%s_text_watermarked.mp4", strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile)))
+
+	log.Infof("Starting to add text watermark to video: %s, text:%s, options:%+v, output:%s",
+		inputFile, watermarkText, opts, output)
+	err = ffmpegcmd.TextWatermark2Video(inputFile, watermarkText, output, videoInfo.Duration, opts)
+	if err != nil {
+		log.Errorf("error adding text watermark to video: %v", err)
+		return fmt.Sprintf("error adding text watermark to video: %v", err)
+	}
+	return output
+}
@@ -0,0 +1,313 @@
+package medialib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+	log "github.com/gollmagent/logging"
+)
+
+// 媒体库扫描时识别的文件扩展名
+var MediaExtensions = map[string]bool{
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".avi": true,
+	".flv": true, ".webm": true, ".ts": true, ".mts": true, ".wmv": true,
+	".mpg": true, ".mpeg": true, ".3gp": true,
+	".m4a": true, ".mp3": true, ".aac": true, ".wav": true, ".flac": true,
+	".ogg": true, ".opus": true, ".wma": true,
+}
+
+// 缓存格式版本, FullInfo 增加字段后加一, 旧版本的缓存条目会被重新探测(版本 2 增加了 Rotation)
+const kProbeVersion = 2
+
+type ProbeFunc func(filename string) (ffprobe.FullInfo, error)
+
+// MediaEntry 媒体库中一个文件的缓存信息, Size 和 ModTime 用于判断缓存是否失效
+type MediaEntry struct {
+	Path    string           `json:"path"`
+	Size    int64            `json:"size"`
+	ModTime time.Time        `json:"mod_time"`
+	Info    ffprobe.FullInfo `json:"info"`
+	Version int              `json:"probe_version,omitempty"` // 探测时的 kProbeVersion
+}
+
+func (entry *MediaEntry) cacheKey() string {
+	return cacheKey(entry.Path, entry.Size, entry.ModTime)
+}
+
+func cacheKey(path string, size int64, modTime time.Time) string {
+	return fmt.Sprintf("%s|%d|%d", path, modTime.UnixNano(), size)
+}
+
+// Orientation 根据旋转后的显示宽高返回 vertical, horizontal, square, 纯音频返回空串
+func (entry *MediaEntry) Orientation() string {
+	w, h := entry.Info.DisplaySize()
+	if !entry.Info.HasVideo || w <= 0 || h <= 0 {
+		return ""
+	}
+	if w > h {
+		return "horizontal"
+	}
+	if w < h {
+		return "vertical"
+	}
+	return "square"
+}
+
+// SearchFilter 搜索条件, 零值表示不过滤
+type SearchFilter struct {
+	Dir            string
+	NameContains   string
+	MinDuration    float64
+	MaxDuration    float64
+	MinWidth       int
+	MinHeight      int
+	Orientation    string
+	VideoCodec     string
+	AudioCodec     string
+	HasVideo       *bool
+	HasAudio       *bool
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	Limit          int
+}
+
+type MediaLibrary struct {
+	dirs      []string
+	cacheFile string
+	entries   map[string]*MediaEntry // key: path|mtime|size
+	probe     ProbeFunc
+	mutex     sync.Mutex
+}
+
+func NewMediaLibrary(dirs []string, cacheFile string) *MediaLibrary {
+	lib := &MediaLibrary{
+		cacheFile: cacheFile,
+		entries:   make(map[string]*MediaEntry),
+		probe:     ffprobe.GetMediaFullInfo,
+	}
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if len(dir) == 0 {
+			continue
+		}
+		if absDir, err := filepath.Abs(dir); err == nil {
+			dir = absDir
+		}
+		lib.dirs = append(lib.dirs, dir)
+	}
+	return lib
+}
+
+// SetProbeFunc 替换探测函数, 主要用于测试
+func (lib *MediaLibrary) SetProbeFunc(probe ProbeFunc) {
+	lib.mutex.Lock()
+	defer lib.mutex.Unlock()
+	lib.probe = probe
+}
+
+func (lib *MediaLibrary) Dirs() []string {
+	return append([]string{}, lib.dirs...)
+}
+
+// Load 从缓存文件加载上次的扫描结果, 缓存文件不存在不算错误
+func (lib *MediaLibrary) Load() error {
+	if len(lib.cacheFile) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(lib.cacheFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var entries []*MediaEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("invalid media library cache %s: %v", lib.cacheFile, err)
+	}
+
+	lib.mutex.Lock()
+	defer lib.mutex.Unlock()
+	stale := 0
+	for _, entry := range entries {
+		if entry.Version < kProbeVersion {
+			// 旧版本缓存缺少新增的字段(例如 Rotation), 不加载, 下次扫描时重新探测
+			stale++
+			continue
+		}
+		lib.entries[entry.cacheKey()] = entry
+	}
+	log.Infof("media library loaded %d entries from %s, %d stale entries dropped", len(entries)-stale, lib.cacheFile, stale)
+	return nil
+}
+
+func (lib *MediaLibrary) Save() error {
+	if len(lib.cacheFile) == 0 {
+		return nil
+	}
+	entries := lib.allEntries()
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(lib.cacheFile, data, 0644)
+}
+
+// Scan 遍历配置的目录, 只对新增或者修改过的文件执行 ffprobe, 删除的文件从缓存中移除.
+// 返回本次重新探测的文件数量
+func (lib *MediaLibrary) Scan() (int, error) {
+	if len(lib.dirs) == 0 {
+		return 0, fmt.Errorf("no media directories configured")
+	}
+
+	lib.mutex.Lock()
+	probe := lib.probe
+	lib.mutex.Unlock()
+
+	seen := make(map[string]*MediaEntry)
+	probed := 0
+	for _, dir := range lib.dirs {
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				log.Warningf("media library walk error: %v, path:%s", err, path)
+				return nil
+			}
+			if fi.IsDir() {
+				return nil
+			}
+			if !MediaExtensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			key := cacheKey(path, fi.Size(), fi.ModTime())
+
+			lib.mutex.Lock()
+			entry, cached := lib.entries[key]
+			lib.mutex.Unlock()
+			if cached {
+				seen[key] = entry
+				return nil
+			}
+
+			info, err := probe(path)
+			if err != nil {
+				log.Warningf("media library probe failed: %v, file:%s", err, path)
+				return nil
+			}
+			probed++
+			seen[key] = &MediaEntry{
+				Path:    path,
+				Size:    fi.Size(),
+				ModTime: fi.ModTime(),
+				Info:    info,
+				Version: kProbeVersion,
+			}
+			return nil
+		})
+		if err != nil {
+			return probed, err
+		}
+	}
+
+	lib.mutex.Lock()
+	lib.entries = seen
+	lib.mutex.Unlock()
+
+	log.Infof("media library scan done, files:%d, probed:%d", len(seen), probed)
+	if probed > 0 {
+		if err := lib.Save(); err != nil {
+			log.Errorf("media library save cache failed: %v", err)
+		}
+	}
+	return probed, nil
+}
+
+func (lib *MediaLibrary) allEntries() []*MediaEntry {
+	lib.mutex.Lock()
+	defer lib.mutex.Unlock()
+
+	entries := make([]*MediaEntry, 0, len(lib.entries))
+	for _, entry := range lib.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// Search 按条件过滤媒体文件, 结果按路径排序
+func (lib *MediaLibrary) Search(filter *SearchFilter) []*MediaEntry {
+	if filter == nil {
+		filter = &SearchFilter{}
+	}
+	var ret []*MediaEntry
+	for _, entry := range lib.allEntries() {
+		if !filter.match(entry) {
+			continue
+		}
+		ret = append(ret, entry)
+		if filter.Limit > 0 && len(ret) >= filter.Limit {
+			break
+		}
+	}
+	return ret
+}
+
+func (filter *SearchFilter) match(entry *MediaEntry) bool {
+	info := &entry.Info
+	if len(filter.Dir) > 0 {
+		dir, err := filepath.Abs(filter.Dir)
+		if err != nil {
+			dir = filter.Dir
+		}
+		if !strings.HasPrefix(entry.Path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+			return false
+		}
+	}
+	if len(filter.NameContains) > 0 &&
+		!strings.Contains(strings.ToLower(filepath.Base(entry.Path)), strings.ToLower(filter.NameContains)) {
+		return false
+	}
+	if filter.MinDuration > 0 && info.Duration < filter.MinDuration {
+		return false
+	}
+	if filter.MaxDuration > 0 && info.Duration > filter.MaxDuration {
+		return false
+	}
+	width, height := info.DisplaySize()
+	if filter.MinWidth > 0 && width < filter.MinWidth {
+		return false
+	}
+	if filter.MinHeight > 0 && height < filter.MinHeight {
+		return false
+	}
+	if len(filter.Orientation) > 0 && entry.Orientation() != filter.Orientation {
+		return false
+	}
+	if len(filter.VideoCodec) > 0 && !strings.EqualFold(info.VideoCodec, filter.VideoCodec) {
+		return false
+	}
+	if len(filter.AudioCodec) > 0 && !strings.EqualFold(info.AudioCodec, filter.AudioCodec) {
+		return false
+	}
+	if filter.HasVideo != nil && info.HasVideo != *filter.HasVideo {
+		return false
+	}
+	if filter.HasAudio != nil && info.HasAudio != *filter.HasAudio {
+		return false
+	}
+	if !filter.ModifiedAfter.IsZero() && entry.ModTime.Before(filter.ModifiedAfter) {
+		return false
+	}
+	if !filter.ModifiedBefore.IsZero() && !entry.ModTime.Before(filter.ModifiedBefore) {
+		return false
+	}
+	return true
+}
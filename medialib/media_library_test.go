@@ -0,0 +1,112 @@
+package medialib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gollmagent/ffmpegcmd/ffprobe"
+)
+
+func writeTestFile(t *testing.T, path string, data string) {
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write %s failed: %v", path, err)
+	}
+}
+
+func TestMediaLibraryScanAndSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "landscape.mp4"), "a")
+	writeTestFile(t, filepath.Join(dir, "portrait.mov"), "bb")
+	writeTestFile(t, filepath.Join(dir, "podcast.m4a"), "ccc")
+	writeTestFile(t, filepath.Join(dir, "notes.txt"), "not media")
+
+	infos := map[string]ffprobe.FullInfo{
+		"landscape.mp4": {Duration: 700, HasVideo: true, HasAudio: true, VideoCodec: "h264", AudioCodec: "aac", Width: 1920, Height: 1080},
+		"portrait.mov":  {Duration: 30, HasVideo: true, VideoCodec: "hevc", Width: 1920, Height: 1080, Rotation: 90},
+		"podcast.m4a":   {Duration: 1800, HasAudio: true, AudioCodec: "aac"},
+	}
+	probeCount := 0
+	lib := NewMediaLibrary([]string{dir}, filepath.Join(dir, "cache.json"))
+	lib.SetProbeFunc(func(filename string) (ffprobe.FullInfo, error) {
+		probeCount++
+		return infos[filepath.Base(filename)], nil
+	})
+
+	probed, err := lib.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if probed != 3 {
+		t.Fatalf("expected 3 probed files, got %d", probed)
+	}
+
+	// 第二次扫描应该全部命中缓存
+	probed, _ = lib.Scan()
+	if probed != 0 || probeCount != 3 {
+		t.Fatalf("expected cached scan, probed:%d, probeCount:%d", probed, probeCount)
+	}
+
+	vertical := lib.Search(&SearchFilter{Orientation: "vertical"})
+	if len(vertical) != 1 || filepath.Base(vertical[0].Path) != "portrait.mov" {
+		t.Errorf("vertical search got %+v", vertical)
+	}
+	long := lib.Search(&SearchFilter{MinDuration: 600})
+	if len(long) != 2 {
+		t.Errorf("expected 2 files longer than 10 minutes, got %d", len(long))
+	}
+	noAudio := false
+	silent := lib.Search(&SearchFilter{HasAudio: &noAudio})
+	if len(silent) != 1 || silent[0].Info.VideoCodec != "hevc" {
+		t.Errorf("no audio search got %+v", silent)
+	}
+	if got := lib.Search(&SearchFilter{VideoCodec: "H264", MinHeight: 1080}); len(got) != 1 {
+		t.Errorf("codec search got %d results", len(got))
+	}
+
+	// 修改文件后需要重新探测, 并且缓存文件可以被重新加载
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "podcast.m4a"), later, later)
+	probed, _ = lib.Scan()
+	if probed != 1 {
+		t.Errorf("expected 1 re-probed file after modification, got %d", probed)
+	}
+
+	reloaded := NewMediaLibrary([]string{dir}, filepath.Join(dir, "cache.json"))
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	reloaded.SetProbeFunc(func(filename string) (ffprobe.FullInfo, error) {
+		t.Errorf("unexpected probe of %s after loading cache", filename)
+		return ffprobe.FullInfo{}, nil
+	})
+	if probed, _ = reloaded.Scan(); probed != 0 {
+		t.Errorf("expected loaded cache to be reused, probed:%d", probed)
+	}
+}
+
+func TestMediaLibraryReprobeStaleCache(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "portrait.mov")
+	writeTestFile(t, file, "a")
+	fi, _ := os.Stat(file)
+
+	// 没有 probe_version 的旧缓存, 缺少 Rotation, 加载时丢弃并重新探测
+	stale := `[{"path":"` + file + `","size":1,"mod_time":"` + fi.ModTime().Format(time.RFC3339Nano) + `","info":{"duration":30,"has_video":true,"width":1920,"height":1080}}]`
+	writeTestFile(t, filepath.Join(dir, "cache.json"), stale)
+
+	lib := NewMediaLibrary([]string{dir}, filepath.Join(dir, "cache.json"))
+	if err := lib.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	lib.SetProbeFunc(func(filename string) (ffprobe.FullInfo, error) {
+		return ffprobe.FullInfo{Duration: 30, HasVideo: true, Width: 1920, Height: 1080, Rotation: 90}, nil
+	})
+	if probed, _ := lib.Scan(); probed != 1 {
+		t.Fatalf("expected stale entry to be re-probed, probed:%d", probed)
+	}
+	if got := lib.Search(&SearchFilter{Orientation: "vertical"}); len(got) != 1 {
+		t.Errorf("expected re-probed entry to be vertical, got %+v", got)
+	}
+}
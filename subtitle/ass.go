@@ -0,0 +1,110 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 384
+PlayResY: 288
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,16,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,1,0,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// 花括号里的样式覆盖标签, 例如 {\an8}, {\i1}
+var assOverrideRegex = regexp.MustCompile(`\{[^}]*\}`)
+
+// formatASSTimestamp 格式化为 H:MM:SS.cc, ass 精度只到百分之一秒
+func formatASSTimestamp(sec float64) string {
+	if sec < 0 {
+		sec = 0
+	}
+	cs := int64(math.Round(sec * 100))
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// FormatASS 生成使用默认样式的 ass 内容
+func FormatASS(cues []Cue) string {
+	var b strings.Builder
+	b.WriteString(assHeader)
+	for _, c := range cues {
+		text := strings.ReplaceAll(strings.TrimSpace(c.Text), "\n", `\N`)
+		fmt.Fprintf(&b, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n",
+			formatASSTimestamp(c.Start), formatASSTimestamp(c.End), text)
+	}
+	return b.String()
+}
+
+// assText 去掉样式覆盖标签, 把 \N 换行和 \h 硬空格还原为纯文本
+func assText(text string) string {
+	text = assOverrideRegex.ReplaceAllString(text, "")
+	text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// ParseASS 解析 ass/ssa 的 [Events] 段, 字段顺序以 Format 行为准, 样式信息不保留
+func ParseASS(content string) ([]Cue, error) {
+	var cues []Cue
+	inEvents := false
+	var fields []string
+	for _, line := range strings.Split(normalizeContent(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Format":
+			fields = nil
+			for _, f := range strings.Split(value, ",") {
+				fields = append(fields, strings.ToLower(strings.TrimSpace(f)))
+			}
+		case "Dialogue":
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("invalid ass: Dialogue before Format line")
+			}
+			// Text 是最后一个字段, 本身可以包含逗号
+			values := strings.SplitN(strings.TrimSpace(value), ",", len(fields))
+			if len(values) != len(fields) {
+				return nil, fmt.Errorf("invalid ass dialogue: %s", line)
+			}
+			var cue Cue
+			for i, f := range fields {
+				var err error
+				switch f {
+				case "start":
+					cue.Start, err = parseTimestamp(values[i])
+				case "end":
+					cue.End, err = parseTimestamp(values[i])
+				case "text":
+					cue.Text = assText(values[i])
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			if cue.Text != "" {
+				cues = append(cues, cue)
+			}
+		}
+	}
+	return cues, nil
+}
@@ -0,0 +1,175 @@
+package subtitle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 调整重叠时, 剩余时长不足这个值的字幕合并到下一条
+const kMinCueDuration = 0.2
+
+func copyCues(cues []Cue) []Cue {
+	return append([]Cue(nil), cues...)
+}
+
+// Shift 整体平移 offset 秒, 平移后完全落在 0 之前的字幕被丢弃, 开始时间小于 0 的截断到 0
+func Shift(cues []Cue, offset float64) []Cue {
+	var ret []Cue
+	for _, c := range cues {
+		c.Start += offset
+		c.End += offset
+		if c.End <= 0 {
+			continue
+		}
+		if c.Start < 0 {
+			c.Start = 0
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// Stretch 根据两个同步点线性校正时间轴: 原来在 from1 的字幕移到 to1, 原来在 from2 的字幕移到 to2
+func Stretch(cues []Cue, from1, to1, from2, to2 float64) ([]Cue, error) {
+	if from1 == from2 {
+		return nil, fmt.Errorf("sync points must have different source times")
+	}
+	scale := (to2 - to1) / (from2 - from1)
+	if scale <= 0 {
+		return nil, fmt.Errorf("sync points must keep the same order after retiming")
+	}
+	ret := copyCues(cues)
+	for i := range ret {
+		ret[i].Start = to1 + (ret[i].Start-from1)*scale
+		ret[i].End = to1 + (ret[i].End-from1)*scale
+	}
+	return Shift(ret, 0), nil
+}
+
+// ConvertFrameRate 把按 fromFps 制作的字幕转换到 toFps 的视频, 例如 23.976 的字幕用于 25 帧的 PAL 加速版本
+func ConvertFrameRate(cues []Cue, fromFps, toFps float64) ([]Cue, error) {
+	if fromFps <= 0 || toFps <= 0 {
+		return nil, fmt.Errorf("invalid frame rate: %g -> %g", fromFps, toFps)
+	}
+	ret := copyCues(cues)
+	for i := range ret {
+		ret[i].Start = ret[i].Start * fromFps / toFps
+		ret[i].End = ret[i].End * fromFps / toFps
+	}
+	return ret, nil
+}
+
+// Merge 合并多个字幕列表并按开始时间排序, 开始时间相同的保持参数顺序
+func Merge(lists ...[]Cue) []Cue {
+	var ret []Cue
+	for _, l := range lists {
+		ret = append(ret, l...)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Start < ret[j].Start
+	})
+	return ret
+}
+
+// wrapLine 把超过 maxChars 个字符的一行拆成多行, 优先在空格处断开, 中日韩文本没有空格时按字符数断开
+func wrapLine(line string, maxChars int) []string {
+	var lines []string
+	for utf8.RuneCountInString(line) > maxChars {
+		runes := []rune(line)
+		cut := -1
+		for i := maxChars; i > 0; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		if cut < 0 {
+			lines = append(lines, string(runes[:maxChars]))
+			line = string(runes[maxChars:])
+		} else {
+			lines = append(lines, strings.TrimSpace(string(runes[:cut])))
+			line = strings.TrimSpace(string(runes[cut:]))
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// SplitLongLines 把每行限制在 maxChars 个字符以内, 折行后超过 maxLines 行的字幕按字数比例拆成多条
+func SplitLongLines(cues []Cue, maxChars, maxLines int) []Cue {
+	if maxChars <= 0 {
+		return copyCues(cues)
+	}
+	if maxLines <= 0 {
+		maxLines = 2
+	}
+	var ret []Cue
+	for _, c := range cues {
+		var lines []string
+		for _, line := range strings.Split(c.Text, "\n") {
+			lines = append(lines, wrapLine(strings.TrimSpace(line), maxChars)...)
+		}
+		if len(lines) <= maxLines {
+			c.Text = strings.Join(lines, "\n")
+			ret = append(ret, c)
+			continue
+		}
+
+		var chunks []string
+		total := 0
+		for i := 0; i < len(lines); i += maxLines {
+			chunk := strings.Join(lines[i:min(i+maxLines, len(lines))], "\n")
+			chunks = append(chunks, chunk)
+			total += utf8.RuneCountInString(chunk)
+		}
+		start := c.Start
+		for i, chunk := range chunks {
+			end := c.End
+			if i < len(chunks)-1 {
+				end = start + (c.End-c.Start)*float64(utf8.RuneCountInString(chunk))/float64(total)
+			}
+			ret = append(ret, Cue{Start: start, End: end, Text: chunk})
+			start = end
+		}
+	}
+	return ret
+}
+
+// FixOverlaps 按开始时间排序, 去掉空字幕和时长非法的字幕, 把与下一条重叠的字幕结束时间提前到下一条开始前 minGap 秒.
+// 提前后太短的字幕合并到下一条
+func FixOverlaps(cues []Cue, minGap float64) []Cue {
+	var valid []Cue
+	for _, c := range cues {
+		c.Text = strings.TrimSpace(c.Text)
+		if c.Text == "" || c.End <= c.Start {
+			continue
+		}
+		valid = append(valid, c)
+	}
+	valid = Merge(valid)
+
+	var ret []Cue
+	for i := 0; i < len(valid); i++ {
+		c := valid[i]
+		if i == len(valid)-1 {
+			ret = append(ret, c)
+			break
+		}
+		next := &valid[i+1]
+		if c.End > next.Start-minGap {
+			if next.Start-minGap-c.Start < kMinCueDuration {
+				next.Start = c.Start
+				next.End = max(next.End, c.End)
+				next.Text = c.Text + "\n" + next.Text
+				continue
+			}
+			c.End = next.Start - minGap
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
@@ -0,0 +1,91 @@
+package subtitle
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func cuesAlmostEqual(a, b []Cue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i].Start-b[i].Start) > 1e-6 || math.Abs(a[i].End-b[i].End) > 1e-6 || a[i].Text != b[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+func TestShift(t *testing.T) {
+	cues := []Cue{{0, 1, "a"}, {1.5, 3, "b"}, {4, 5, "c"}}
+	got := Shift(cues, -2)
+	want := []Cue{{0, 1, "b"}, {2, 3, "c"}}
+	if !cuesAlmostEqual(got, want) {
+		t.Errorf("Shift got %+v, want %+v", got, want)
+	}
+}
+
+func TestStretch(t *testing.T) {
+	cues := []Cue{{10, 12, "a"}, {110, 112, "b"}}
+	// 10s 的字幕应该在 12s, 110s 的应该在 132s
+	got, err := Stretch(cues, 10, 12, 110, 132)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{{12, 14.4, "a"}, {132, 134.4, "b"}}
+	if !cuesAlmostEqual(got, want) {
+		t.Errorf("Stretch got %+v, want %+v", got, want)
+	}
+	if _, err := Stretch(cues, 10, 12, 10, 20); err == nil {
+		t.Errorf("expected error for same source time")
+	}
+	if _, err := Stretch(cues, 10, 20, 20, 10); err == nil {
+		t.Errorf("expected error for reversed sync points")
+	}
+}
+
+func TestConvertFrameRate(t *testing.T) {
+	got, err := ConvertFrameRate([]Cue{{25, 50, "a"}}, 25, 23.976)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{{25 * 25 / 23.976, 50 * 25 / 23.976, "a"}}
+	if !cuesAlmostEqual(got, want) {
+		t.Errorf("ConvertFrameRate got %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitLongLines(t *testing.T) {
+	cues := []Cue{
+		{0, 2, "the quick brown fox jumps over"},
+		{2, 6, "一二三四五六七八九十甲乙"},
+	}
+	got := SplitLongLines(cues, 10, 2)
+	// 第一条折成 3 行, 超过 2 行按字数拆成两条
+	want := []Cue{
+		{0, 2 * 19.0 / 29.0, "the quick\nbrown fox"},
+		{2 * 19.0 / 29.0, 2, "jumps over"},
+		{2, 6, "一二三四五六七八九十\n甲乙"},
+	}
+	if !cuesAlmostEqual(got, want) {
+		t.Errorf("SplitLongLines got %+v, want %+v", got, want)
+	}
+}
+
+func TestFixOverlaps(t *testing.T) {
+	cues := []Cue{
+		{5, 8, "c"},
+		{0, 3, "a"},
+		{2, 4, "b"},
+		{5.1, 6, "d"},
+		{7, 7, "zero"},
+		{9, 10, "  "},
+	}
+	got := FixOverlaps(cues, 0.1)
+	want := []Cue{{0, 1.9, "a"}, {2, 4, "b"}, {5, 8, "c\nd"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FixOverlaps got %+v, want %+v", got, want)
+	}
+}
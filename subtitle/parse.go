@@ -0,0 +1,146 @@
+package subtitle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var blankLineRegex = regexp.MustCompile(`\n[ \t]*\n`)
+
+// normalizeContent 去掉 BOM 并统一换行符
+func normalizeContent(content string) string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.ReplaceAll(content, "\r", "\n")
+}
+
+// parseTimestamp 解析 HH:MM:SS,mmm, HH:MM:SS.mmm, MM:SS.mmm 以及 ass 的 H:MM:SS.cc
+func parseTimestamp(ts string) (float64, error) {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(ts), ",", "."), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %s", ts)
+	}
+	var sec float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || (i < len(parts)-1 && strings.Contains(p, ".")) {
+			return 0, fmt.Errorf("invalid timestamp: %s", ts)
+		}
+		sec = sec*60 + v
+	}
+	return sec, nil
+}
+
+// parseTimingLine 解析 "start --> end [cue settings]"
+func parseTimingLine(line string) (float64, float64, error) {
+	items := strings.SplitN(line, "-->", 2)
+	if len(items) != 2 {
+		return 0, 0, fmt.Errorf("invalid timing line: %s", line)
+	}
+	start, err := parseTimestamp(items[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(items[1])
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("invalid timing line: %s", line)
+	}
+	end, err := parseTimestamp(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// parseBlocks 解析 srt/vtt 的字幕块: 可选的序号/标识行, 时间行, 文本行. 没有时间行的块(vtt 的 NOTE, STYLE 等)忽略
+func parseBlocks(content string) ([]Cue, error) {
+	var cues []Cue
+	for _, block := range blankLineRegex.Split(content, -1) {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 || timing > 1 {
+			continue
+		}
+		start, end, err := parseTimingLine(lines[timing])
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, Cue{
+			Start: start,
+			End:   end,
+			Text:  strings.TrimSpace(strings.Join(lines[timing+1:], "\n")),
+		})
+	}
+	return cues, nil
+}
+
+// ParseSRT 解析 srt 内容
+func ParseSRT(content string) ([]Cue, error) {
+	return parseBlocks(normalizeContent(content))
+}
+
+// ParseVTT 解析 WebVTT 内容, 忽略 cue 设置和 NOTE/STYLE/REGION 块
+func ParseVTT(content string) ([]Cue, error) {
+	content = normalizeContent(content)
+	if !strings.HasPrefix(content, "WEBVTT") {
+		return nil, fmt.Errorf("invalid vtt: missing WEBVTT header")
+	}
+	return parseBlocks(content)
+}
+
+// DetectFormat 根据内容判断字幕格式
+func DetectFormat(content string) string {
+	content = strings.TrimSpace(normalizeContent(content))
+	switch {
+	case strings.HasPrefix(content, "WEBVTT"):
+		return "vtt"
+	case strings.HasPrefix(content, "[Script Info]") || strings.Contains(content, "\n[Events]"):
+		return "ass"
+	}
+	return "srt"
+}
+
+// Parse 按格式名解析字幕内容, format 为空时根据内容判断
+func Parse(content string, format string) ([]Cue, error) {
+	if format == "" {
+		format = DetectFormat(content)
+	}
+	switch strings.ToLower(format) {
+	case "srt":
+		return ParseSRT(content)
+	case "vtt":
+		return ParseVTT(content)
+	case "ass", "ssa":
+		return ParseASS(content)
+	}
+	return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+}
+
+// ReadFile 读取字幕文件, 按扩展名选择格式, 未知扩展名根据内容判断
+func ReadFile(path string) ([]Cue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch format {
+	case "srt", "vtt", "ass", "ssa":
+	default:
+		format = ""
+	}
+	cues, err := Parse(string(data), format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cues, nil
+}
@@ -0,0 +1,74 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSRT(t *testing.T) {
+	content := "\ufeff1\r\n00:00:00,500 --> 00:00:02,250\r\n你好, 欢迎收看\r\n\r\n" +
+		"2\r\n01:01:01,001 --> 01:01:03,000\r\nfirst\r\nsecond\r\n"
+	cues, err := ParseSRT(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Start: 0.5, End: 2.25, Text: "你好, 欢迎收看"},
+		{Start: 3661.001, End: 3663, Text: "first\nsecond"},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("ParseSRT got %+v, want %+v", cues, want)
+	}
+
+	// 写出再读回保持一致
+	again, err := ParseSRT(FormatSRT(cues))
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("srt round trip got %+v, %v", again, err)
+	}
+	if _, err := ParseSRT("1\n00:00:xx,000 --> 00:00:01,000\nbad\n"); err == nil {
+		t.Errorf("expected error for invalid timestamp")
+	}
+}
+
+func TestParseVTT(t *testing.T) {
+	content := "WEBVTT - test\n\nNOTE 注释\n\nSTYLE\n::cue { color: yellow }\n\n" +
+		"intro\n00:01.000 --> 00:03.500 align:start position:10%\nhello\n\n" +
+		"01:00:00.000 --> 01:00:01.000\nbye\n"
+	cues, err := Parse(content, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Start: 1, End: 3.5, Text: "hello"},
+		{Start: 3600, End: 3601, Text: "bye"},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("ParseVTT got %+v, want %+v", cues, want)
+	}
+	if _, err := ParseVTT("1\n00:00:01.000 --> 00:00:02.000\nno header\n"); err == nil {
+		t.Errorf("expected error for missing header")
+	}
+}
+
+func TestParseASS(t *testing.T) {
+	content := "[Script Info]\nTitle: test\n\n[V4+ Styles]\nFormat: Name, Fontname\nStyle: Default,Arial\n\n" +
+		"[Events]\nFormat: Layer, Start, End, Style, Text\n" +
+		"Comment: 0,0:00:00.00,0:00:01.00,Default,ignored\n" +
+		"Dialogue: 0,0:00:01.50,0:00:04.00,Default,{\\an8}Hello, world\\Nsecond\\hline\n"
+	if DetectFormat(content) != "ass" {
+		t.Errorf("DetectFormat got %s, want ass", DetectFormat(content))
+	}
+	cues, err := Parse(content, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{{Start: 1.5, End: 4, Text: "Hello, world\nsecond line"}}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("ParseASS got %+v, want %+v", cues, want)
+	}
+
+	again, err := ParseASS(FormatASS(cues))
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("ass round trip got %+v, %v", again, err)
+	}
+}
@@ -0,0 +1,71 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cue 一条字幕, 时间单位秒
+type Cue struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// 支持输出的字幕格式
+var SupportedFormats = []string{"srt", "vtt", "ass"}
+
+// formatTimestamp 把秒格式化为 HH:MM:SS<sep>mmm, srt 用逗号, vtt 用点
+func formatTimestamp(sec float64, sep string) string {
+	if sec < 0 {
+		sec = 0
+	}
+	ms := int64(math.Round(sec * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// FormatSRT 生成 srt 内容, 序号从 1 开始
+func FormatSRT(cues []Cue) string {
+	var b strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatTimestamp(c.Start, ","), formatTimestamp(c.End, ","), strings.TrimSpace(c.Text))
+	}
+	return b.String()
+}
+
+// FormatVTT 生成 WebVTT 内容
+func FormatVTT(cues []Cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n",
+			formatTimestamp(c.Start, "."), formatTimestamp(c.End, "."), strings.TrimSpace(c.Text))
+	}
+	return b.String()
+}
+
+// Format 按格式名(srt/vtt/ass)生成字幕内容
+func Format(cues []Cue, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "srt":
+		return FormatSRT(cues), nil
+	case "vtt":
+		return FormatVTT(cues), nil
+	case "ass", "ssa":
+		return FormatASS(cues), nil
+	}
+	return "", fmt.Errorf("unsupported subtitle format: %s", format)
+}
+
+// WriteFile 按文件扩展名写出字幕文件
+func WriteFile(path string, cues []Cue) error {
+	content, err := Format(cues, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
@@ -0,0 +1,28 @@
+package subtitle
+
+import "testing"
+
+var testCues = []Cue{
+	{Start: 0.5, End: 2.25, Text: "你好, 欢迎收看"},
+	{Start: 3661.001, End: 3663, Text: " second line \n"},
+}
+
+func TestFormatSRT(t *testing.T) {
+	want := "1\n00:00:00,500 --> 00:00:02,250\n你好, 欢迎收看\n\n" +
+		"2\n01:01:01,001 --> 01:01:03,000\nsecond line\n\n"
+	if got := FormatSRT(testCues); got != want {
+		t.Errorf("FormatSRT got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestFormatVTT(t *testing.T) {
+	want := "WEBVTT\n\n00:00:00.500 --> 00:00:02.250\n你好, 欢迎收看\n\n" +
+		"01:01:01.001 --> 01:01:03.000\nsecond line\n\n"
+	got, err := Format(testCues, "VTT")
+	if err != nil || got != want {
+		t.Errorf("Format vtt got:\n%q, %v\nwant:\n%q", got, err, want)
+	}
+	if _, err := Format(testCues, "txt"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
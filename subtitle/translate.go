@@ -0,0 +1,177 @@
+package subtitle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TextCompleter 无状态的文本补全, 由 llm 实现
+type TextCompleter interface {
+	Complete(prompt string) (string, error)
+}
+
+type TranslateOptions struct {
+	TargetLanguage string
+	SourceLanguage string // 为空时由模型自动判断
+	BatchSize      int    // 每次请求翻译的字幕条数, 默认 30
+	ContextSize    int    // 每批前后附带的参考字幕条数, 默认 3, 负数表示不附带
+	MaxRetries     int    // 缺失条目的重试次数, 默认 2
+	Bilingual      bool   // 输出原文加译文的双语字幕
+}
+
+// translateItem 和模型之间传递的字幕条目, id 为字幕序号(从 1 开始)
+type translateItem struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+func (opts *TranslateOptions) setDefaults() {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 30
+	}
+	if opts.ContextSize < 0 {
+		opts.ContextSize = 0
+	} else if opts.ContextSize == 0 {
+		opts.ContextSize = 3
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 2
+	}
+}
+
+// buildTranslatePrompt 生成一批字幕的翻译提示词. 待翻译条目以 json 放在最后一行
+func buildTranslatePrompt(opts *TranslateOptions, before []translateItem, after []translateItem, items []translateItem) string {
+	var b strings.Builder
+	b.WriteString("你是专业的字幕翻译. ")
+	if opts.SourceLanguage != "" {
+		fmt.Fprintf(&b, "请把下面的字幕从%s翻译成%s. ", opts.SourceLanguage, opts.TargetLanguage)
+	} else {
+		fmt.Fprintf(&b, "请把下面的字幕翻译成%s. ", opts.TargetLanguage)
+	}
+	b.WriteString("要求:\n")
+	b.WriteString("1. 每条字幕单独翻译, 保持 id 不变, 不要合并、拆分、遗漏或新增条目\n")
+	b.WriteString("2. 译文简洁口语化, 适合字幕显示, 保留原有的换行\n")
+	b.WriteString("3. 只输出 json 数组, 格式为 [{\"id\":1,\"text\":\"译文\"}], 不要输出其他内容\n")
+	if len(before) > 0 {
+		data, _ := json.Marshal(before)
+		fmt.Fprintf(&b, "前文(仅供参考, 不要翻译): %s\n", data)
+	}
+	if len(after) > 0 {
+		data, _ := json.Marshal(after)
+		fmt.Fprintf(&b, "后文(仅供参考, 不要翻译): %s\n", data)
+	}
+	data, _ := json.Marshal(items)
+	fmt.Fprintf(&b, "待翻译:\n%s", data)
+	return b.String()
+}
+
+// parseTranslateReply 从模型回复中取出 json 数组, 兼容 ```json 代码块和前后多余的文字
+func parseTranslateReply(reply string) ([]translateItem, error) {
+	start := strings.Index(reply, "[")
+	end := strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no json array found in reply")
+	}
+	var items []translateItem
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &items); err != nil {
+		return nil, fmt.Errorf("invalid json in reply: %v", err)
+	}
+	return items, nil
+}
+
+// translateBatch 翻译 cues[start:end], 结果写入 translated. 回复中缺失的条目单独重试
+func translateBatch(completer TextCompleter, cues []Cue, translated []string, start, end int, opts *TranslateOptions) error {
+	var before, after []translateItem
+	for i := max(0, start-opts.ContextSize); i < start; i++ {
+		text := cues[i].Text
+		if translated[i] != "" {
+			text = fmt.Sprintf("%s (译文: %s)", cues[i].Text, translated[i])
+		}
+		before = append(before, translateItem{ID: i + 1, Text: text})
+	}
+	for i := end; i < min(len(cues), end+opts.ContextSize); i++ {
+		after = append(after, translateItem{ID: i + 1, Text: cues[i].Text})
+	}
+
+	pending := make(map[int]bool)
+	for i := start; i < end; i++ {
+		pending[i+1] = true
+	}
+	var lastErr error
+	for attempt := 0; attempt <= opts.MaxRetries && len(pending) > 0; attempt++ {
+		var items []translateItem
+		for i := start; i < end; i++ {
+			if pending[i+1] {
+				items = append(items, translateItem{ID: i + 1, Text: cues[i].Text})
+			}
+		}
+		reply, err := completer.Complete(buildTranslatePrompt(opts, before, after, items))
+		if err == nil {
+			var results []translateItem
+			results, err = parseTranslateReply(reply)
+			for _, r := range results {
+				text := strings.TrimSpace(r.Text)
+				if pending[r.ID] && text != "" {
+					translated[r.ID-1] = text
+					delete(pending, r.ID)
+				}
+			}
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if len(pending) > 0 {
+		if lastErr != nil {
+			return fmt.Errorf("cues %d-%d: %d not translated after %d retries: %v", start+1, end, len(pending), opts.MaxRetries, lastErr)
+		}
+		return fmt.Errorf("cues %d-%d: %d not translated after %d retries", start+1, end, len(pending), opts.MaxRetries)
+	}
+	return nil
+}
+
+// validateTranslation 检查译文和原文条数一致, 时间轴没有变化
+func validateTranslation(src []Cue, dst []Cue) error {
+	if len(src) != len(dst) {
+		return fmt.Errorf("cue count mismatch: %d -> %d", len(src), len(dst))
+	}
+	for i := range src {
+		if src[i].Start != dst[i].Start || src[i].End != dst[i].End {
+			return fmt.Errorf("cue %d timing changed", i+1)
+		}
+		if strings.TrimSpace(dst[i].Text) == "" {
+			return fmt.Errorf("cue %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// Translate 分批翻译字幕文本, 序号和时间轴保持不变
+func Translate(completer TextCompleter, cues []Cue, opts TranslateOptions) ([]Cue, error) {
+	if opts.TargetLanguage == "" {
+		return nil, fmt.Errorf("target language is required")
+	}
+	opts.setDefaults()
+
+	translated := make([]string, len(cues))
+	for start := 0; start < len(cues); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(cues))
+		if err := translateBatch(completer, cues, translated, start, end, &opts); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := copyCues(cues)
+	for i := range ret {
+		if opts.Bilingual {
+			ret[i].Text = strings.TrimSpace(cues[i].Text) + "\n" + translated[i]
+		} else {
+			ret[i].Text = translated[i]
+		}
+	}
+	if err := validateTranslation(cues, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
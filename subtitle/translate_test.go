@@ -0,0 +1,86 @@
+package subtitle
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeCompleter 把待翻译条目的文本加上 "译:" 前缀返回, dropOnce 中的 id 第一次会被漏掉
+type fakeCompleter struct {
+	calls    int
+	dropOnce map[int]bool
+	prompts  []string
+}
+
+func (f *fakeCompleter) Complete(prompt string) (string, error) {
+	f.calls++
+	f.prompts = append(f.prompts, prompt)
+	lines := strings.Split(prompt, "\n")
+	var items []translateItem
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &items); err != nil {
+		return "", err
+	}
+	var out []translateItem
+	for _, item := range items {
+		if f.dropOnce[item.ID] {
+			delete(f.dropOnce, item.ID)
+			continue
+		}
+		out = append(out, translateItem{ID: item.ID, Text: "译:" + item.Text})
+	}
+	data, _ := json.Marshal(out)
+	return fmt.Sprintf("```json\n%s\n```", data), nil
+}
+
+func TestTranslate(t *testing.T) {
+	var cues []Cue
+	for i := 0; i < 5; i++ {
+		cues = append(cues, Cue{Start: float64(i), End: float64(i) + 0.5, Text: fmt.Sprintf("line %d", i+1)})
+	}
+	completer := &fakeCompleter{dropOnce: map[int]bool{2: true}}
+	got, err := Translate(completer, cues, TranslateOptions{TargetLanguage: "中文", BatchSize: 3, ContextSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两批, 第一批漏掉的 id 2 重试一次
+	if completer.calls != 3 {
+		t.Errorf("calls got %d, want 3", completer.calls)
+	}
+	if !strings.Contains(completer.prompts[1], `[{"id":2,"text":"line 2"}]`) {
+		t.Errorf("retry prompt should only contain the missing cue:\n%s", completer.prompts[1])
+	}
+	if !strings.Contains(completer.prompts[2], "line 3 (译文: 译:line 3)") {
+		t.Errorf("second batch should carry translated context:\n%s", completer.prompts[2])
+	}
+	for i, c := range got {
+		want := Cue{Start: cues[i].Start, End: cues[i].End, Text: "译:" + cues[i].Text}
+		if !reflect.DeepEqual(c, want) {
+			t.Errorf("cue %d got %+v, want %+v", i+1, c, want)
+		}
+	}
+
+	bilingual, err := Translate(&fakeCompleter{}, cues[:1], TranslateOptions{TargetLanguage: "中文", Bilingual: true})
+	if err != nil || bilingual[0].Text != "line 1\n译:line 1" {
+		t.Errorf("bilingual got %+v, %v", bilingual, err)
+	}
+}
+
+func TestTranslateMissing(t *testing.T) {
+	cues := []Cue{{0, 1, "a"}, {1, 2, "b"}}
+	// 每次都漏掉 id 1, 重试用尽后报错, 不在请求里的 id 忽略
+	_, err := Translate(completerFunc(func(prompt string) (string, error) {
+		return `[{"id":2,"text":"B"},{"id":9,"text":"extra"}]`, nil
+	}), cues, TranslateOptions{TargetLanguage: "en", MaxRetries: 1})
+	if err == nil || !strings.Contains(err.Error(), "1 not translated") {
+		t.Errorf("expected missing cue error, got %v", err)
+	}
+}
+
+type completerFunc func(prompt string) (string, error)
+
+func (f completerFunc) Complete(prompt string) (string, error) {
+	return f(prompt)
+}